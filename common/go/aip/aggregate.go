@@ -0,0 +1,228 @@
+package aip
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.einride.tech/aip/filtering"
+	"google.golang.org/protobuf/proto"
+)
+
+// AggregateFunction is an SQL aggregate function.
+type AggregateFunction string
+
+const (
+	// AggregateFunctionCount counts rows. It is the only function that does not take a path.
+	AggregateFunctionCount AggregateFunction = "COUNT"
+	// AggregateFunctionSum sums the values of a path.
+	AggregateFunctionSum AggregateFunction = "SUM"
+	// AggregateFunctionMin returns the minimum value of a path.
+	AggregateFunctionMin AggregateFunction = "MIN"
+	// AggregateFunctionMax returns the maximum value of a path.
+	AggregateFunctionMax AggregateFunction = "MAX"
+)
+
+var (
+	// Matches `count()`, `count(*)`, `sum(page_count)`, etc.
+	aggregationRegexp = regexp.MustCompile(`^(?i)(count|sum|min|max)\(\s*(\*|[a-z_][a-z0-9_]*)?\s*\)$`)
+	// Paths are used as column names, so we only allow plain identifiers.
+	pathRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// AggregateRequest defines the interface of an AggregateResourceRequest.
+type AggregateRequest interface {
+	proto.Message
+	GetFilter() string
+	// Comma separated paths to group by, e.g. "author, status".
+	GetGroupBy() string
+	// Comma separated aggregations, e.g. "count(), sum(page_count), max(publish_year)".
+	GetAggregations() string
+}
+
+// Aggregation is a single aggregation of an aggregate request.
+type Aggregation struct {
+	Function AggregateFunction
+	// Empty for COUNT.
+	Path string
+	// The column alias of this aggregation in the SQL select clause, e.g. "count" or "sum_page_count".
+	Alias string
+}
+
+// ParsedAggregateRequest is an aggregate request that is parsed.
+type ParsedAggregateRequest interface {
+	// Returns the group by paths, in the order they appear in the select clause.
+	GetGroupByPaths() []string
+	// Returns the aggregations, in the order they appear in the select clause, after the group by paths.
+	GetAggregations() []*Aggregation
+	// Returns an SQL select clause, e.g. "author, COUNT(*) AS count".
+	GetSQLSelectClause() string
+	// Returns an SQL group by clause, or "" if the request does not group by any path.
+	GetSQLGroupByClause() string
+	// Returns an SQL where clause + any params.
+	GetSQLWhereClause() (string, []any)
+}
+
+type parsedAggregateRequest struct {
+	groupByPaths []string
+	aggregations []*Aggregation
+	whereClause  string
+	whereParams  []any
+}
+
+// GetGroupByPaths implements the ParsedAggregateRequest interface.
+func (pr *parsedAggregateRequest) GetGroupByPaths() []string { return pr.groupByPaths }
+
+// GetAggregations implements the ParsedAggregateRequest interface.
+func (pr *parsedAggregateRequest) GetAggregations() []*Aggregation { return pr.aggregations }
+
+// GetSQLSelectClause implements the ParsedAggregateRequest interface.
+func (pr *parsedAggregateRequest) GetSQLSelectClause() string {
+	columns := make([]string, 0, len(pr.groupByPaths)+len(pr.aggregations))
+	columns = append(columns, pr.groupByPaths...)
+	for _, aggregation := range pr.aggregations {
+		argument := aggregation.Path
+		if aggregation.Function == AggregateFunctionCount {
+			argument = "*"
+		}
+		columns = append(columns, fmt.Sprintf("%s(%s) AS %s", aggregation.Function, argument, aggregation.Alias))
+	}
+	return strings.Join(columns, ", ")
+}
+
+// GetSQLGroupByClause implements the ParsedAggregateRequest interface.
+func (pr *parsedAggregateRequest) GetSQLGroupByClause() string {
+	if len(pr.groupByPaths) == 0 {
+		return ""
+	}
+	return "GROUP BY " + strings.Join(pr.groupByPaths, ", ")
+}
+
+// GetSQLWhereClause implements the ParsedAggregateRequest interface.
+func (pr *parsedAggregateRequest) GetSQLWhereClause() (string, []any) {
+	return pr.whereClause, pr.whereParams
+}
+
+// WithAggregationOptions sets the paths a request may group by, and the paths it may sum / min / max over.
+// This method panics on invalid paths as this method should be declared as a topline variable.
+func (p *Parser) WithAggregationOptions(groupByPaths []string, aggregatePaths []string) *Parser {
+	p.groupByPaths = map[string]struct{}{}
+	p.aggregatePaths = map[string]struct{}{}
+	for _, path := range groupByPaths {
+		if !pathRegexp.MatchString(path) {
+			log.Panicf("invalid group by path: %s", path)
+		}
+		p.groupByPaths[path] = struct{}{}
+	}
+	for _, path := range aggregatePaths {
+		if !pathRegexp.MatchString(path) {
+			log.Panicf("invalid aggregate path: %s", path)
+		}
+		p.aggregatePaths[path] = struct{}{}
+	}
+	return p
+}
+
+// ParseAggregateRequest parses the given request. Any error should be returned as a InvalidArgument error.
+// Scoped parsers return an error, as they need the request's context.
+func (p *Parser) ParseAggregateRequest(request AggregateRequest, macros ...filtering.Macro) (ParsedAggregateRequest, error) {
+	if p.scopeFN != nil {
+		return nil, errScopedParser
+	}
+	return p.parseAggregateRequest(context.Background(), request, macros...)
+}
+
+// ParseAggregateRequestContext parses the given request, restricted by the parser's scope if any. Errors of the scope
+// are wrapped, any other error should be returned as a InvalidArgument error.
+func (p *Parser) ParseAggregateRequestContext(ctx context.Context, request AggregateRequest, macros ...filtering.Macro) (ParsedAggregateRequest, error) {
+	return p.parseAggregateRequest(ctx, request, macros...)
+}
+
+func (p *Parser) parseAggregateRequest(ctx context.Context, request AggregateRequest, macros ...filtering.Macro) (ParsedAggregateRequest, error) {
+	groupByPaths, err := p.parseGroupBy(request.GetGroupBy())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing group by")
+	}
+	aggregations, err := p.parseAggregations(request.GetAggregations())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing aggregations")
+	}
+
+	// Parse filtering. Searches only restrict the aggregated rows, as aggregates are not ranked.
+	parsedFilter, err := p.parseFilter(request, macros...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyScope(ctx, parsedFilter); err != nil {
+		return nil, err
+	}
+
+	return &parsedAggregateRequest{
+		groupByPaths: groupByPaths,
+		aggregations: aggregations,
+		whereClause:  parsedFilter.whereClause,
+		whereParams:  parsedFilter.whereParams,
+	}, nil
+}
+
+func (p *Parser) parseGroupBy(groupBy string) ([]string, error) {
+	var paths []string
+	seen := map[string]struct{}{}
+	for _, path := range splitList(groupBy) {
+		if _, ok := p.groupByPaths[path]; !ok {
+			return nil, errors.Errorf("cannot group by %s", path)
+		}
+		if _, ok := seen[path]; ok {
+			return nil, errors.Errorf("duplicate group by path %s", path)
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (p *Parser) parseAggregations(aggregationsString string) ([]*Aggregation, error) {
+	var aggregations []*Aggregation
+	seen := map[string]struct{}{}
+	for _, element := range splitList(aggregationsString) {
+		matches := aggregationRegexp.FindStringSubmatch(element)
+		if matches == nil {
+			return nil, errors.Errorf("invalid aggregation %s", element)
+		}
+		aggregation := &Aggregation{Function: AggregateFunction(strings.ToUpper(matches[1])), Path: matches[2]}
+		if aggregation.Function == AggregateFunctionCount {
+			if aggregation.Path != "" && aggregation.Path != "*" {
+				return nil, errors.Errorf("invalid aggregation %s: count does not take a path", element)
+			}
+			aggregation.Path = ""
+			aggregation.Alias = "count"
+		} else {
+			if _, ok := p.aggregatePaths[aggregation.Path]; !ok {
+				return nil, errors.Errorf("invalid aggregation %s: cannot aggregate %s", element, aggregation.Path)
+			}
+			aggregation.Alias = strings.ToLower(string(aggregation.Function)) + "_" + aggregation.Path
+		}
+		if _, ok := seen[aggregation.Alias]; ok {
+			return nil, errors.Errorf("duplicate aggregation %s", element)
+		}
+		seen[aggregation.Alias] = struct{}{}
+		aggregations = append(aggregations, aggregation)
+	}
+	if len(aggregations) == 0 {
+		return nil, errors.New("at least one aggregation is required")
+	}
+	return aggregations, nil
+}
+
+// splitList splits a comma separated list, trimming whitespace and dropping empty elements.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
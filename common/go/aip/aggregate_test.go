@@ -0,0 +1,74 @@
+package aip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+type aggregateRequest struct {
+	proto.Message
+	filter       string
+	groupBy      string
+	aggregations string
+}
+
+func (r *aggregateRequest) GetFilter() string       { return r.filter }
+func (r *aggregateRequest) GetGroupBy() string      { return r.groupBy }
+func (r *aggregateRequest) GetAggregations() string { return r.aggregations }
+
+func TestParseAggregateRequest(t *testing.T) {
+	parser := newTestParser().WithAggregationOptions([]string{"author", "title"}, []string{"page_count"})
+
+	parsedRequest, err := parser.ParseAggregateRequest(&aggregateRequest{
+		groupBy:      "author, title",
+		aggregations: "count(), SUM(page_count), max( page_count )",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"author", "title"}, parsedRequest.GetGroupByPaths())
+	require.Equal(t, []*Aggregation{
+		{Function: AggregateFunctionCount, Alias: "count"},
+		{Function: AggregateFunctionSum, Path: "page_count", Alias: "sum_page_count"},
+		{Function: AggregateFunctionMax, Path: "page_count", Alias: "max_page_count"},
+	}, parsedRequest.GetAggregations())
+	require.Equal(t, "author, title, COUNT(*) AS count, SUM(page_count) AS sum_page_count, MAX(page_count) AS max_page_count", parsedRequest.GetSQLSelectClause())
+	require.Equal(t, "GROUP BY author, title", parsedRequest.GetSQLGroupByClause())
+
+	parsedRequest, err = parser.ParseAggregateRequest(&aggregateRequest{aggregations: "count(*)"})
+	require.NoError(t, err)
+	require.Empty(t, parsedRequest.GetGroupByPaths())
+	require.Equal(t, "COUNT(*) AS count", parsedRequest.GetSQLSelectClause())
+	require.Equal(t, "", parsedRequest.GetSQLGroupByClause())
+
+	for _, testCase := range []struct {
+		groupBy       string
+		aggregations  string
+		expectedError string
+	}{
+		{groupBy: "page_count", aggregations: "count()", expectedError: "cannot group by page_count"},
+		{groupBy: "author, author", aggregations: "count()", expectedError: "duplicate group by path author"},
+		{aggregations: "", expectedError: "at least one aggregation is required"},
+		{aggregations: "avg(page_count)", expectedError: "invalid aggregation avg(page_count)"},
+		{aggregations: "count(author)", expectedError: "count does not take a path"},
+		{aggregations: "sum(author)", expectedError: "cannot aggregate author"},
+		{aggregations: "sum(page_count), SUM(page_count)", expectedError: "duplicate aggregation"},
+	} {
+		_, err := parser.ParseAggregateRequest(&aggregateRequest{groupBy: testCase.groupBy, aggregations: testCase.aggregations})
+		require.ErrorContains(t, err, testCase.expectedError)
+	}
+}
+
+func TestParseAggregateRequestSearch(t *testing.T) {
+	request := &aggregateRequest{filter: `search("orwell")`, groupBy: "author", aggregations: "count()"}
+
+	_, err := newTestParser().WithAggregationOptions([]string{"author"}, nil).ParseAggregateRequest(request)
+	require.ErrorContains(t, err, "search is not supported")
+
+	parser := newTestParser().WithAggregationOptions([]string{"author"}, nil).WithSearch("search_vector", "english")
+	parsedRequest, err := parser.ParseAggregateRequest(request)
+	require.NoError(t, err)
+	whereClause, whereParams := parsedRequest.GetSQLWhereClause()
+	require.Equal(t, "WHERE search_vector @@ websearch_to_tsquery('english', $1)", whereClause)
+	require.Equal(t, []any{"orwell"}, whereParams)
+}
@@ -1,7 +1,9 @@
 package aip
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	"go.einride.tech/aip/filtering"
@@ -29,6 +31,10 @@ type Request interface {
 type Parser struct {
 	declarations   *filtering.Declarations
 	orderByOptions []string
+	groupByPaths   map[string]struct{}
+	aggregatePaths map[string]struct{}
+	search         *searchOptions
+	scopeFN        ScopeFN
 }
 
 // NewParser instantiates and returns a new parser.
@@ -44,7 +50,7 @@ var nullFunctionDeclarationOption = filtering.DeclareFunction(
 
 // WithFilteringOptions sets filtering options. This method panics on error as this method should be declared as a topline variable.
 func (p *Parser) WithFilteringOptions(declarationOptions ...filtering.DeclarationOption) *Parser {
-	declarationOptions = append(declarationOptions, filtering.DeclareStandardFunctions(), nullFunctionDeclarationOption)
+	declarationOptions = append(declarationOptions, filtering.DeclareStandardFunctions(), nullFunctionDeclarationOption, searchFunctionDeclarationOption)
 	declarations, err := filtering.NewDeclarations(declarationOptions...)
 	if err != nil {
 		log.Panicf("invalid declaration options: %v", err)
@@ -59,6 +65,19 @@ func (p *Parser) WithOrderByOptions(orderByOptions ...string) *Parser {
 	return p
 }
 
+// ScopeFN returns an equality condition restricting every query of a parser, e.g. to the caller's tenant.
+type ScopeFN func(ctx context.Context) (column string, value any, err error)
+
+// errScopedParser is returned when a scoped parser is asked to parse a request without its context.
+var errScopedParser = errors.New("scoped parsers must parse requests with their context")
+
+// WithScope restricts every request this parser parses, whatever its filter. Scoped parsers only parse requests with
+// their context (ParseRequestContext, ParseAggregateRequestContext), so that a store cannot skip the scope.
+func (p *Parser) WithScope(scopeFN ScopeFN) *Parser {
+	p.scopeFN = scopeFN
+	return p
+}
+
 // ParsedRequest is a request that is parsed.
 type ParsedRequest interface {
 	// Returns an SQL limit/offset clause. The limit is 0 if the request's page size is 0, or pageSize + 1 otherwise. Offset is the page token's offset if it exists.
@@ -78,6 +97,8 @@ type parsedRequest struct {
 	orderBy     ordering.OrderBy
 	whereClause string
 	whereParams []any
+	// Ranks search results by relevance when the request has no order by.
+	searchRankOrderByClause string
 }
 
 // GetSQLLimitClause implements the ParsedRequest interface.
@@ -98,6 +119,9 @@ func (pr *parsedRequest) GetNextPageToken(itemsFetched int) string {
 
 // GetSQLOrderByClause implements the ParsedRequest interface.
 func (pr *parsedRequest) GetSQLOrderByClause() string {
+	if pr.searchRankOrderByClause != "" {
+		return pr.searchRankOrderByClause
+	}
 	return spanordering.TranspileOrderBy(pr.orderBy)
 }
 
@@ -107,7 +131,21 @@ func (pr *parsedRequest) GetSQLWhereClause() (string, []any) {
 }
 
 // ParseRequest parses the given request. Any error should be returned as a InvalidArgument error.
+// Scoped parsers return an error, as they need the request's context.
 func (p *Parser) ParseRequest(request Request, macros ...filtering.Macro) (ParsedRequest, error) {
+	if p.scopeFN != nil {
+		return nil, errScopedParser
+	}
+	return p.parseRequest(context.Background(), request, macros...)
+}
+
+// ParseRequestContext parses the given request, restricted by the parser's scope if any. Errors of the scope are
+// wrapped, any other error should be returned as a InvalidArgument error.
+func (p *Parser) ParseRequestContext(ctx context.Context, request Request, macros ...filtering.Macro) (ParsedRequest, error) {
+	return p.parseRequest(ctx, request, macros...)
+}
+
+func (p *Parser) parseRequest(ctx context.Context, request Request, macros ...filtering.Macro) (ParsedRequest, error) {
 	// Parse page token.
 	pageToken, err := pagination.ParsePageToken(request)
 	if err != nil {
@@ -124,6 +162,36 @@ func (p *Parser) ParseRequest(request Request, macros ...filtering.Macro) (Parse
 	}
 
 	// Parse filtering.
+	parsedFilter, err := p.parseFilter(request, macros...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyScope(ctx, parsedFilter); err != nil {
+		return nil, err
+	}
+
+	parsedRequest := &parsedRequest{
+		request:     request,
+		pageToken:   pageToken,
+		orderBy:     orderBy,
+		whereClause: parsedFilter.whereClause,
+		whereParams: parsedFilter.whereParams,
+	}
+	if parsedFilter.searchTSQuery != "" && len(orderBy.Fields) == 0 {
+		parsedRequest.searchRankOrderByClause = fmt.Sprintf("ORDER BY ts_rank(%s, %s) DESC", p.search.vectorColumn, parsedFilter.searchTSQuery)
+	}
+	return parsedRequest, nil
+}
+
+type parsedFilter struct {
+	whereClause string
+	whereParams []any
+	// The tsquery of the filter's search, if any.
+	searchTSQuery string
+}
+
+// parseFilter parses the filter of the given request, applies macros to it, and transpiles it to SQL, search included.
+func (p *Parser) parseFilter(request filtering.Request, macros ...filtering.Macro) (*parsedFilter, error) {
 	filter, err := filtering.ParseFilter(request, p.declarations)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing filter")
@@ -135,16 +203,66 @@ func (p *Parser) ParseRequest(request Request, macros ...filtering.Macro) (Parse
 		}
 	}
 
+	filter, searchQuery, err := extractSearch(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing search")
+	}
+	if searchQuery != "" && p.search == nil {
+		return nil, errors.Errorf("%s is not supported on this resource", searchFunction)
+	}
+
 	whereClause, whereParams, err := spanfiltering.TranspileFilter(filter)
 	if err != nil {
 		return nil, errors.Wrap(err, "transpiling filter to SQL")
 	}
+	parsedFilter := &parsedFilter{whereClause: whereClause, whereParams: whereParams}
+	if searchQuery != "" {
+		p.applySearch(parsedFilter, searchQuery)
+	}
+	return parsedFilter, nil
+}
 
-	return &parsedRequest{
-		request:     request,
-		pageToken:   pageToken,
-		orderBy:     orderBy,
-		whereClause: whereClause,
-		whereParams: whereParams,
-	}, nil
+// applyScope restricts the parsed filter by the parser's scope, if any.
+func (p *Parser) applyScope(ctx context.Context, pf *parsedFilter) error {
+	if p.scopeFN == nil {
+		return nil
+	}
+	column, value, err := p.scopeFN(ctx)
+	if err != nil {
+		return errors.Wrap(err, "scoping request")
+	}
+	pf.whereClause, pf.whereParams = andWhereEqual(pf.whereClause, pf.whereParams, column, value)
+	return nil
+}
+
+// andWhere returns the given where clause, further restricted by the given condition.
+func andWhere(whereClause, condition string) string {
+	if whereClause == "" {
+		return "WHERE " + condition
+	}
+	return fmt.Sprintf("WHERE (%s) AND %s", strings.TrimPrefix(whereClause, "WHERE "), condition)
+}
+
+// WithEqualityCondition returns a parsed request whose where clause is further restricted to rows where the given
+// column equals the given value. This lets callers inject conditions (e.g. tenant scoping) a client cannot override.
+func WithEqualityCondition(parsedRequest ParsedRequest, column string, value any) ParsedRequest {
+	return &conditionedRequest{ParsedRequest: parsedRequest, column: column, value: value}
+}
+
+type conditionedRequest struct {
+	ParsedRequest
+	column string
+	value  any
+}
+
+// GetSQLWhereClause implements the ParsedRequest interface.
+func (cr *conditionedRequest) GetSQLWhereClause() (string, []any) {
+	whereClause, whereParams := cr.ParsedRequest.GetSQLWhereClause()
+	return andWhereEqual(whereClause, whereParams, cr.column, cr.value)
+}
+
+// andWhereEqual returns the given where clause and params, further restricted to rows where the column equals the value.
+func andWhereEqual(whereClause string, whereParams []any, column string, value any) (string, []any) {
+	whereParams = append(whereParams, value)
+	return andWhere(whereClause, fmt.Sprintf("%s = $%d", column, len(whereParams))), whereParams
 }
@@ -0,0 +1,78 @@
+package aip
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type listRequest struct {
+	*longrunningpb.ListOperationsRequest
+}
+
+func (r *listRequest) GetOrderBy() string { return "" }
+
+type tenantContextKey struct{}
+
+// tenantScope scopes queries to the tenant of the context.
+func tenantScope(ctx context.Context) (string, any, error) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	if !ok {
+		return "", nil, errors.New("no tenant")
+	}
+	return "organization_id", tenant, nil
+}
+
+func TestParserScope(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantContextKey{}, "acme")
+	parser := newTestParser().WithSearch("search_vector", "english").WithScope(tenantScope)
+
+	t.Run("RestrictsListRequests", func(t *testing.T) {
+		request := &listRequest{&longrunningpb.ListOperationsRequest{Filter: `search("orwell")`}}
+		parsedRequest, err := parser.ParseRequestContext(ctx, request)
+		require.NoError(t, err)
+		whereClause, whereParams := parsedRequest.GetSQLWhereClause()
+		require.Equal(t, "WHERE (search_vector @@ websearch_to_tsquery('english', $1)) AND organization_id = $2", whereClause)
+		require.Equal(t, []any{"orwell", "acme"}, whereParams)
+		// The search still ranks results.
+		require.Equal(t, "ORDER BY ts_rank(search_vector, websearch_to_tsquery('english', $1)) DESC", parsedRequest.GetSQLOrderByClause())
+
+		parsedRequest, err = parser.ParseRequestContext(ctx, &listRequest{&longrunningpb.ListOperationsRequest{}})
+		require.NoError(t, err)
+		whereClause, whereParams = parsedRequest.GetSQLWhereClause()
+		require.Equal(t, "WHERE organization_id = $1", whereClause)
+		require.Equal(t, []any{"acme"}, whereParams)
+	})
+
+	t.Run("RestrictsAggregateRequests", func(t *testing.T) {
+		parser := newTestParser().WithAggregationOptions([]string{"author"}, nil).WithScope(tenantScope)
+		parsedRequest, err := parser.ParseAggregateRequestContext(ctx, &aggregateRequest{aggregations: "count()"})
+		require.NoError(t, err)
+		whereClause, whereParams := parsedRequest.GetSQLWhereClause()
+		require.Equal(t, "WHERE organization_id = $1", whereClause)
+		require.Equal(t, []any{"acme"}, whereParams)
+
+		_, err = parser.ParseAggregateRequest(&aggregateRequest{aggregations: "count()"})
+		require.ErrorIs(t, err, errScopedParser)
+	})
+
+	t.Run("FailsClosed", func(t *testing.T) {
+		request := &listRequest{&longrunningpb.ListOperationsRequest{}}
+		_, err := parser.ParseRequest(request)
+		require.ErrorIs(t, err, errScopedParser)
+		_, err = parser.ParseRequestContext(context.Background(), request)
+		require.ErrorContains(t, err, "no tenant")
+	})
+
+	t.Run("UnscopedParsersParseWithoutContext", func(t *testing.T) {
+		parser := newTestParser().WithSearch("search_vector", "english")
+		parsedRequest, err := parser.ParseRequest(&listRequest{&longrunningpb.ListOperationsRequest{Filter: `search("orwell")`}})
+		require.NoError(t, err)
+		whereClause, whereParams := parsedRequest.GetSQLWhereClause()
+		require.Equal(t, "WHERE search_vector @@ websearch_to_tsquery('english', $1)", whereClause)
+		require.Equal(t, []any{"orwell"}, whereParams)
+	})
+}
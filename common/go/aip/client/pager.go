@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	pageTokenFieldName = "page_token"
+	pageSizeFieldName  = "page_size"
+)
+
+// ListRequest defines the interface of a generated ListResourceRequest.
+type ListRequest interface {
+	proto.Message
+	GetPageToken() string
+	GetPageSize() int32
+}
+
+// ListResponse defines the interface of a generated ListResourceResponse.
+type ListResponse interface {
+	proto.Message
+	GetNextPageToken() string
+}
+
+// ListFN is the signature of a generated List client method.
+type ListFN[Req ListRequest, Resp ListResponse] func(ctx context.Context, request Req, options ...grpc.CallOption) (Resp, error)
+
+// Pager iterates over every resource of a List RPC, fetching pages as needed.
+// It is used like a `bufio.Scanner`:
+//
+//	pager := client.NewPager(libraryClient.ListBooks, request, (*pb.ListBooksResponse).GetBooks)
+//	for pager.Next(ctx) {
+//		book := pager.Item()
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+type Pager[Req ListRequest, Resp ListResponse, Res any] struct {
+	listFN      ListFN[Req, Resp]
+	request     Req
+	itemsFN     func(Resp) []Res
+	pageSize    int32
+	callOptions []grpc.CallOption
+
+	items         []Res
+	item          Res
+	nextPageToken string
+	done          bool
+	err           error
+}
+
+// NewPager instantiates and returns a new pager, starting at the request's page token.
+// The items function extracts the resources of a response, typically the generated getter of its repeated field.
+func NewPager[Req ListRequest, Resp ListResponse, Res any](listFN ListFN[Req, Resp], request Req, itemsFN func(Resp) []Res) *Pager[Req, Resp, Res] {
+	return &Pager[Req, Resp, Res]{
+		listFN:        listFN,
+		request:       request,
+		itemsFN:       itemsFN,
+		pageSize:      request.GetPageSize(),
+		nextPageToken: request.GetPageToken(),
+	}
+}
+
+// WithPageSize overrides the page size of the request. Larger pages mean fewer round trips.
+func (p *Pager[Req, Resp, Res]) WithPageSize(pageSize int32) *Pager[Req, Resp, Res] {
+	p.pageSize = pageSize
+	return p
+}
+
+// WithCallOptions sets call options passed to every List call.
+func (p *Pager[Req, Resp, Res]) WithCallOptions(callOptions ...grpc.CallOption) *Pager[Req, Resp, Res] {
+	p.callOptions = callOptions
+	return p
+}
+
+// Next advances the pager to the next resource, fetching the next page if needed. It returns false when all resources
+// have been iterated over, the context is cancelled, or a List call fails. Check `Err` once it returns false.
+func (p *Pager[Req, Resp, Res]) Next(ctx context.Context) bool {
+	for len(p.items) == 0 {
+		if p.done || p.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		if err := p.fetchPage(ctx); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	p.item, p.items = p.items[0], p.items[1:]
+	return true
+}
+
+// Item returns the current resource.
+func (p *Pager[Req, Resp, Res]) Item() Res {
+	return p.item
+}
+
+// Err returns the error that stopped the pager, if any.
+func (p *Pager[Req, Resp, Res]) Err() error {
+	return p.err
+}
+
+// ForEach calls the given function on every remaining resource, stopping at the first error.
+func (p *Pager[Req, Resp, Res]) ForEach(ctx context.Context, fn func(Res) error) error {
+	for p.Next(ctx) {
+		if err := fn(p.Item()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// All returns every remaining resource.
+func (p *Pager[Req, Resp, Res]) All(ctx context.Context) ([]Res, error) {
+	var resources []Res
+	for p.Next(ctx) {
+		resources = append(resources, p.Item())
+	}
+	return resources, p.Err()
+}
+
+// Seq returns an iterator over every remaining resource, with the signature of `iter.Seq2[Res, error]`. It stops after
+// yielding the first error, along with a zero resource. Once the module targets Go 1.23, it can be ranged over:
+//
+//	for book, err := range client.NewPager(libraryClient.ListBooks, request, (*pb.ListBooksResponse).GetBooks).Seq(ctx) {
+//		if err != nil {
+//			...
+//		}
+//	}
+func (p *Pager[Req, Resp, Res]) Seq(ctx context.Context) func(yield func(Res, error) bool) {
+	return func(yield func(Res, error) bool) {
+		for p.Next(ctx) {
+			if !yield(p.Item(), nil) {
+				return
+			}
+		}
+		if err := p.Err(); err != nil {
+			var zero Res
+			yield(zero, err)
+		}
+	}
+}
+
+func (p *Pager[Req, Resp, Res]) fetchPage(ctx context.Context) error {
+	request := proto.Clone(p.request).(Req)
+	if err := setField(request, pageTokenFieldName, protoreflect.ValueOfString(p.nextPageToken)); err != nil {
+		return err
+	}
+	if err := setField(request, pageSizeFieldName, protoreflect.ValueOfInt32(p.pageSize)); err != nil {
+		return err
+	}
+	response, err := p.listFN(ctx, request, p.callOptions...)
+	if err != nil {
+		return errors.Wrap(err, "listing page")
+	}
+	p.items = p.itemsFN(response)
+	p.nextPageToken = response.GetNextPageToken()
+	p.done = p.nextPageToken == ""
+	return nil
+}
+
+func setField(message proto.Message, name protoreflect.Name, value protoreflect.Value) error {
+	reflectMessage := message.ProtoReflect()
+	fieldDescriptor := reflectMessage.Descriptor().Fields().ByName(name)
+	if fieldDescriptor == nil {
+		return errors.Errorf("%s has no %s field", reflectMessage.Descriptor().FullName(), name)
+	}
+	reflectMessage.Set(fieldDescriptor, value)
+	return nil
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeList serves `total` operations, paginated with offset page tokens.
+func fakeList(total int, pageSizes *[]int32) ListFN[*longrunningpb.ListOperationsRequest, *longrunningpb.ListOperationsResponse] {
+	return func(ctx context.Context, request *longrunningpb.ListOperationsRequest, _ ...grpc.CallOption) (*longrunningpb.ListOperationsResponse, error) {
+		*pageSizes = append(*pageSizes, request.GetPageSize())
+		offset := 0
+		if request.GetPageToken() != "" {
+			offset, _ = strconv.Atoi(request.GetPageToken())
+		}
+		response := &longrunningpb.ListOperationsResponse{}
+		for i := offset; i < total && i < offset+int(request.GetPageSize()); i++ {
+			response.Operations = append(response.Operations, &longrunningpb.Operation{Name: strconv.Itoa(i)})
+		}
+		if end := offset + int(request.GetPageSize()); end < total {
+			response.NextPageToken = strconv.Itoa(end)
+		}
+		return response, nil
+	}
+}
+
+func TestPager(t *testing.T) {
+	ctx := context.Background()
+	var pageSizes []int32
+	request := &longrunningpb.ListOperationsRequest{Name: "operations", PageSize: 2}
+	pager := NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations)
+	operations, err := pager.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, operations, 5)
+	for i, operation := range operations {
+		require.Equal(t, strconv.Itoa(i), operation.GetName())
+	}
+	require.Equal(t, []int32{2, 2, 2}, pageSizes)
+	// The caller's request is left untouched.
+	require.Empty(t, request.GetPageToken())
+
+	pageSizes = nil
+	pager = NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations).WithPageSize(10)
+	operations, err = pager.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, operations, 5)
+	require.Equal(t, []int32{10}, pageSizes)
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	pager = NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations)
+	require.True(t, pager.Next(cancelledCtx))
+	require.True(t, pager.Next(cancelledCtx))
+	cancel()
+	require.False(t, pager.Next(cancelledCtx))
+	require.ErrorIs(t, pager.Err(), context.Canceled)
+}
+
+func TestPagerSeq(t *testing.T) {
+	ctx := context.Background()
+	request := &longrunningpb.ListOperationsRequest{Name: "operations", PageSize: 2}
+
+	t.Run("YieldsEveryResource", func(t *testing.T) {
+		var pageSizes []int32
+		pager := NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations)
+		var names []string
+		pager.Seq(ctx)(func(operation *longrunningpb.Operation, err error) bool {
+			require.NoError(t, err)
+			names = append(names, operation.GetName())
+			return true
+		})
+		require.Equal(t, []string{"0", "1", "2", "3", "4"}, names)
+		require.Equal(t, []int32{2, 2, 2}, pageSizes)
+	})
+
+	t.Run("StopsFetchingPagesOnceTheCallerStops", func(t *testing.T) {
+		var pageSizes []int32
+		pager := NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations)
+		var names []string
+		pager.Seq(ctx)(func(operation *longrunningpb.Operation, err error) bool {
+			require.NoError(t, err)
+			names = append(names, operation.GetName())
+			return len(names) < 2
+		})
+		require.Equal(t, []string{"0", "1"}, names)
+		require.Len(t, pageSizes, 1)
+	})
+
+	t.Run("YieldsTheErrorLast", func(t *testing.T) {
+		var pageSizes []int32
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		pager := NewPager(fakeList(5, &pageSizes), request, (*longrunningpb.ListOperationsResponse).GetOperations)
+		var names []string
+		var errs []error
+		pager.Seq(cancelledCtx)(func(operation *longrunningpb.Operation, err error) bool {
+			if err != nil {
+				require.Nil(t, operation)
+				errs = append(errs, err)
+				return true
+			}
+			names = append(names, operation.GetName())
+			if len(names) == 2 {
+				cancel()
+			}
+			return true
+		})
+		require.Equal(t, []string{"0", "1"}, names)
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], context.Canceled)
+	})
+}
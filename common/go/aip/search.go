@@ -0,0 +1,128 @@
+package aip
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.einride.tech/aip/filtering"
+	expr "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+const searchFunction = "search"
+
+// Declares the `search("...")` filter shorthand. It is declared on every parser, but only usable with `WithSearch`.
+var searchFunctionDeclarationOption = filtering.DeclareFunction(
+	searchFunction,
+	filtering.NewFunctionOverload(searchFunction+"_string", filtering.TypeBool, filtering.TypeString),
+)
+
+type searchOptions struct {
+	vectorColumn string
+	config       string
+}
+
+// WithSearch enables full-text search through the `search("orwell dystopia")` filter shorthand.
+// Searches match the given tsvector column against `websearch_to_tsquery` using the given text search config
+// (e.g. "english"), which must be the one the column is generated with. Unless the request specifies an order by,
+// results are ranked by relevance.
+func (p *Parser) WithSearch(vectorColumn, config string) *Parser {
+	p.search = &searchOptions{vectorColumn: vectorColumn, config: config}
+	return p
+}
+
+// extractSearch removes the search call from the filter, returning the search query, if any.
+// A search must be a top-level conjunct of the filter, i.e. `search("a") AND author = "b"` but not `search("a") OR ...`.
+func extractSearch(filter filtering.Filter) (filtering.Filter, string, error) {
+	if filter.CheckedExpr == nil {
+		return filter, "", nil
+	}
+	remainingExpr, query, err := extractSearchFromExpr(filter.CheckedExpr.GetExpr())
+	if err != nil || query == "" {
+		return filter, "", err
+	}
+	if remainingExpr == nil {
+		return filtering.Filter{}, query, nil
+	}
+	filter.CheckedExpr.Expr = remainingExpr
+	return filter, query, nil
+}
+
+func extractSearchFromExpr(e *expr.Expr) (*expr.Expr, string, error) {
+	callExpr := e.GetCallExpr()
+	if callExpr == nil {
+		return e, "", nil
+	}
+	switch callExpr.GetFunction() {
+	case searchFunction:
+		if len(callExpr.GetArgs()) != 1 {
+			return nil, "", errors.Errorf("%s expects a single argument", searchFunction)
+		}
+		constExpr := callExpr.GetArgs()[0].GetConstExpr()
+		if constExpr == nil || constExpr.GetStringValue() == "" {
+			return nil, "", errors.Errorf("%s expects a non-empty string literal", searchFunction)
+		}
+		return nil, constExpr.GetStringValue(), nil
+	case filtering.FunctionAnd:
+		var remainingArgs []*expr.Expr
+		var query string
+		for _, arg := range callExpr.GetArgs() {
+			remainingArg, argQuery, err := extractSearchFromExpr(arg)
+			if err != nil {
+				return nil, "", err
+			}
+			if argQuery != "" {
+				if query != "" {
+					return nil, "", errors.Errorf("filter can only hold a single %s", searchFunction)
+				}
+				query = argQuery
+			}
+			if remainingArg != nil {
+				remainingArgs = append(remainingArgs, remainingArg)
+			}
+		}
+		switch {
+		case query == "":
+			return e, "", nil
+		case len(remainingArgs) == 0:
+			return nil, query, nil
+		case len(remainingArgs) == 1:
+			return remainingArgs[0], query, nil
+		default:
+			// Rebuild the conjunction without the search.
+			return &expr.Expr{
+				Id: e.GetId(),
+				ExprKind: &expr.Expr_CallExpr{
+					CallExpr: &expr.Expr_Call{Function: filtering.FunctionAnd, Args: remainingArgs},
+				},
+			}, query, nil
+		}
+	default:
+		if containsSearch(e) {
+			return nil, "", errors.Errorf("%s must be a top-level conjunct of the filter", searchFunction)
+		}
+		return e, "", nil
+	}
+}
+
+func containsSearch(e *expr.Expr) bool {
+	callExpr := e.GetCallExpr()
+	if callExpr == nil {
+		return false
+	}
+	if callExpr.GetFunction() == searchFunction {
+		return true
+	}
+	for _, arg := range callExpr.GetArgs() {
+		if containsSearch(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// applySearch restricts the parsed filter to rows matching the search query.
+func (p *Parser) applySearch(pf *parsedFilter, query string) {
+	pf.whereParams = append(pf.whereParams, query)
+	pf.searchTSQuery = fmt.Sprintf("websearch_to_tsquery('%s', $%d)", p.search.config, len(pf.whereParams))
+	pf.whereClause = andWhere(pf.whereClause, fmt.Sprintf("%s @@ %s", p.search.vectorColumn, pf.searchTSQuery))
+}
@@ -0,0 +1,108 @@
+package aip
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.einride.tech/aip/filtering"
+	expr "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+type filterRequest struct{ filter string }
+
+func (r *filterRequest) GetFilter() string { return r.filter }
+
+func newTestParser() *Parser {
+	return NewParser().WithFilteringOptions(
+		filtering.DeclareIdent("author", filtering.TypeString),
+		filtering.DeclareIdent("title", filtering.TypeString),
+		filtering.DeclareIdent("page_count", filtering.TypeInt),
+	)
+}
+
+// formatExpr formats an expression as nested calls, e.g. `AND(=(author, "a"), =(title, "b"))`.
+func formatExpr(e *expr.Expr) string {
+	switch {
+	case e == nil:
+		return ""
+	case e.GetIdentExpr() != nil:
+		return e.GetIdentExpr().GetName()
+	case e.GetConstExpr() != nil:
+		if stringValue, ok := e.GetConstExpr().GetConstantKind().(*expr.Constant_StringValue); ok {
+			return fmt.Sprintf("%q", stringValue.StringValue)
+		}
+		return fmt.Sprintf("%d", e.GetConstExpr().GetInt64Value())
+	default:
+		args := make([]string, 0, len(e.GetCallExpr().GetArgs()))
+		for _, arg := range e.GetCallExpr().GetArgs() {
+			args = append(args, formatExpr(arg))
+		}
+		return fmt.Sprintf("%s(%s)", e.GetCallExpr().GetFunction(), strings.Join(args, ", "))
+	}
+}
+
+func TestExtractSearch(t *testing.T) {
+	parser := newTestParser()
+	for _, testCase := range []struct {
+		name          string
+		filter        string
+		expectedQuery string
+		expectedExpr  string
+		expectedError string
+	}{
+		{name: "no search", filter: `author = "a"`, expectedExpr: `=(author, "a")`},
+		{name: "search alone", filter: `search("orwell dystopia")`, expectedQuery: "orwell dystopia"},
+		{name: "search and one condition", filter: `search("orwell") AND author = "a"`, expectedQuery: "orwell", expectedExpr: `=(author, "a")`},
+		{
+			name:          "search and two conditions",
+			filter:        `search("orwell") AND author = "a" AND page_count > 100`,
+			expectedQuery: "orwell",
+			expectedExpr:  `AND(=(author, "a"), >(page_count, 100))`,
+		},
+		{
+			name:          "search between conditions",
+			filter:        `author = "a" AND search("orwell") AND title = "b" AND page_count > 100`,
+			expectedQuery: "orwell",
+			expectedExpr:  `AND(AND(=(author, "a"), =(title, "b")), >(page_count, 100))`,
+		},
+		{name: "search under or", filter: `author = "a" OR search("orwell")`, expectedError: "must be a top-level conjunct"},
+		{name: "search under not", filter: `NOT search("orwell")`, expectedError: "must be a top-level conjunct"},
+		{name: "two searches", filter: `search("orwell") AND search("huxley")`, expectedError: "a single search"},
+		{name: "empty search", filter: `search("")`, expectedError: "non-empty string literal"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			filter, err := filtering.ParseFilter(&filterRequest{filter: testCase.filter}, parser.declarations)
+			require.NoError(t, err)
+			filter, query, err := extractSearch(filter)
+			if testCase.expectedError != "" {
+				require.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedQuery, query)
+			require.Equal(t, testCase.expectedExpr, formatExpr(filter.CheckedExpr.GetExpr()))
+		})
+	}
+}
+
+func TestParseFilterSearch(t *testing.T) {
+	parser := newTestParser().WithSearch("search_vector", "english")
+	parsedFilter, err := parser.parseFilter(&filterRequest{filter: `search("orwell") AND author = "a" AND page_count > 100`})
+	require.NoError(t, err)
+	// The search is the last condition of the where clause, holding the last param.
+	require.Equal(t, "orwell", parsedFilter.whereParams[len(parsedFilter.whereParams)-1])
+	tsQuery := fmt.Sprintf("websearch_to_tsquery('english', $%d)", len(parsedFilter.whereParams))
+	require.Equal(t, tsQuery, parsedFilter.searchTSQuery)
+	require.True(t, strings.HasSuffix(parsedFilter.whereClause, "search_vector @@ "+tsQuery), parsedFilter.whereClause)
+
+	parsedFilter, err = parser.parseFilter(&filterRequest{filter: `search("orwell")`})
+	require.NoError(t, err)
+	require.Equal(t, "WHERE search_vector @@ websearch_to_tsquery('english', $1)", parsedFilter.whereClause)
+	require.Equal(t, []any{"orwell"}, parsedFilter.whereParams)
+
+	// Parsers without search reject it.
+	_, err = newTestParser().parseFilter(&filterRequest{filter: `search("orwell")`})
+	require.ErrorContains(t, err, "search is not supported")
+}
@@ -0,0 +1,201 @@
+// Package aws authenticates requests to AWS APIs with Signature Version 4, so that they can be called over plain HTTP.
+// Signatures are tested against the vectors of the AWS Signature Version 4 test suite.
+//
+// It only covers what our AWS clients need, and is not a replacement for the AWS SDK:
+//   - Credentials are static. CredentialsFromEnv reads the standard environment variables, and web identity (IRSA),
+//     instance profiles and the shared config files are not supported. Callers using temporary credentials must
+//     refresh them, and sign every request with the current ones.
+//   - Payloads are signed whole, so chunked uploads are not supported.
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	timeFormat       = "20060102T150405Z"
+	dateFormat       = "20060102"
+	// UnsignedPayload is the payload hash of requests whose body is not signed, e.g. presigned URLs.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Credentials of an AWS principal.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials.
+	SessionToken string
+}
+
+// CredentialsFromEnv returns the credentials set in the standard AWS environment variables. Session tokens are returned
+// as is, and are not refreshed.
+func CredentialsFromEnv() (*Credentials, error) {
+	credentials := &Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return credentials, nil
+}
+
+// RegionFromEnv returns the region set in the standard AWS environment variables.
+func RegionFromEnv() (string, error) {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region, nil
+		}
+	}
+	return "", errors.New("AWS_REGION must be set")
+}
+
+// HashPayload returns the hex encoded sha256 of a payload, as expected by SignRequest.
+func HashPayload(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignRequest signs a request, whose body hashes to payloadHash, by setting its Authorization header.
+// The host, content type and every `x-amz-*` header are signed, so they must be set beforehand.
+func SignRequest(request *http.Request, payloadHash string, credentials *Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	request.Header.Set("X-Amz-Date", now.Format(timeFormat))
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	headers := map[string]string{"host": host(request)}
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(dateFormat), region, service)
+	signedHeaders, signature := sign(request.Method, request.URL, headers, payloadHash, credentials, scope, now)
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", signingAlgorithm, credentials.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// PresignURL returns a URL granting its bearer the right to send the request, until it expires.
+// Only the host is signed, so the bearer can set any other header.
+func PresignURL(request *http.Request, credentials *Credentials, region, service string, expiry time.Duration, now time.Time) string {
+	now = now.UTC()
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(dateFormat), region, service)
+	presignedURL := *request.URL
+	query := presignedURL.Query()
+	query.Set("X-Amz-Algorithm", signingAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(timeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	presignedURL.RawQuery = CanonicalQuery(query)
+	headers := map[string]string{"host": host(request)}
+	_, signature := sign(request.Method, &presignedURL, headers, UnsignedPayload, credentials, scope, now)
+	presignedURL.RawQuery += "&X-Amz-Signature=" + signature
+	return presignedURL.String()
+}
+
+// sign returns the signed headers and signature of a request.
+func sign(method string, u *url.URL, headers map[string]string, payloadHash string, credentials *Credentials, scope string, now time.Time) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		method, EscapePath(path), CanonicalQuery(u.Query()), canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{signingAlgorithm, now.Format(timeFormat), scope, HashPayload([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func host(request *http.Request) string {
+	if request.Host != "" {
+		return request.Host
+	}
+	return request.URL.Host
+}
+
+// CanonicalQuery encodes a query as it is signed: sorted by encoded key, then encoded value. Sorting whole `key=value`
+// parameters would misorder keys prefixing other keys, e.g. `a-b=1` before `a=1`. Setting it as the URL's raw query
+// ensures the query that is sent is the one that was signed.
+func CanonicalQuery(query url.Values) string {
+	parameters := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, [2]string{uriEncode(key, true), uriEncode(value, true)})
+		}
+	}
+	sort.Slice(parameters, func(i, j int) bool {
+		if parameters[i][0] != parameters[j][0] {
+			return parameters[i][0] < parameters[j][0]
+		}
+		return parameters[i][1] < parameters[j][1]
+	})
+	encoded := make([]string, 0, len(parameters))
+	for _, parameter := range parameters {
+		encoded = append(encoded, parameter[0]+"="+parameter[1])
+	}
+	return strings.Join(encoded, "&")
+}
+
+// EscapePath percent-encodes a URL path as it is signed. Setting it as the URL's raw path ensures the path that is
+// sent is the one that was signed.
+func EscapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes every byte but unreserved characters, and slashes unless encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	encoded := &strings.Builder{}
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
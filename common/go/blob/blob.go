@@ -0,0 +1,112 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Opts holds blob storage opts.
+type Opts struct {
+	Backend    string `long:"blob_backend"     env:"BLOB_BACKEND"     default:"local" choice:"local" choice:"s3" choice:"gcs" description:"Blob storage backend"`
+	Bucket     string `long:"blob_bucket"      env:"BLOB_BUCKET"      default:"blobs" description:"Blob storage bucket"`
+	Directory  string `long:"blob_directory"   env:"BLOB_DIRECTORY"   default:"/tmp/blobs" description:"Root directory of the local backend"`
+	BaseURL    string `long:"blob_base_url"    env:"BLOB_BASE_URL"    description:"Base URL signed URLs of the local backend point to, e.g. https://api.example.com/blobs"`
+	SigningKey string `long:"blob_signing_key" env:"BLOB_SIGNING_KEY" description:"Key used to sign URLs of the local backend, or a secret reference" secret-ref:"true"`
+	Endpoint   string `long:"blob_endpoint"    env:"BLOB_ENDPOINT"    description:"Endpoint of the s3 or gcs backend, e.g. of an S3 compatible store. Defaults to the provider's"`
+	Region     string `long:"blob_region"      env:"BLOB_REGION"      description:"Region of the s3 backend. Defaults to that of the AWS environment"`
+}
+
+// Attributes describes a stored blob.
+type Attributes struct {
+	Key         string
+	ContentType string
+	Size        int64
+	UpdateTime  time.Time
+}
+
+// Bucket stores blobs by key.
+type Bucket interface {
+	// Upload streams the reader's content to the given key, overwriting any existing blob.
+	Upload(ctx context.Context, key, contentType string, reader io.Reader) (*Attributes, error)
+	// Download returns a reader streaming the blob's content. The caller must close it.
+	Download(ctx context.Context, key string) (io.ReadCloser, *Attributes, error)
+	// Delete deletes a blob. Deleting a blob that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL granting access to a blob using the given http method, until it expires.
+	SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error)
+	// URI returns the blob's URI, e.g. `local://bucket/key`, which can be stored to reference a blob.
+	URI(key string) string
+}
+
+// NewBucket instantiates and returns the bucket configured by opts.
+func NewBucket(opts Opts) (Bucket, error) {
+	switch opts.Backend {
+	case localBackend:
+		return NewLocalBucket(opts)
+	case s3Backend:
+		return NewS3Bucket(opts)
+	case gcsBackend:
+		return NewGCSBucket(opts)
+	default:
+		return nil, errors.Errorf("unsupported blob backend %q", opts.Backend)
+	}
+}
+
+// MustNewBucket calls NewBucket and panics on error.
+func MustNewBucket(opts Opts) Bucket {
+	bucket, err := NewBucket(opts)
+	if err != nil {
+		log.Panic(err)
+	}
+	return bucket
+}
+
+// ContentAddressedKey returns a key derived from the content's sha256, under the given prefix.
+// Identical content always maps to the same key, which deduplicates uploads.
+func ContentAddressedKey(prefix string, content []byte) string {
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	// Shard by the first two bytes so that no directory grows unbounded.
+	return path.Join(prefix, digest[:2], digest[2:4], digest)
+}
+
+// ParseURI parses a blob URI into its backend, bucket and key.
+func ParseURI(uri string) (backend, bucket, key string, err error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", errors.Wrapf(err, "parsing blob uri %s", uri)
+	}
+	key = strings.TrimPrefix(parsed.Path, "/")
+	if parsed.Scheme == "" || parsed.Host == "" || key == "" {
+		return "", "", "", errors.Errorf("blob uri %s must be of the form backend://bucket/key", uri)
+	}
+	return parsed.Scheme, parsed.Host, key, nil
+}
+
+func formatURI(backend, bucket, key string) string {
+	return fmt.Sprintf("%s://%s/%s", backend, bucket, key)
+}
+
+// Returns an error if the key could escape its bucket. Keys starting with a dot are reserved.
+func validateKey(key string) error {
+	if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "/") || strings.HasPrefix(key, ".") {
+		return errors.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
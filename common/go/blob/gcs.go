@@ -0,0 +1,205 @@
+package blob
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/aws"
+	"common/go/gcp"
+)
+
+const (
+	gcsBackend         = "gcs"
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	// gcsMaxSignedURLExpiry is the longest expiry GCS accepts for signed URLs.
+	gcsMaxSignedURLExpiry = 7 * 24 * time.Hour
+	gcsSigningAlgorithm   = "GOOG4-RSA-SHA256"
+)
+
+// GCSBucket stores blobs in a Google Cloud Storage bucket. It authenticates with the default credentials of the
+// environment, and signs URLs with the service account key GOOGLE_APPLICATION_CREDENTIALS points to.
+type GCSBucket struct {
+	opts        Opts
+	endpoint    *url.URL
+	tokenSource *gcp.TokenSource
+	httpClient  *http.Client
+
+	signingKeyMutex sync.Mutex
+	signingKey      *gcp.ServiceAccountKey
+}
+
+// NewGCSBucket instantiates and returns a new GCS bucket.
+func NewGCSBucket(opts Opts) (*GCSBucket, error) {
+	rawEndpoint := opts.Endpoint
+	if rawEndpoint == "" {
+		rawEndpoint = gcsDefaultEndpoint
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(rawEndpoint, "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing endpoint %s", rawEndpoint)
+	}
+	return &GCSBucket{
+		opts:        opts,
+		endpoint:    endpoint,
+		tokenSource: gcp.NewDefaultTokenSource(gcp.CloudPlatformScope),
+		httpClient:  http.DefaultClient,
+	}, nil
+}
+
+// Upload implements the Bucket interface. The reader is streamed in a single media upload.
+func (b *GCSBucket) Upload(ctx context.Context, key, contentType string, reader io.Reader) (*Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", b.endpoint, url.PathEscape(b.opts.Bucket), query.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, io.NopCloser(reader))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	request.Header.Set("Content-Type", contentType)
+	response, err := b.do(request, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "uploading blob")
+	}
+	defer response.Body.Close()
+	object := &struct {
+		ContentType string    `json:"contentType"`
+		Size        string    `json:"size"`
+		Updated     time.Time `json:"updated"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(object); err != nil {
+		return nil, errors.Wrap(err, "decoding uploaded object")
+	}
+	size, err := strconv.ParseInt(object.Size, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing size %q", object.Size)
+	}
+	return &Attributes{Key: key, ContentType: object.ContentType, Size: size, UpdateTime: object.Updated}, nil
+}
+
+// Download implements the Bucket interface.
+func (b *GCSBucket) Download(ctx context.Context, key string) (io.ReadCloser, *Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, b.objectURL(key), nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating request")
+	}
+	response, err := b.do(request, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "downloading blob")
+	}
+	return response.Body, attributesFromHeader(key, response), nil
+}
+
+// Delete implements the Bucket interface.
+func (b *GCSBucket) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, b.objectURL(key), nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	response, err := b.do(request, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.Wrap(err, "deleting blob")
+	}
+	if err == nil {
+		response.Body.Close()
+	}
+	return nil
+}
+
+// SignedURL implements the Bucket interface, with V4 signing.
+func (b *GCSBucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if expiry > gcsMaxSignedURLExpiry {
+		return "", errors.Errorf("signed urls expire after at most %s, got %s", gcsMaxSignedURLExpiry, expiry)
+	}
+	signingKey, err := b.getSigningKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	query := url.Values{
+		"X-Goog-Algorithm":     {gcsSigningAlgorithm},
+		"X-Goog-Credential":    {signingKey.ClientEmail + "/" + scope},
+		"X-Goog-Date":          {now.Format("20060102T150405Z")},
+		"X-Goog-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Goog-SignedHeaders": {"host"},
+	}
+	signedURL, err := url.Parse(b.objectURL(key))
+	if err != nil {
+		return "", errors.Wrap(err, "parsing object url")
+	}
+	// GCS canonicalizes queries the way AWS does.
+	signedURL.RawQuery = aws.CanonicalQuery(query)
+	canonicalRequest := strings.Join([]string{
+		method, signedURL.EscapedPath(), signedURL.RawQuery, "host:" + signedURL.Host + "\n", "host", aws.UnsignedPayload,
+	}, "\n")
+	stringToSign := strings.Join([]string{gcsSigningAlgorithm, query.Get("X-Goog-Date"), scope, aws.HashPayload([]byte(canonicalRequest))}, "\n")
+	signature, err := signingKey.Sign([]byte(stringToSign))
+	if err != nil {
+		return "", errors.Wrap(err, "signing url")
+	}
+	signedURL.RawQuery += "&X-Goog-Signature=" + hex.EncodeToString(signature)
+	return signedURL.String(), nil
+}
+
+// URI implements the Bucket interface.
+func (b *GCSBucket) URI(key string) string {
+	return formatURI(gcsBackend, b.opts.Bucket, key)
+}
+
+// getSigningKey loads the service account key signing URLs on first use, as it is only needed to sign URLs.
+func (b *GCSBucket) getSigningKey() (*gcp.ServiceAccountKey, error) {
+	b.signingKeyMutex.Lock()
+	defer b.signingKeyMutex.Unlock()
+	if b.signingKey != nil {
+		return b.signingKey, nil
+	}
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return nil, errors.New("signed urls require a service account key, set GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	signingKey, err := gcp.LoadServiceAccountKey(path)
+	if err != nil {
+		return nil, err
+	}
+	b.signingKey = signingKey
+	return signingKey, nil
+}
+
+// objectURL returns the XML API url of an object, which signed URLs also point to.
+func (b *GCSBucket) objectURL(key string) string {
+	objectURL := *b.endpoint
+	objectURL.Path += "/" + b.opts.Bucket + "/" + key
+	// GCS canonicalizes paths the way AWS does.
+	objectURL.RawPath = aws.EscapePath(objectURL.Path)
+	return objectURL.String()
+}
+
+func (b *GCSBucket) do(request *http.Request, key string) (*http.Response, error) {
+	if err := b.tokenSource.Authorize(request.Context(), request); err != nil {
+		return nil, err
+	}
+	return doBlobRequest(b.httpClient, request, key)
+}
@@ -0,0 +1,151 @@
+package blob
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"common/go/aws"
+)
+
+// fakeGCS is an in-memory GCS, which also issues the tokens of its service account.
+type fakeGCS struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (g *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if r.URL.Path == "/token" {
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/upload/storage/v1/b/bucket/o" && r.Method == http.MethodPost {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.URL.Query().Get("uploadType") != "media" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		g.objects[r.URL.Query().Get("name")] = body
+		json.NewEncoder(w).Encode(map[string]any{
+			"contentType": r.Header.Get("Content-Type"),
+			"size":        strconv.Itoa(len(body)),
+			"updated":     time.Now().UTC(),
+		})
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	object, ok := g.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		delete(g.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(object)
+}
+
+// writeServiceAccountKey writes a new service account key, whose tokens are issued by tokenURI, to a file.
+func writeServiceAccountKey(t *testing.T, tokenURI string) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	bytes, err := json.Marshal(map[string]string{
+		"client_email": "robot@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, bytes, 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	return privateKey
+}
+
+func TestGCSBucket(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&fakeGCS{objects: map[string][]byte{}})
+	defer server.Close()
+	privateKey := writeServiceAccountKey(t, server.URL+"/token")
+	bucket, err := NewBucket(Opts{Backend: "gcs", Bucket: "bucket", Endpoint: server.URL})
+	require.NoError(t, err)
+	content := "hello world"
+	key := "dir/hello world"
+
+	t.Run("UploadAndDownload", func(t *testing.T) {
+		attributes, err := bucket.Upload(ctx, key, "text/plain", strings.NewReader(content))
+		require.NoError(t, err)
+		require.EqualValues(t, len(content), attributes.Size)
+		require.Equal(t, "text/plain", attributes.ContentType)
+
+		reader, attributes, err := bucket.Download(ctx, key)
+		require.NoError(t, err)
+		defer reader.Close()
+		bytes, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, content, string(bytes))
+		require.EqualValues(t, len(content), attributes.Size)
+	})
+
+	t.Run("SignedURLs", func(t *testing.T) {
+		signedURL, err := bucket.SignedURL(ctx, key, http.MethodGet, time.Hour)
+		require.NoError(t, err)
+		parsed, err := url.Parse(signedURL)
+		require.NoError(t, err)
+		require.Equal(t, "/bucket/dir/hello%20world", parsed.EscapedPath())
+		query := parsed.Query()
+		require.Equal(t, "3600", query.Get("X-Goog-Expires"))
+		require.True(t, strings.HasPrefix(query.Get("X-Goog-Credential"), "robot@project.iam.gserviceaccount.com/"))
+
+		// The signature covers the canonical request without the signature itself.
+		rawQuery, rawSignature, ok := strings.Cut(parsed.RawQuery, "&X-Goog-Signature=")
+		require.True(t, ok)
+		canonicalRequest := strings.Join([]string{"GET", parsed.EscapedPath(), rawQuery, "host:" + parsed.Host + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+		scope := strings.TrimPrefix(query.Get("X-Goog-Credential"), "robot@project.iam.gserviceaccount.com/")
+		stringToSign := strings.Join([]string{"GOOG4-RSA-SHA256", query.Get("X-Goog-Date"), scope, aws.HashPayload([]byte(canonicalRequest))}, "\n")
+		signature, err := hex.DecodeString(rawSignature)
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(stringToSign))
+		require.NoError(t, rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+		_, err = bucket.SignedURL(ctx, key, http.MethodGet, 8*24*time.Hour)
+		require.Error(t, err)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, bucket.Delete(ctx, key))
+		_, _, err := bucket.Download(ctx, key)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, bucket.Delete(ctx, key))
+		require.Equal(t, "gcs://bucket/"+key, bucket.URI(key))
+	})
+}
@@ -0,0 +1,234 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	localBackend = "local"
+	// Attributes are stored alongside blobs, under this directory of the bucket.
+	localAttributesDirectory = ".attributes"
+)
+
+// LocalBucket stores blobs on the local filesystem. It is meant for development and tests.
+// Signed URLs point to `Handler`, which must be served at the configured base URL.
+type LocalBucket struct {
+	opts      Opts
+	directory string
+}
+
+// NewLocalBucket instantiates and returns a new local bucket.
+func NewLocalBucket(opts Opts) (*LocalBucket, error) {
+	directory := filepath.Join(opts.Directory, opts.Bucket)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "creating bucket directory %s", directory)
+	}
+	return &LocalBucket{opts: opts, directory: directory}, nil
+}
+
+// Upload implements the Bucket interface.
+func (b *LocalBucket) Upload(ctx context.Context, key, contentType string, reader io.Reader) (*Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	blobFilepath := b.filepath(key)
+	if err := os.MkdirAll(filepath.Dir(blobFilepath), 0o755); err != nil {
+		return nil, errors.Wrap(err, "creating blob directory")
+	}
+	// Write to a temporary file first so that readers never observe a partial blob.
+	file, err := os.CreateTemp(filepath.Dir(blobFilepath), ".upload-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating temporary file")
+	}
+	defer os.Remove(file.Name())
+	size, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "writing blob")
+	}
+	attributes := &Attributes{Key: key, ContentType: contentType, Size: size, UpdateTime: time.Now().UTC()}
+	if err := b.writeAttributes(attributes); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(file.Name(), blobFilepath); err != nil {
+		return nil, errors.Wrap(err, "renaming blob")
+	}
+	return attributes, nil
+}
+
+// Download implements the Bucket interface.
+func (b *LocalBucket) Download(ctx context.Context, key string) (io.ReadCloser, *Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(b.filepath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, errors.Wrap(ErrNotFound, key)
+		}
+		return nil, nil, errors.Wrap(err, "opening blob")
+	}
+	attributes, err := b.readAttributes(key)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, attributes, nil
+}
+
+// Delete implements the Bucket interface.
+func (b *LocalBucket) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	for _, blobFilepath := range []string{b.filepath(key), b.attributesFilepath(key)} {
+		if err := os.Remove(blobFilepath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "deleting blob")
+		}
+	}
+	return nil
+}
+
+// SignedURL implements the Bucket interface.
+func (b *LocalBucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if b.opts.BaseURL == "" || b.opts.SigningKey == "" {
+		return "", errors.New("signed urls require a base url and a signing key")
+	}
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("method", method)
+	query.Set("expires", expires)
+	query.Set("signature", b.sign(key, method, expires))
+	return fmt.Sprintf("%s/%s/%s?%s", strings.TrimSuffix(b.opts.BaseURL, "/"), b.opts.Bucket, key, query.Encode()), nil
+}
+
+// URI implements the Bucket interface.
+func (b *LocalBucket) URI(key string) string {
+	return formatURI(localBackend, b.opts.Bucket, key)
+}
+
+// Handler returns an http handler serving downloads (GET) and uploads (PUT) authorized by signed URLs.
+// It expects paths of the form `/bucket/key`, so it should be mounted with `http.StripPrefix` if needed.
+func (b *LocalBucket) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/"+b.opts.Bucket+"/")
+		if err := b.verify(key, r.Method, r.URL.Query()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			reader, attributes, err := b.Download(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				log.Errorf("downloading blob %s: %v", key, err)
+				http.Error(w, "downloading blob", http.StatusInternalServerError)
+				return
+			}
+			defer reader.Close()
+			w.Header().Set("Content-Type", attributes.ContentType)
+			w.Header().Set("Content-Length", strconv.FormatInt(attributes.Size, 10))
+			if _, err := io.Copy(w, reader); err != nil {
+				log.Warningf("streaming blob %s: %v", key, err)
+			}
+		case http.MethodPut:
+			if _, err := b.Upload(r.Context(), key, r.Header.Get("Content-Type"), r.Body); err != nil {
+				log.Errorf("uploading blob %s: %v", key, err)
+				http.Error(w, "uploading blob", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (b *LocalBucket) sign(key, method, expires string) string {
+	mac := hmac.New(sha256.New, []byte(b.opts.SigningKey))
+	mac.Write([]byte(strings.Join([]string{method, b.opts.Bucket, key, expires}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (b *LocalBucket) verify(key, method string, query url.Values) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if b.opts.SigningKey == "" {
+		return errors.New("signed urls are disabled")
+	}
+	if query.Get("method") != method {
+		return errors.New("signed url does not grant this method")
+	}
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+	if time.Now().Unix() > unix {
+		return errors.New("signed url expired")
+	}
+	expected := b.sign(key, method, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (b *LocalBucket) filepath(key string) string {
+	return filepath.Join(b.directory, filepath.FromSlash(key))
+}
+
+func (b *LocalBucket) attributesFilepath(key string) string {
+	return filepath.Join(b.directory, localAttributesDirectory, filepath.FromSlash(key)+".json")
+}
+
+func (b *LocalBucket) writeAttributes(attributes *Attributes) error {
+	attributesFilepath := b.attributesFilepath(attributes.Key)
+	if err := os.MkdirAll(filepath.Dir(attributesFilepath), 0o755); err != nil {
+		return errors.Wrap(err, "creating attributes directory")
+	}
+	bytes, err := json.Marshal(attributes)
+	if err != nil {
+		return errors.Wrap(err, "marshaling attributes")
+	}
+	if err := os.WriteFile(attributesFilepath, bytes, 0o644); err != nil {
+		return errors.Wrap(err, "writing attributes")
+	}
+	return nil
+}
+
+func (b *LocalBucket) readAttributes(key string) (*Attributes, error) {
+	bytes, err := os.ReadFile(b.attributesFilepath(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading attributes")
+	}
+	attributes := &Attributes{}
+	if err := json.Unmarshal(bytes, attributes); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling attributes")
+	}
+	return attributes, nil
+}
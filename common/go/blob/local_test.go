@@ -0,0 +1,76 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBucket(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	bucket, err := NewLocalBucket(Opts{Bucket: "test", Directory: t.TempDir(), BaseURL: server.URL, SigningKey: "key"})
+	require.NoError(t, err)
+	server.Config.Handler = bucket.Handler()
+
+	content := []byte("hello world")
+	key := ContentAddressedKey("images", content)
+	require.Equal(t, key, ContentAddressedKey("images", content))
+
+	t.Run("upload and download", func(t *testing.T) {
+		attributes, err := bucket.Upload(ctx, key, "text/plain", strings.NewReader(string(content)))
+		require.NoError(t, err)
+		require.Equal(t, int64(len(content)), attributes.Size)
+		reader, attributes, err := bucket.Download(ctx, key)
+		require.NoError(t, err)
+		defer reader.Close()
+		bytes, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, content, bytes)
+		require.Equal(t, "text/plain", attributes.ContentType)
+	})
+
+	t.Run("uri round trips", func(t *testing.T) {
+		backend, bucketName, parsedKey, err := ParseURI(bucket.URI(key))
+		require.NoError(t, err)
+		require.Equal(t, []string{"local", "test", key}, []string{backend, bucketName, parsedKey})
+	})
+
+	t.Run("signed urls", func(t *testing.T) {
+		signedURL, err := bucket.SignedURL(ctx, key, http.MethodGet, time.Minute)
+		require.NoError(t, err)
+		response, err := http.Get(signedURL)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		// The signature only grants the signed method.
+		request, err := http.NewRequest(http.MethodPut, signedURL, strings.NewReader("tampered"))
+		require.NoError(t, err)
+		response, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusForbidden, response.StatusCode)
+	})
+
+	t.Run("invalid keys are rejected", func(t *testing.T) {
+		for _, key := range []string{"", "/abs", "../escape", "a/../../escape", ".attributes/a"} {
+			_, err := bucket.Upload(ctx, key, "", strings.NewReader(""))
+			require.Error(t, err, key)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, bucket.Delete(ctx, key))
+		_, _, err := bucket.Download(ctx, key)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, bucket.Delete(ctx, key))
+	})
+}
@@ -0,0 +1,259 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/aws"
+)
+
+const (
+	s3Backend = "s3"
+	// s3MaxSignedURLExpiry is the longest expiry S3 accepts for presigned URLs.
+	s3MaxSignedURLExpiry = 7 * 24 * time.Hour
+)
+
+// s3PartSize is the size of the parts of multipart uploads. Blobs that fit in a part are uploaded in a single request.
+var s3PartSize = 8 << 20
+
+// S3Bucket stores blobs in an S3 bucket, or a bucket of an S3 compatible store if an endpoint is configured.
+// It authenticates with the credentials of the AWS environment.
+type S3Bucket struct {
+	opts        Opts
+	region      string
+	bucketURL   *url.URL
+	credentials *aws.Credentials
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// NewS3Bucket instantiates and returns a new S3 bucket.
+func NewS3Bucket(opts Opts) (*S3Bucket, error) {
+	region := opts.Region
+	if region == "" {
+		var err error
+		if region, err = aws.RegionFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+	credentials, err := aws.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	// Virtual hosted URLs on AWS, and path style URLs on S3 compatible stores which rarely support the former.
+	rawBucketURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", opts.Bucket, region)
+	if opts.Endpoint != "" {
+		rawBucketURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + opts.Bucket
+	}
+	bucketURL, err := url.Parse(rawBucketURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing bucket url %s", rawBucketURL)
+	}
+	return &S3Bucket{opts: opts, region: region, bucketURL: bucketURL, credentials: credentials, httpClient: http.DefaultClient, now: time.Now}, nil
+}
+
+// Upload implements the Bucket interface. Blobs larger than a part are streamed with a multipart upload.
+func (b *S3Bucket) Upload(ctx context.Context, key, contentType string, reader io.Reader) (*Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	part := make([]byte, s3PartSize)
+	n, err := io.ReadFull(reader, part)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		header := http.Header{"Content-Type": {contentType}}
+		response, err := b.do(ctx, http.MethodPut, key, nil, header, part[:n])
+		if err != nil {
+			return nil, errors.Wrap(err, "uploading blob")
+		}
+		response.Body.Close()
+	case err != nil:
+		return nil, errors.Wrap(err, "reading blob")
+	default:
+		if n, err = b.uploadMultipart(ctx, key, contentType, part, reader); err != nil {
+			return nil, err
+		}
+	}
+	return &Attributes{Key: key, ContentType: contentType, Size: int64(n), UpdateTime: time.Now().UTC()}, nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// uploadMultipart uploads the first part, then the rest of the reader part by part, and returns the blob's size.
+// The upload is aborted on error, so that its parts are not billed.
+func (b *S3Bucket) uploadMultipart(ctx context.Context, key, contentType string, part []byte, reader io.Reader) (int, error) {
+	response, err := b.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, http.Header{"Content-Type": {contentType}}, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating multipart upload")
+	}
+	initiated := &struct{ UploadId string }{}
+	err = xml.NewDecoder(response.Body).Decode(initiated)
+	response.Body.Close()
+	if err != nil {
+		return 0, errors.Wrap(err, "decoding multipart upload")
+	}
+	uploadID := initiated.UploadId
+
+	size, err := b.uploadParts(ctx, key, uploadID, part, reader)
+	if err != nil {
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if response, abortErr := b.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil); abortErr != nil {
+			log.Warningf("Could not abort multipart upload of %s: %v", key, abortErr)
+		} else {
+			response.Body.Close()
+		}
+		return 0, err
+	}
+	return size, nil
+}
+
+func (b *S3Bucket) uploadParts(ctx context.Context, key, uploadID string, part []byte, reader io.Reader) (int, error) {
+	var completed struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}
+	size := len(part)
+	for partNumber := 1; len(part) > 0; partNumber++ {
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+		response, err := b.do(ctx, http.MethodPut, key, query, nil, part)
+		if err != nil {
+			return 0, errors.Wrapf(err, "uploading part %d", partNumber)
+		}
+		response.Body.Close()
+		completed.Parts = append(completed.Parts, s3CompletedPart{PartNumber: partNumber, ETag: response.Header.Get("ETag")})
+
+		n, err := io.ReadFull(reader, part[:cap(part)])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, errors.Wrap(err, "reading blob")
+		}
+		part = part[:n]
+		size += n
+	}
+
+	body, err := xml.Marshal(&completed)
+	if err != nil {
+		return 0, errors.Wrap(err, "marshaling completed parts")
+	}
+	response, err := b.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return 0, errors.Wrap(err, "completing multipart upload")
+	}
+	defer response.Body.Close()
+	// Completions can fail after S3 responded 200, in which case the body is an error.
+	result := &struct{ XMLName xml.Name }{}
+	if err := xml.NewDecoder(response.Body).Decode(result); err != nil {
+		return 0, errors.Wrap(err, "decoding completed multipart upload")
+	}
+	if result.XMLName.Local == "Error" {
+		return 0, errors.New("completing multipart upload failed")
+	}
+	return size, nil
+}
+
+// Download implements the Bucket interface.
+func (b *S3Bucket) Download(ctx context.Context, key string) (io.ReadCloser, *Attributes, error) {
+	if err := validateKey(key); err != nil {
+		return nil, nil, err
+	}
+	response, err := b.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "downloading blob")
+	}
+	return response.Body, attributesFromHeader(key, response), nil
+}
+
+// Delete implements the Bucket interface.
+func (b *S3Bucket) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	response, err := b.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.Wrap(err, "deleting blob")
+	}
+	if err == nil {
+		response.Body.Close()
+	}
+	return nil
+}
+
+// SignedURL implements the Bucket interface.
+func (b *S3Bucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if expiry > s3MaxSignedURLExpiry {
+		return "", errors.Errorf("signed urls expire after at most %s, got %s", s3MaxSignedURLExpiry, expiry)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, b.objectURL(key, nil), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "creating request")
+	}
+	return aws.PresignURL(request, b.credentials, b.region, "s3", expiry, b.now()), nil
+}
+
+// URI implements the Bucket interface.
+func (b *S3Bucket) URI(key string) string {
+	return formatURI(s3Backend, b.opts.Bucket, key)
+}
+
+func (b *S3Bucket) objectURL(key string, query url.Values) string {
+	objectURL := *b.bucketURL
+	objectURL.Path += "/" + key
+	objectURL.RawPath = aws.EscapePath(objectURL.Path)
+	objectURL.RawQuery = aws.CanonicalQuery(query)
+	return objectURL.String()
+}
+
+// do sends a signed request to the object of the given key. Responses with an error status are returned as errors,
+// and the caller must close the body of others.
+func (b *S3Bucket) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, b.objectURL(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	payloadHash := aws.HashPayload(body)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	aws.SignRequest(request, payloadHash, b.credentials, b.region, "s3", b.now())
+	return doBlobRequest(b.httpClient, request, key)
+}
+
+// doBlobRequest sends a request, returning ErrNotFound for 404s and an error for other error statuses.
+func doBlobRequest(httpClient *http.Client, request *http.Request, key string) (*http.Response, error) {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode/100 == 2 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrap(ErrNotFound, key)
+	}
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return nil, errors.Errorf("%s %s returned %d: %s", request.Method, key, response.StatusCode, responseBody)
+}
+
+// attributesFromHeader returns the attributes of a downloaded blob.
+func attributesFromHeader(key string, response *http.Response) *Attributes {
+	updateTime, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return &Attributes{Key: key, ContentType: response.Header.Get("Content-Type"), Size: response.ContentLength, UpdateTime: updateTime}
+}
@@ -0,0 +1,239 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const configFileLongName = "config-file"
+
+// Upon detecting a config file change, how long to wait for other changes before reloading.
+// This avoids parsing a partially written file.
+var waitForOtherConfigFileChanges = 200 * time.Millisecond
+
+// ConfigFileOpts can be embedded into a service's opts to load flag values from a YAML or JSON config file.
+// The file maps long flag names to values. Precedence is: defaults < config file < env < command line.
+type ConfigFileOpts struct {
+	ConfigFile string `long:"config-file" env:"CONFIG_FILE" description:"Path to a YAML or JSON file mapping long flag names to values"`
+}
+
+// Parses args into opts, layering the config file's values, if any, under env and command line values.
+func parseArgsWithConfigFile(opts any, args []string) error {
+	parser := flags.NewParser(opts, flags.Default)
+	if _, err := parser.ParseArgs(args); err != nil {
+		return err
+	}
+	configFileOption := parser.FindOptionByLongName(configFileLongName)
+	if configFileOption == nil {
+		return nil
+	}
+	configFile, ok := configFileOption.Value().(string)
+	if !ok || configFile == "" {
+		return nil
+	}
+	configArgs, err := getConfigFileArgs(parser, configFile)
+	if err != nil {
+		return errors.Wrapf(err, "loading config file @%s", configFile)
+	}
+	if len(configArgs) == 0 {
+		return nil
+	}
+	// Parse again with a fresh parser so that every option is reset to its default before layering.
+	parser = flags.NewParser(opts, flags.Default)
+	if _, err := parser.ParseArgs(append(configArgs, args...)); err != nil {
+		return errors.Wrapf(err, "applying config file @%s", configFile)
+	}
+	return nil
+}
+
+// Returns the command line args equivalent to the config file's values, skipping any option set through env or command line.
+// Note that parser must already have parsed the original args.
+func getConfigFileArgs(parser *flags.Parser, configFile string) ([]string, error) {
+	bytes, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading")
+	}
+	// JSON is a subset of YAML, so a single decoder handles both formats.
+	values := map[string]any{}
+	if err := yaml.Unmarshal(bytes, &values); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling")
+	}
+
+	longNames := make([]string, 0, len(values))
+	for longName := range values {
+		longNames = append(longNames, longName)
+	}
+	sort.Strings(longNames)
+
+	var args []string
+	for _, longName := range longNames {
+		option := parser.FindOptionByLongName(longName)
+		if option == nil {
+			return nil, errors.Errorf("unknown flag %q", longName)
+		}
+		if option.IsSet() && !option.IsSetDefault() {
+			continue // Set through the command line.
+		}
+		if envKey := option.EnvKeyWithNamespace(); envKey != "" {
+			if _, ok := os.LookupEnv(envKey); ok {
+				continue // Set through env.
+			}
+		}
+		optionArgs, err := getOptionArgs(option, values[longName])
+		if err != nil {
+			return nil, errors.Wrap(err, longName)
+		}
+		args = append(args, optionArgs...)
+	}
+	return args, nil
+}
+
+// Converts a config file value into command line args for the given option.
+func getOptionArgs(option *flags.Option, value any) ([]string, error) {
+	flag := "--" + option.LongNameWithNamespace()
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if option.Field().Type.Kind() != reflect.Bool {
+			return []string{fmt.Sprintf("%s=%t", flag, v)}, nil
+		}
+		// Boolean flags do not take an argument, so they can only be switched on.
+		if !v {
+			return nil, nil
+		}
+		return []string{flag}, nil
+	case []any:
+		args := make([]string, 0, len(v))
+		for _, element := range v {
+			if _, ok := element.([]any); ok {
+				return nil, errors.New("nested lists are not supported")
+			}
+			args = append(args, fmt.Sprintf("%s=%v", flag, element))
+		}
+		return args, nil
+	case map[any]any:
+		args := make([]string, 0, len(v))
+		for key, element := range v {
+			args = append(args, fmt.Sprintf("%s=%v:%v", flag, key, element))
+		}
+		sort.Strings(args)
+		return args, nil
+	default:
+		return []string{fmt.Sprintf("%s=%v", flag, v)}, nil
+	}
+}
+
+// ConfigWatcher re-parses opts whenever their config file changes, notifying registered components.
+// This lets common settings (logging verbosity, rate limits, etc) change without a redeploy.
+type ConfigWatcher[T any] struct {
+	args            []string
+	configFile      string
+	fsnotifyWatcher *fsnotify.Watcher
+
+	callbacksMutex sync.Mutex
+	callbacks      []func(*T)
+}
+
+// NewConfigWatcher instantiates and returns a new config watcher. Opts are re-parsed from the given args on every change.
+func NewConfigWatcher[T any](configFile string, args []string) (*ConfigWatcher[T], error) {
+	fsnotifyWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "instantiating fsnotify watcher")
+	}
+	// Watch the directory rather than the file: kubernetes config maps are updated by swapping symlinks.
+	if err := fsnotifyWatcher.Add(filepath.Dir(configFile)); err != nil {
+		fsnotifyWatcher.Close()
+		return nil, errors.Wrapf(err, "watching config file @%s", configFile)
+	}
+	return &ConfigWatcher[T]{
+		args:            args,
+		configFile:      configFile,
+		fsnotifyWatcher: fsnotifyWatcher,
+	}, nil
+}
+
+// MustNewConfigWatcher calls NewConfigWatcher and panics on error.
+func MustNewConfigWatcher[T any](configFile string, args []string) *ConfigWatcher[T] {
+	watcher, err := NewConfigWatcher[T](configFile, args)
+	if err != nil {
+		log.Panic(err)
+	}
+	return watcher
+}
+
+// OnChange registers a callback which is invoked with the re-parsed opts every time the config file changes.
+func (w *ConfigWatcher[T]) OnChange(fn func(opts *T)) *ConfigWatcher[T] {
+	w.callbacksMutex.Lock()
+	defer w.callbacksMutex.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+	return w
+}
+
+// Start watches the config file in the background until the context is cancelled or the watcher is closed.
+func (w *ConfigWatcher[T]) Start(ctx context.Context) {
+	go w.watch(ctx)
+}
+
+// Close stops the watcher.
+func (w *ConfigWatcher[T]) Close() {
+	w.fsnotifyWatcher.Close()
+}
+
+func (w *ConfigWatcher[T]) watch(ctx context.Context) {
+	var reloadTimer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fsnotifyWatcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !w.isConfigFileEvent(event) {
+				continue
+			}
+			reloadTimer = time.After(waitForOtherConfigFileChanges)
+		case err, ok := <-w.fsnotifyWatcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("watching config file @%s: %v", w.configFile, err)
+		case <-reloadTimer:
+			reloadTimer = nil
+			w.reload()
+		}
+	}
+}
+
+// Config map updates surface as changes to the `..data` symlink.
+func (w *ConfigWatcher[T]) isConfigFileEvent(event fsnotify.Event) bool {
+	return filepath.Clean(event.Name) == filepath.Clean(w.configFile) || filepath.Base(event.Name) == "..data"
+}
+
+func (w *ConfigWatcher[T]) reload() {
+	opts := new(T)
+	if err := ParseArgs(opts, w.args); err != nil {
+		// Keep the previous config in place until the file is fixed.
+		log.Errorf("reloading config file @%s: %v", w.configFile, err)
+		return
+	}
+	log.Infof("reloaded config file @%s", w.configFile)
+	w.callbacksMutex.Lock()
+	callbacks := append([]func(*T){}, w.callbacks...)
+	w.callbacksMutex.Unlock()
+	for _, callback := range callbacks {
+		callback(opts)
+	}
+}
@@ -0,0 +1,46 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfigOpts struct {
+	ConfigFileOpts
+	Host    string   `long:"host" env:"TEST_CONFIG_HOST" default:"localhost"`
+	Port    int      `long:"port" env:"TEST_CONFIG_PORT" default:"8080"`
+	Verbose bool     `long:"verbose"`
+	Tags    []string `long:"tag"`
+}
+
+func TestParseArgsWithConfigFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := "host: config-host\nport: 9090\nverbose: true\ntag: [a, b]\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o644))
+
+	t.Run("config file overrides defaults", func(t *testing.T) {
+		opts := &testConfigOpts{}
+		require.NoError(t, ParseArgs(opts, []string{"--config-file", configFile}))
+		require.Equal(t, "config-host", opts.Host)
+		require.Equal(t, 9090, opts.Port)
+		require.True(t, opts.Verbose)
+		require.Equal(t, []string{"a", "b"}, opts.Tags)
+	})
+
+	t.Run("env and args override config file", func(t *testing.T) {
+		t.Setenv("TEST_CONFIG_PORT", "7070")
+		opts := &testConfigOpts{}
+		require.NoError(t, ParseArgs(opts, []string{"--config-file", configFile, "--host", "cli-host", "--tag", "c"}))
+		require.Equal(t, "cli-host", opts.Host)
+		require.Equal(t, 7070, opts.Port)
+		require.Equal(t, []string{"c"}, opts.Tags)
+	})
+
+	t.Run("unknown flags are rejected", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configFile, []byte("unknown: value\n"), 0o644))
+		require.Error(t, ParseArgs(&testConfigOpts{}, []string{"--config-file", configFile}))
+	})
+}
@@ -1,14 +1,15 @@
 package flags
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"reflect"
 
-	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
 
 	"common/go/logging"
+	"common/go/secrets"
 )
 
 var log = logging.NewLogger()
@@ -25,14 +26,48 @@ func MustParseArgs(opts any, args []string) {
 	}
 }
 
-// ParseArgs parses the given args into opts.
+// ParseArgs parses the given args into opts. See `ConfigFileOpts` to layer a config file under env and args.
 func ParseArgs(opts any, args []string) error {
 	if err := parseSecrets(opts); err != nil {
 		return errors.Wrap(err, "parsing secrets")
 	}
-	if _, err := flags.ParseArgs(opts, args); err != nil {
+	if err := parseArgsWithConfigFile(opts, args); err != nil {
 		return errors.Wrap(err, "parsing flags")
 	}
+	if err := resolveSecretRefs(reflect.ValueOf(opts)); err != nil {
+		return errors.Wrap(err, "resolving secret references")
+	}
+	return nil
+}
+
+// Resolves secret references, e.g. "env://API_KEY", held by string fields which use the `secret-ref` tag.
+func resolveSecretRefs(value reflect.Value) error {
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("secret-ref"); !ok || fieldValue.Kind() != reflect.String {
+			if err := resolveSecretRefs(fieldValue); err != nil {
+				return errors.Wrap(err, field.Name)
+			}
+			continue
+		}
+		// Raw values are left as is.
+		if !secrets.IsRef(fieldValue.String()) {
+			continue
+		}
+		secret, err := secrets.Resolve(context.Background(), fieldValue.String())
+		if err != nil {
+			return errors.Wrap(err, field.Name)
+		}
+		fieldValue.SetString(secret)
+	}
 	return nil
 }
 
@@ -0,0 +1,227 @@
+// Package gcp authenticates requests to Google Cloud APIs with OAuth2 access tokens, so that they can be called over
+// plain HTTP.
+//
+// It only covers what our Google Cloud clients need, and is not a replacement for golang.org/x/oauth2/google: tokens
+// are issued for service account keys, or by the metadata server, which also serves GKE workload identity. User
+// credentials, workload identity federation and service account impersonation are not supported.
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CloudPlatformScope grants access to every Google Cloud API the principal is allowed to call.
+	CloudPlatformScope  = "https://www.googleapis.com/auth/cloud-platform"
+	defaultMetadataHost = "metadata.google.internal"
+	jwtBearerGrantType  = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// Tokens are refreshed this long before they expire, so that in-flight requests don't race their expiry.
+	tokenExpiryMargin = time.Minute
+)
+
+// ServiceAccountKey is the JSON key of a service account.
+type ServiceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	privateKey *rsa.PrivateKey
+}
+
+// LoadServiceAccountKey loads a service account's JSON key from the given file.
+func LoadServiceAccountKey(path string) (*ServiceAccountKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading service account key")
+	}
+	key := &ServiceAccountKey{}
+	if err := json.Unmarshal(bytes, key); err != nil {
+		return nil, errors.Wrapf(err, "unmarshaling service account key @%s", path)
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, errors.Errorf("service account key @%s has no PEM private key", path)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing private key of service account key @%s", path)
+	}
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("private key of service account key @%s is not an RSA key", path)
+	}
+	key.privateKey = rsaPrivateKey
+	return key, nil
+}
+
+// Sign signs the data with the service account's private key, using RSA SHA256.
+func (k *ServiceAccountKey) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, k.privateKey, crypto.SHA256, digest[:])
+}
+
+// TokenSource returns OAuth2 access tokens, and caches them until shortly before they expire.
+type TokenSource struct {
+	httpClient *http.Client
+	fetch      func(ctx context.Context) (*tokenResponse, error)
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// NewServiceAccountTokenSource instantiates and returns a TokenSource exchanging signed assertions of the service
+// account for tokens granting the given scopes.
+func NewServiceAccountTokenSource(key *ServiceAccountKey, scopes ...string) *TokenSource {
+	t := &TokenSource{httpClient: http.DefaultClient}
+	t.fetch = func(ctx context.Context) (*tokenResponse, error) { return t.fetchServiceAccountToken(ctx, key, scopes) }
+	return t
+}
+
+// NewMetadataTokenSource instantiates and returns a TokenSource fetching tokens of the instance's service account from
+// the metadata server, whose host can be overridden with the GCE_METADATA_HOST environment variable.
+func NewMetadataTokenSource() *TokenSource {
+	t := &TokenSource{httpClient: http.DefaultClient}
+	t.fetch = t.fetchMetadataToken
+	return t
+}
+
+// NewDefaultTokenSource instantiates and returns a TokenSource using the service account key the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable points to, or the metadata server if it is not set.
+// The key is loaded when the first token is fetched.
+func NewDefaultTokenSource(scopes ...string) *TokenSource {
+	t := &TokenSource{httpClient: http.DefaultClient}
+	t.fetch = func(ctx context.Context) (*tokenResponse, error) {
+		path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+		if path == "" {
+			return t.fetchMetadataToken(ctx)
+		}
+		key, err := LoadServiceAccountKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return t.fetchServiceAccountToken(ctx, key, scopes)
+	}
+	return t
+}
+
+// WithHTTPClient sets the http client used to fetch tokens.
+func (t *TokenSource) WithHTTPClient(httpClient *http.Client) *TokenSource {
+	t.httpClient = httpClient
+	return t
+}
+
+// Token returns an access token, fetching a new one if the cached one is about to expire.
+func (t *TokenSource) Token(ctx context.Context) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.token != "" && time.Now().Before(t.expiry) {
+		return t.token, nil
+	}
+	response, err := t.fetch(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching access token")
+	}
+	if response.AccessToken == "" {
+		return "", errors.New("fetching access token: empty token")
+	}
+	t.token = response.AccessToken
+	t.expiry = time.Now().Add(time.Duration(response.ExpiresIn)*time.Second - tokenExpiryMargin)
+	return t.token, nil
+}
+
+// Authorize sets the Authorization header of the request to a bearer access token.
+func (t *TokenSource) Authorize(ctx context.Context, request *http.Request) error {
+	token, err := t.Token(ctx)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (t *TokenSource) fetchMetadataToken(ctx context.Context) (*tokenResponse, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = defaultMetadataHost
+	}
+	url := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	return t.do(request)
+}
+
+func (t *TokenSource) fetchServiceAccountToken(ctx context.Context, key *ServiceAccountKey, scopes []string) (*tokenResponse, error) {
+	assertion, err := signAssertion(key, scopes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{"grant_type": {jwtBearerGrantType}, "assertion": {assertion}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return t.do(request)
+}
+
+// signAssertion returns a JWT asserting the service account's identity, to exchange for a token granting the scopes.
+func signAssertion(key *ServiceAccountKey, scopes []string, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iss":   key.ClientEmail,
+		"scope": strings.Join(scopes, " "),
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling claims")
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature, err := key.Sign([]byte(unsigned))
+	if err != nil {
+		return "", errors.Wrap(err, "signing assertion")
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (t *TokenSource) do(request *http.Request) (*tokenResponse, error) {
+	response, err := t.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "requesting %s", request.URL)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("requesting %s: status %d", request.URL, response.StatusCode)
+	}
+	token := &tokenResponse{}
+	if err := json.NewDecoder(response.Body).Decode(token); err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", request.URL)
+	}
+	return token, nil
+}
@@ -0,0 +1,268 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nsf/jsondiff"
+	"github.com/pkg/errors"
+)
+
+// Fields grafana fills in the models of alert queries. They are ignored when diffing models that do not set them.
+var alertQueryModelDefaults = []string{"datasource", "intervalMs", "maxDataPoints", "refId"}
+
+// AlertQuery is a query or expression evaluated by an alert rule.
+type AlertQuery struct {
+	RefID             string            `json:"refId"`
+	QueryType         string            `json:"queryType,omitempty"`
+	RelativeTimeRange RelativeTimeRange `json:"relativeTimeRange"`
+	DatasourceUID     string            `json:"datasourceUid"`
+	Model             json.RawMessage   `json:"model"`
+}
+
+// RelativeTimeRange is the time range of an alert query, in seconds relative to the evaluation time.
+type RelativeTimeRange struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// ProvisionedAlertRule is a grafana managed alert rule, as exposed by the provisioning API.
+type ProvisionedAlertRule struct {
+	UID          string            `json:"uid"`
+	FolderUID    string            `json:"folderUID"`
+	RuleGroup    string            `json:"ruleGroup"`
+	Title        string            `json:"title"`
+	Condition    string            `json:"condition"`
+	Data         []AlertQuery      `json:"data"`
+	NoDataState  string            `json:"noDataState"`
+	ExecErrState string            `json:"execErrState"`
+	For          string            `json:"for"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	IsPaused     bool              `json:"isPaused"`
+}
+
+// RuleGroup is a set of alert rules evaluated sequentially, in order, at the same interval.
+type RuleGroup struct {
+	// Title of the folder the group lives in.
+	Folder string `json:"folder"`
+	// Title of the group, unique within a folder.
+	Title string `json:"title"`
+	// Evaluation interval, in seconds.
+	IntervalSeconds int64 `json:"interval"`
+	// Rules of the group, in evaluation order. Every rule must have a uid so that it can be reconciled.
+	Rules []*ProvisionedAlertRule `json:"rules"`
+}
+
+// Name returns a "{folder}/{title}" string for clear/consistent logging.
+func (g *RuleGroup) Name() string {
+	return g.Folder + "/" + g.Title
+}
+
+// ruleGroupPayload is the provisioning API representation of a rule group.
+type ruleGroupPayload struct {
+	Title     string                  `json:"title"`
+	FolderUID string                  `json:"folderUid"`
+	Interval  int64                   `json:"interval"`
+	Rules     []*ProvisionedAlertRule `json:"rules"`
+}
+
+// RuleGroupPlan describes the changes required to make a grafana rule group exactly match a desired group.
+type RuleGroupPlan struct {
+	Group *RuleGroup
+	// Uid of the group's folder. Empty if the folder does not exist yet.
+	FolderUID string
+	// Rules to create, update (uid to diff) and delete.
+	Create []*ProvisionedAlertRule
+	Update map[string]string
+	Delete []*ProvisionedAlertRule
+	// Set to true if the interval or the evaluation order of the rules changed.
+	GroupChanged bool
+}
+
+// HasChanges returns true if applying the plan would modify grafana.
+func (p *RuleGroupPlan) HasChanges() bool {
+	return len(p.Create) > 0 || len(p.Update) > 0 || len(p.Delete) > 0 || p.GroupChanged
+}
+
+// PlanRuleGroup fetches a rule group from grafana and computes the changes needed to make it match the given group.
+func (c *Client) PlanRuleGroup(ctx context.Context, group *RuleGroup) (*RuleGroupPlan, error) {
+	plan := &RuleGroupPlan{Group: group, Update: map[string]string{}}
+	uids := map[string]struct{}{}
+	for _, rule := range group.Rules {
+		if rule.UID == "" {
+			return nil, errors.Errorf("rule %s of group %s has no uid", rule.Title, group.Name())
+		}
+		if _, ok := uids[rule.UID]; ok {
+			return nil, errors.Errorf("rule uid %s is duplicated in group %s", rule.UID, group.Name())
+		}
+		uids[rule.UID] = struct{}{}
+	}
+
+	folder, ok, err := c.getFolder(ctx, group.Folder)
+	if err != nil {
+		return nil, err
+	}
+	existing := &ruleGroupPayload{}
+	if ok {
+		plan.FolderUID = folder.UID
+		if err := c.do(ctx, http.MethodGet, ruleGroupPath(folder.UID, group.Title), nil, existing); err != nil && !isNotFound(err) {
+			return nil, errors.Wrapf(err, "getting rule group %s", group.Name())
+		}
+	}
+	setRuleGroupFields(group, plan.FolderUID)
+
+	uidToExistingRule := map[string]*ProvisionedAlertRule{}
+	for _, rule := range existing.Rules {
+		uidToExistingRule[rule.UID] = rule
+		if _, ok := uids[rule.UID]; !ok {
+			plan.Delete = append(plan.Delete, rule)
+		}
+	}
+	for i, rule := range group.Rules {
+		existingRule, ok := uidToExistingRule[rule.UID]
+		if !ok {
+			plan.Create = append(plan.Create, rule)
+			continue
+		}
+		diff, err := diffRules(existingRule, rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "diffing rule %s", rule.Title)
+		}
+		if diff != "" {
+			plan.Update[rule.UID] = diff
+		}
+		if i >= len(existing.Rules) || existing.Rules[i].UID != rule.UID {
+			plan.GroupChanged = true
+		}
+	}
+	if len(existing.Rules) > 0 && existing.Interval != group.IntervalSeconds {
+		plan.GroupChanged = true
+	}
+	if len(plan.Create) > 0 || len(plan.Delete) > 0 {
+		plan.GroupChanged = true
+	}
+	return plan, nil
+}
+
+// ApplyRuleGroup creates, updates and deletes rules so that the grafana rule group exactly matches the plan's group,
+// then sets the group's interval and rule order.
+func (c *Client) ApplyRuleGroup(ctx context.Context, plan *RuleGroupPlan) error {
+	group := plan.Group
+	if !plan.HasChanges() {
+		log.Infof("rule group [%s] is up to date", group.Name())
+		return nil
+	}
+	if plan.FolderUID == "" {
+		folder, err := c.getOrCreateFolder(ctx, group.Folder)
+		if err != nil {
+			return err
+		}
+		plan.FolderUID = folder.UID
+		setRuleGroupFields(group, plan.FolderUID)
+	}
+
+	for _, rule := range plan.Delete {
+		if err := c.do(ctx, http.MethodDelete, "/api/v1/provisioning/alert-rules/"+url.PathEscape(rule.UID), nil, nil); err != nil {
+			return errors.Wrapf(err, "deleting rule %s", rule.Title)
+		}
+		log.Infof("deleted rule [%s] from group [%s]", rule.Title, group.Name())
+	}
+	for _, rule := range group.Rules {
+		if _, ok := plan.Update[rule.UID]; ok {
+			if err := c.do(ctx, http.MethodPut, "/api/v1/provisioning/alert-rules/"+url.PathEscape(rule.UID), rule, nil); err != nil {
+				return errors.Wrapf(err, "updating rule %s", rule.Title)
+			}
+			log.Infof("updated rule [%s] of group [%s]", rule.Title, group.Name())
+		}
+	}
+	// Rules are created in order, so that they are appended to the group in evaluation order.
+	for _, rule := range plan.Create {
+		if err := c.do(ctx, http.MethodPost, "/api/v1/provisioning/alert-rules", rule, nil); err != nil {
+			return errors.Wrapf(err, "creating rule %s", rule.Title)
+		}
+		log.Infof("created rule [%s] in group [%s]", rule.Title, group.Name())
+	}
+
+	// Finally set the interval and the order of the rules.
+	payload := &ruleGroupPayload{Title: group.Title, FolderUID: plan.FolderUID, Interval: group.IntervalSeconds, Rules: group.Rules}
+	if err := c.do(ctx, http.MethodPut, ruleGroupPath(plan.FolderUID, group.Title), payload, nil); err != nil {
+		return errors.Wrapf(err, "updating rule group %s", group.Name())
+	}
+	log.Infof("reconciled rule group [%s]", group.Name())
+	return nil
+}
+
+// diffRules returns a human readable diff between an existing rule and a desired one. Query models are normalized
+// first, so that the formatting of desired models and the fields grafana fills in do not register as changes.
+func diffRules(existing, desired *ProvisionedAlertRule) (string, error) {
+	existingCopy, desiredCopy := *existing, *desired
+	existingCopy.Data = append([]AlertQuery(nil), existing.Data...)
+	desiredCopy.Data = append([]AlertQuery(nil), desired.Data...)
+	for i := range desiredCopy.Data {
+		desiredModel, err := normalizeModel(desiredCopy.Data[i].Model, nil)
+		if err != nil {
+			return "", errors.Wrap(err, "normalizing desired query model")
+		}
+		desiredCopy.Data[i].Model = desiredModel
+		if i >= len(existingCopy.Data) {
+			continue
+		}
+		if existingCopy.Data[i].Model, err = normalizeModel(existingCopy.Data[i].Model, desired.Data[i].Model); err != nil {
+			return "", errors.Wrap(err, "normalizing existing query model")
+		}
+	}
+
+	existingBytes, err := json.Marshal(&existingCopy)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling existing rule")
+	}
+	desiredBytes, err := json.Marshal(&desiredCopy)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling desired rule")
+	}
+	options := jsondiff.DefaultConsoleOptions()
+	if result, diff := jsondiff.Compare(existingBytes, desiredBytes, &options); result != jsondiff.FullMatch {
+		return diff, nil
+	}
+	return "", nil
+}
+
+// normalizeModel re-encodes a query model with sorted keys and no insignificant whitespace. If a reference model is
+// given, the fields grafana fills in are dropped unless the reference sets them.
+func normalizeModel(model, reference json.RawMessage) (json.RawMessage, error) {
+	if len(model) == 0 {
+		return model, nil
+	}
+	var value any
+	if err := json.Unmarshal(model, &value); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling model")
+	}
+	if fields, ok := value.(map[string]any); ok && reference != nil {
+		referenceFields := map[string]any{}
+		if err := json.Unmarshal(reference, &referenceFields); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling reference model")
+		}
+		for _, key := range alertQueryModelDefaults {
+			if _, ok := referenceFields[key]; !ok {
+				delete(fields, key)
+			}
+		}
+	}
+	return json.Marshal(value)
+}
+
+// setRuleGroupFields sets the folder and group of every rule of the group.
+func setRuleGroupFields(group *RuleGroup, folderUID string) {
+	for _, rule := range group.Rules {
+		rule.FolderUID = folderUID
+		rule.RuleGroup = group.Title
+	}
+}
+
+func ruleGroupPath(folderUID, group string) string {
+	return fmt.Sprintf("/api/v1/provisioning/folder/%s/rule-groups/%s", url.PathEscape(folderUID), url.PathEscape(group))
+}
@@ -0,0 +1,152 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRule(uid, title, model string) *ProvisionedAlertRule {
+	return &ProvisionedAlertRule{
+		UID:       uid,
+		Title:     title,
+		Condition: "A",
+		Data:      []AlertQuery{{RefID: "A", DatasourceUID: "prometheus", Model: json.RawMessage(model)}},
+		For:       "5m",
+	}
+}
+
+func TestDiffRules(t *testing.T) {
+	desired := newTestRule("uid", "Errors", `{"expr": "rate(errors[5m]) > 1", "instant": true}`)
+
+	t.Run("IgnoresModelFormatting", func(t *testing.T) {
+		existing := newTestRule("uid", "Errors", `{"instant":true,"expr":"rate(errors[5m]) > 1"}`)
+		diff, err := diffRules(existing, desired)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+	})
+
+	t.Run("IgnoresFieldsGrafanaFillsIn", func(t *testing.T) {
+		existing := newTestRule("uid", "Errors", `{"expr":"rate(errors[5m]) > 1","instant":true,"intervalMs":1000,"maxDataPoints":43200,"refId":"A"}`)
+		diff, err := diffRules(existing, desired)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+	})
+
+	t.Run("ComparesFieldsTheDesiredModelSets", func(t *testing.T) {
+		desired := newTestRule("uid", "Errors", `{"expr":"rate(errors[5m]) > 1","instant":true,"intervalMs":5000}`)
+		existing := newTestRule("uid", "Errors", `{"expr":"rate(errors[5m]) > 1","instant":true,"intervalMs":1000}`)
+		diff, err := diffRules(existing, desired)
+		require.NoError(t, err)
+		require.Contains(t, diff, "5000")
+	})
+
+	t.Run("ReportsModelChanges", func(t *testing.T) {
+		existing := newTestRule("uid", "Errors", `{"expr":"rate(errors[5m]) > 2","instant":true}`)
+		diff, err := diffRules(existing, desired)
+		require.NoError(t, err)
+		require.Contains(t, diff, "> 2")
+	})
+
+	t.Run("DoesNotMutateRules", func(t *testing.T) {
+		existing := newTestRule("uid", "Errors", `{"expr":"rate(errors[5m]) > 1","instant":true,"intervalMs":1000}`)
+		_, err := diffRules(existing, desired)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"expr":"rate(errors[5m]) > 1","instant":true,"intervalMs":1000}`, string(existing.Data[0].Model))
+		require.Equal(t, `{"expr": "rate(errors[5m]) > 1", "instant": true}`, string(desired.Data[0].Model))
+	})
+}
+
+func newTestRuleGroup(rules ...*ProvisionedAlertRule) *RuleGroup {
+	return &RuleGroup{Folder: "alerts", Title: "library", IntervalSeconds: 60, Rules: rules}
+}
+
+func TestPlanRuleGroup(t *testing.T) {
+	ctx := context.Background()
+	errors := func() *ProvisionedAlertRule { return newTestRule("errors", "Errors", `{"expr": "errors > 1"}`) }
+	latency := func() *ProvisionedAlertRule { return newTestRule("latency", "Latency", `{"expr": "latency > 1"}`) }
+
+	t.Run("RejectsInvalidGroups", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		_, err := client.PlanRuleGroup(ctx, newTestRuleGroup(newTestRule("", "Errors", `{}`)))
+		require.ErrorContains(t, err, "has no uid")
+		_, err = client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), errors()))
+		require.ErrorContains(t, err, "duplicated")
+	})
+
+	t.Run("CreatesGroups", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), latency()))
+		require.NoError(t, err)
+		require.Len(t, plan.Create, 2)
+		require.True(t, plan.GroupChanged)
+		require.Empty(t, grafana.mutations)
+
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+		ruleGroup := grafana.ruleGroups[grafana.folders[0].UID+"/library"]
+		require.Equal(t, int64(60), ruleGroup.Interval)
+		require.Equal(t, "errors", ruleGroup.Rules[0].UID)
+		require.Equal(t, "latency", ruleGroup.Rules[1].UID)
+	})
+
+	t.Run("UpToDateGroupsHaveNoChanges", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), latency()))
+		require.NoError(t, err)
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+		grafana.resetMutations()
+
+		// Grafana filled in the query models, which must not register as changes.
+		plan, err = client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), latency()))
+		require.NoError(t, err)
+		require.False(t, plan.HasChanges(), plan.Update)
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("ReconcilesRules", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), latency()))
+		require.NoError(t, err)
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+
+		updatedErrors := newTestRule("errors", "Errors", `{"expr": "errors > 5"}`)
+		saturation := newTestRule("saturation", "Saturation", `{"expr": "saturation > 1"}`)
+		plan, err = client.PlanRuleGroup(ctx, newTestRuleGroup(saturation, updatedErrors))
+		require.NoError(t, err)
+		require.Equal(t, []*ProvisionedAlertRule{saturation}, plan.Create)
+		require.Contains(t, plan.Update, "errors")
+		require.Len(t, plan.Delete, 1)
+		require.Equal(t, "latency", plan.Delete[0].UID)
+		require.True(t, plan.GroupChanged)
+
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+		ruleGroup := grafana.ruleGroups[grafana.folders[0].UID+"/library"]
+		require.Len(t, ruleGroup.Rules, 2)
+		require.Equal(t, "saturation", ruleGroup.Rules[0].UID)
+		require.Equal(t, "errors", ruleGroup.Rules[1].UID)
+		require.JSONEq(t, `{"expr":"errors > 5","intervalMs":1000,"maxDataPoints":43200,"refId":"A"}`, string(ruleGroup.Rules[1].Data[0].Model))
+	})
+
+	t.Run("DetectsReorderingAndIntervalChanges", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		plan, err := client.PlanRuleGroup(ctx, newTestRuleGroup(errors(), latency()))
+		require.NoError(t, err)
+		require.NoError(t, client.ApplyRuleGroup(ctx, plan))
+
+		plan, err = client.PlanRuleGroup(ctx, newTestRuleGroup(latency(), errors()))
+		require.NoError(t, err)
+		require.Empty(t, plan.Create)
+		require.Empty(t, plan.Update)
+		require.Empty(t, plan.Delete)
+		require.True(t, plan.GroupChanged)
+
+		group := newTestRuleGroup(errors(), latency())
+		group.IntervalSeconds = 120
+		plan, err = client.PlanRuleGroup(ctx, group)
+		require.NoError(t, err)
+		require.True(t, plan.GroupChanged)
+	})
+}
@@ -0,0 +1,74 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Annotation is a grafana annotation. Annotations without a dashboard uid are organization wide.
+type Annotation struct {
+	ID           int64  `json:"id,omitempty"`
+	DashboardUID string `json:"dashboardUID,omitempty"`
+	PanelID      int64  `json:"panelId,omitempty"`
+	// Epoch milliseconds. Setting TimeEnd turns the annotation into a region.
+	Time    int64    `json:"time,omitempty"`
+	TimeEnd int64    `json:"timeEnd,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Text    string   `json:"text"`
+}
+
+// ListAnnotationsRequest filters the annotations returned by ListAnnotations. Zero values are ignored.
+type ListAnnotationsRequest struct {
+	DashboardUID string
+	// Only annotations with all of these tags are returned.
+	Tags  []string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// PostAnnotation creates an annotation and returns its id. The annotation's time defaults to now.
+func (c *Client) PostAnnotation(ctx context.Context, annotation *Annotation) (int64, error) {
+	if annotation.Time == 0 {
+		annotation.Time = time.Now().UnixMilli()
+	}
+	response := &struct {
+		ID int64 `json:"id"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/api/annotations", annotation, response); err != nil {
+		return 0, errors.Wrap(err, "posting annotation")
+	}
+	annotation.ID = response.ID
+	return response.ID, nil
+}
+
+// ListAnnotations returns the annotations matching the given request.
+func (c *Client) ListAnnotations(ctx context.Context, request *ListAnnotationsRequest) ([]*Annotation, error) {
+	values := url.Values{}
+	values.Set("type", "annotation")
+	if request.DashboardUID != "" {
+		values.Set("dashboardUID", request.DashboardUID)
+	}
+	for _, tag := range request.Tags {
+		values.Add("tags", tag)
+	}
+	if !request.From.IsZero() {
+		values.Set("from", strconv.FormatInt(request.From.UnixMilli(), 10))
+	}
+	if !request.To.IsZero() {
+		values.Set("to", strconv.FormatInt(request.To.UnixMilli(), 10))
+	}
+	if request.Limit > 0 {
+		values.Set("limit", strconv.Itoa(request.Limit))
+	}
+	var annotations []*Annotation
+	if err := c.do(ctx, http.MethodGet, "/api/annotations?"+values.Encode(), nil, &annotations); err != nil {
+		return nil, errors.Wrap(err, "listing annotations")
+	}
+	return annotations, nil
+}
@@ -0,0 +1,79 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostAnnotation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DefaultsTimeToNow", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		annotation := &Annotation{Text: "deployed", Tags: []string{"deploy"}}
+		before := time.Now().UnixMilli()
+		id, err := client.PostAnnotation(ctx, annotation)
+		require.NoError(t, err)
+		require.NotZero(t, id)
+		require.Equal(t, id, annotation.ID)
+		require.GreaterOrEqual(t, annotation.Time, before)
+		require.LessOrEqual(t, annotation.Time, time.Now().UnixMilli())
+		require.Equal(t, []string{"POST /api/annotations"}, grafana.mutations)
+	})
+
+	t.Run("KeepsExplicitTimes", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		_, err := client.PostAnnotation(ctx, &Annotation{Text: "incident", Time: 1000, TimeEnd: 2000})
+		require.NoError(t, err)
+		require.Equal(t, int64(1000), grafana.annotations[0].Time)
+		require.Equal(t, int64(2000), grafana.annotations[0].TimeEnd)
+	})
+
+	t.Run("SurfacesHTTPErrors", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		_, err := client.PostAnnotation(ctx, &Annotation{})
+		httpError := &HTTPError{}
+		require.True(t, errors.As(err, &httpError))
+		require.Equal(t, http.StatusBadRequest, httpError.StatusCode)
+		require.Contains(t, httpError.Body, "text field should not be empty")
+	})
+}
+
+func TestListAnnotations(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	annotations := []*Annotation{
+		{Text: "deployed books", DashboardUID: "books", Tags: []string{"deploy", "books"}, Time: 1000},
+		{Text: "deployed authors", DashboardUID: "authors", Tags: []string{"deploy", "authors"}, Time: 2000},
+		{Text: "incident", Tags: []string{"incident"}, Time: 3000, TimeEnd: 4000},
+	}
+	for _, annotation := range annotations {
+		_, err := client.PostAnnotation(ctx, annotation)
+		require.NoError(t, err)
+	}
+	texts := func(request *ListAnnotationsRequest) []string {
+		annotations, err := client.ListAnnotations(ctx, request)
+		require.NoError(t, err)
+		texts := []string{}
+		for _, annotation := range annotations {
+			texts = append(texts, annotation.Text)
+		}
+		return texts
+	}
+
+	require.Equal(t, []string{"deployed books", "deployed authors", "incident"}, texts(&ListAnnotationsRequest{}))
+	require.Equal(t, []string{"deployed authors"}, texts(&ListAnnotationsRequest{DashboardUID: "authors"}))
+	require.Equal(t, []string{"deployed books", "deployed authors"}, texts(&ListAnnotationsRequest{Tags: []string{"deploy"}}))
+	require.Equal(t, []string{"deployed books"}, texts(&ListAnnotationsRequest{Tags: []string{"deploy", "books"}}))
+	require.Equal(t, []string{"deployed authors"}, texts(&ListAnnotationsRequest{From: time.UnixMilli(1500), To: time.UnixMilli(2500)}))
+	require.Equal(t, []string{"deployed books"}, texts(&ListAnnotationsRequest{Limit: 1}))
+
+	listed, err := client.ListAnnotations(ctx, &ListAnnotationsRequest{Tags: []string{"incident"}})
+	require.NoError(t, err)
+	require.Equal(t, []*Annotation{annotations[2]}, listed)
+}
@@ -0,0 +1,111 @@
+// Package grafana wraps the grafana sdk with the helpers our tooling needs to provision dashboards.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/pkg/errors"
+
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+// Opts holds grafana opts.
+type Opts struct {
+	APIURL string `long:"grafana-api-url" env:"GRAFANA_API_URL" description:"Grafana API url" required:"true"`
+	APIKey string `long:"grafana-api-key" env:"GRAFANA_API_KEY" description:"Grafana API key, or a secret reference" required:"true" secret-ref:"true"`
+}
+
+// Client is a wrapper around the grafana sdk client.
+type Client struct {
+	opts       Opts
+	httpClient *http.Client
+	*sdk.Client
+}
+
+// NewClient instantiates and returns a new grafana Client.
+func NewClient(opts Opts) (*Client, error) {
+	client, err := sdk.NewClient(opts.APIURL, opts.APIKey, sdk.DefaultHTTPClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "instantiating grafana client")
+	}
+	return &Client{opts: opts, httpClient: sdk.DefaultHTTPClient, Client: client}, nil
+}
+
+// MustNewClient calls NewClient and panics on error.
+func MustNewClient(opts Opts) *Client {
+	client, err := NewClient(opts)
+	if err != nil {
+		log.Panic(err)
+	}
+	return client
+}
+
+// do executes a request against an API the sdk does not cover. The request body is marshaled from `in` and the
+// response body is unmarshaled into `out`, if they are not nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return errors.Wrap(err, "marshaling request")
+		}
+		body = bytes.NewReader(payload)
+	}
+	url := strings.TrimSuffix(c.opts.APIURL, "/") + path
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	request.Header.Set("Authorization", "Bearer "+c.opts.APIKey)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	defer response.Body.Close()
+	responseBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading response")
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &HTTPError{Method: method, Path: path, StatusCode: response.StatusCode, Body: string(responseBytes)}
+	}
+	if out == nil || len(responseBytes) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(responseBytes, out); err != nil {
+		return errors.Wrap(err, "unmarshaling response")
+	}
+	return nil
+}
+
+// HTTPError is returned by raw API calls that do not return a 2xx status code.
+type HTTPError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP error %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// isNotFound returns true if the given error is an HTTPError with a 404 status code.
+func isNotFound(err error) bool {
+	var httpError *HTTPError
+	return errors.As(err, &httpError) && httpError.StatusCode == http.StatusNotFound
+}
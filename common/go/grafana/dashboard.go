@@ -0,0 +1,224 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/nsf/jsondiff"
+	"github.com/pkg/errors"
+)
+
+const (
+	generalFolderName = "General"
+	// The sdk surfaces non-200 responses as untyped errors prefixed with the http status.
+	notFoundError = "HTTP error 404"
+)
+
+// DashboardPlan describes the changes required to bring a grafana dashboard in line with a desired board.
+type DashboardPlan struct {
+	// The board we wish to upload.
+	Board *sdk.Board
+	// The title of the folder the board belongs to. Empty for grafana's default folder.
+	Folder string
+	// The id of the folder, or the default folder id if it does not exist yet.
+	FolderID int
+	// Set to true if a dashboard with the same uid (or title, if the board has no uid) exists in the folder.
+	Exists bool
+	// Human readable diff between the existing dashboard and the desired one. Empty if there are no changes.
+	Diff string
+}
+
+// HasChanges returns true if uploading the plan's board would modify grafana.
+func (p *DashboardPlan) HasChanges() bool {
+	return !p.Exists || p.Diff != ""
+}
+
+// Name returns a "{folder}/{title}" string for clear/consistent logging.
+func (p *DashboardPlan) Name() string {
+	folder := p.Folder
+	if folder == "" {
+		folder = generalFolderName
+	}
+	return folder + "/" + p.Board.Title
+}
+
+// GetOrCreateFolder returns the id of the folder with the given title, creating it if it doesn't exist.
+// An empty title maps to grafana's default `General` folder.
+func (c *Client) GetOrCreateFolder(ctx context.Context, title string) (int, error) {
+	if title == "" {
+		return sdk.DefaultFolderId, nil
+	}
+	folder, err := c.getOrCreateFolder(ctx, title)
+	if err != nil {
+		return 0, err
+	}
+	return folder.ID, nil
+}
+
+// getOrCreateFolder returns the folder with the given title, creating it if it doesn't exist.
+func (c *Client) getOrCreateFolder(ctx context.Context, title string) (*sdk.Folder, error) {
+	folder, ok, err := c.getFolder(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return folder, nil
+	}
+	created, err := c.CreateFolder(ctx, sdk.Folder{Title: title})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating folder")
+	}
+	if created.ID == sdk.DefaultFolderId {
+		return nil, errors.Errorf("folder %s created did not return an id", title)
+	}
+	log.Infof("created folder: %s", title)
+	return &created, nil
+}
+
+// getFolder returns the folder with the given title, if it exists.
+func (c *Client) getFolder(ctx context.Context, title string) (*sdk.Folder, bool, error) {
+	folders, err := c.GetAllFolders(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting folders")
+	}
+	for _, folder := range folders {
+		if folder.Title == title {
+			return &folder, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// getFolderID returns the id of the folder with the given title, or the default folder id if it does not exist.
+func (c *Client) getFolderID(ctx context.Context, title string) (int, error) {
+	if title == "" {
+		return sdk.DefaultFolderId, nil
+	}
+	folder, ok, err := c.getFolder(ctx, title)
+	if err != nil || !ok {
+		return sdk.DefaultFolderId, err
+	}
+	return folder.ID, nil
+}
+
+// PlanDashboard fetches the dashboard currently in grafana and diffs it against the given board.
+// It does not modify grafana, and does not create the folder if it is missing.
+func (c *Client) PlanDashboard(ctx context.Context, board *sdk.Board, folder string) (*DashboardPlan, error) {
+	plan := &DashboardPlan{Board: board, Folder: folder}
+	folderID, err := c.getFolderID(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	plan.FolderID = folderID
+	if folder != "" && folderID == sdk.DefaultFolderId {
+		// The folder does not exist yet, so neither does the dashboard.
+		return plan, nil
+	}
+
+	existing, ok, err := c.getDashboard(ctx, board, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return plan, nil
+	}
+	plan.Exists = true
+	plan.Diff, err = diffBoards(existing, board)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ApplyDashboard uploads the plan's board if it has changes. It returns the url of the dashboard if it was uploaded.
+func (c *Client) ApplyDashboard(ctx context.Context, plan *DashboardPlan) (string, error) {
+	if !plan.HasChanges() {
+		log.Infof("dashboard [%s] is up to date", plan.Name())
+		return "", nil
+	}
+	folderID := plan.FolderID
+	if plan.Folder != "" && folderID == sdk.DefaultFolderId {
+		var err error
+		if folderID, err = c.GetOrCreateFolder(ctx, plan.Folder); err != nil {
+			return "", err
+		}
+	}
+	params := sdk.SetDashboardParams{
+		FolderID:  folderID,
+		Overwrite: true,
+	}
+	response, err := c.SetDashboard(ctx, *plan.Board, params)
+	if err != nil {
+		return "", errors.Wrap(err, "uploading dashboard to grafana")
+	}
+	url := c.opts.APIURL + *response.URL
+	log.Infof("uploaded dashboard [%s] @ %s", plan.Name(), url)
+	return url, nil
+}
+
+// UploadDashboard plans and applies the given board.
+func (c *Client) UploadDashboard(ctx context.Context, board *sdk.Board, folder string) (*DashboardPlan, error) {
+	plan, err := c.PlanDashboard(ctx, board, folder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.ApplyDashboard(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// getDashboard returns the dashboard matching the given board's uid, or its title within the folder if the board has no uid.
+func (c *Client) getDashboard(ctx context.Context, board *sdk.Board, folderID int) (*sdk.Board, bool, error) {
+	uid := board.UID
+	if uid == "" {
+		foundBoards, err := c.Search(ctx, sdk.SearchType(sdk.SearchTypeDashboard), sdk.SearchQuery(board.Title))
+		if err != nil {
+			return nil, false, errors.Wrap(err, "searching dashboards")
+		}
+		for _, foundBoard := range foundBoards {
+			if foundBoard.Title == board.Title && foundBoard.FolderID == folderID {
+				uid = foundBoard.UID
+				break
+			}
+		}
+		if uid == "" {
+			return nil, false, nil
+		}
+	}
+	existing, _, err := c.GetDashboardByUID(ctx, uid)
+	if err != nil {
+		// The sdk does not expose a typed not found error.
+		if strings.Contains(err.Error(), notFoundError) {
+			return nil, false, nil
+		}
+		return nil, false, errors.Wrapf(err, "getting dashboard %s", uid)
+	}
+	return &existing, true, nil
+}
+
+// diffBoards returns a human readable diff between an existing board and a desired one, ignoring the fields grafana manages.
+func diffBoards(existing, desired *sdk.Board) (string, error) {
+	existingCopy := *existing
+	existingCopy.ID = desired.ID
+	existingCopy.Version = desired.Version
+	if desired.UID == "" {
+		existingCopy.UID = ""
+	}
+	existingBytes, err := json.Marshal(&existingCopy)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling existing board")
+	}
+	desiredBytes, err := json.Marshal(desired)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling desired board")
+	}
+	options := jsondiff.DefaultConsoleOptions()
+	result, diff := jsondiff.Compare(existingBytes, desiredBytes, &options)
+	if result == jsondiff.FullMatch {
+		return "", nil
+	}
+	return diff, nil
+}
@@ -0,0 +1,110 @@
+package grafana
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffBoards(t *testing.T) {
+	desired := &sdk.Board{UID: "uid", Title: "Books", Tags: []string{"library"}}
+
+	t.Run("IgnoresFieldsGrafanaManages", func(t *testing.T) {
+		existing := *desired
+		existing.ID = 12
+		existing.Version = 3
+		diff, err := diffBoards(&existing, desired)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+	})
+
+	t.Run("IgnoresGeneratedUIDs", func(t *testing.T) {
+		desired := &sdk.Board{Title: "Books"}
+		existing := &sdk.Board{UID: "generated", Title: "Books"}
+		diff, err := diffBoards(existing, desired)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+	})
+
+	t.Run("ReportsChanges", func(t *testing.T) {
+		existing := *desired
+		existing.Tags = []string{"authors"}
+		diff, err := diffBoards(&existing, desired)
+		require.NoError(t, err)
+		require.Contains(t, diff, "authors")
+		require.Contains(t, diff, "library")
+	})
+}
+
+func TestDashboardPlanName(t *testing.T) {
+	board := &sdk.Board{Title: "Books"}
+	require.Equal(t, "General/Books", (&DashboardPlan{Board: board}).Name())
+	require.Equal(t, "library/Books", (&DashboardPlan{Board: board, Folder: "library"}).Name())
+}
+
+func TestPlanAndApplyDashboard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PlanningDoesNotModifyGrafana", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanDashboard(ctx, &sdk.Board{UID: "books", Title: "Books"}, "library")
+		require.NoError(t, err)
+		require.False(t, plan.Exists)
+		require.True(t, plan.HasChanges())
+		require.Equal(t, sdk.DefaultFolderId, plan.FolderID)
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("ApplyCreatesTheFolderAndDashboard", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanDashboard(ctx, &sdk.Board{UID: "books", Title: "Books"}, "library")
+		require.NoError(t, err)
+		url, err := client.ApplyDashboard(ctx, plan)
+		require.NoError(t, err)
+		require.Equal(t, client.opts.APIURL+"/d/books", url)
+		require.Equal(t, map[string]int{"Books": grafana.folderID("library")}, grafana.dashboardFolderIDs())
+	})
+
+	t.Run("SkipsUpToDateDashboards", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		_, err := client.UploadDashboard(ctx, &sdk.Board{UID: "books", Title: "Books"}, "library")
+		require.NoError(t, err)
+		grafana.resetMutations()
+
+		plan, err := client.UploadDashboard(ctx, &sdk.Board{UID: "books", Title: "Books"}, "library")
+		require.NoError(t, err)
+		require.True(t, plan.Exists)
+		require.False(t, plan.HasChanges())
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("UploadsChangedDashboards", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		_, err := client.UploadDashboard(ctx, &sdk.Board{UID: "books", Title: "Books"}, "")
+		require.NoError(t, err)
+		grafana.resetMutations()
+
+		plan, err := client.UploadDashboard(ctx, &sdk.Board{UID: "books", Title: "Books", Timezone: "utc"}, "")
+		require.NoError(t, err)
+		require.True(t, plan.Exists)
+		require.Contains(t, plan.Diff, "utc")
+		require.Equal(t, []string{"POST /api/dashboards/db"}, grafana.mutations)
+	})
+
+	t.Run("MatchesDashboardsWithoutUIDByTitleWithinTheirFolder", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		_, err := client.UploadDashboard(ctx, &sdk.Board{Title: "Books"}, "library")
+		require.NoError(t, err)
+
+		plan, err := client.PlanDashboard(ctx, &sdk.Board{Title: "Books"}, "library")
+		require.NoError(t, err)
+		require.True(t, plan.Exists)
+		require.False(t, plan.HasChanges())
+
+		plan, err = client.PlanDashboard(ctx, &sdk.Board{Title: "Books"}, "")
+		require.NoError(t, err)
+		require.False(t, plan.Exists)
+	})
+}
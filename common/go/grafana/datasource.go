@@ -0,0 +1,196 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/nsf/jsondiff"
+	"github.com/pkg/errors"
+)
+
+// Datasource is the declarative representation of a grafana datasource.
+type Datasource struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Access        string `json:"access"`
+	URL           string `json:"url"`
+	IsDefault     bool   `json:"isDefault"`
+	BasicAuth     bool   `json:"basicAuth"`
+	BasicAuthUser string `json:"basicAuthUser"`
+	// Non sensitive, datasource specific settings.
+	JSONData map[string]any `json:"jsonData"`
+	// Sensitive, datasource specific settings. Values are interpolated with environment variables, using the `${VAR}`
+	// syntax, so that secrets never need to be checked in.
+	SecureJSONData map[string]string `json:"secureJsonData"`
+}
+
+// toSDK interpolates the datasource's secure json data and returns its sdk representation.
+func (d *Datasource) toSDK() (sdk.Datasource, error) {
+	secureJSONData := make(map[string]string, len(d.SecureJSONData))
+	for key, value := range d.SecureJSONData {
+		interpolated, err := interpolateEnv(value)
+		if err != nil {
+			return sdk.Datasource{}, errors.Wrapf(err, "interpolating secure json data %s", key)
+		}
+		secureJSONData[key] = interpolated
+	}
+	datasource := sdk.Datasource{
+		Name:           d.Name,
+		Type:           d.Type,
+		Access:         d.Access,
+		URL:            d.URL,
+		IsDefault:      d.IsDefault,
+		JSONData:       d.JSONData,
+		SecureJSONData: secureJSONData,
+	}
+	if d.BasicAuth {
+		basicAuth, basicAuthUser := d.BasicAuth, d.BasicAuthUser
+		datasource.BasicAuth = &basicAuth
+		datasource.BasicAuthUser = &basicAuthUser
+	}
+	return datasource, nil
+}
+
+// DatasourcePlan describes the changes required to bring a grafana datasource in line with a desired one.
+type DatasourcePlan struct {
+	// The datasource we wish to provision.
+	Datasource *Datasource
+	// Set to true if a datasource with the same name exists.
+	Exists bool
+	// Human readable diff between the existing datasource and the desired one. Empty if there are no changes.
+	// Grafana never returns secure json data, so it is not diffed.
+	Diff string
+}
+
+// HasChanges returns true if provisioning the plan's datasource would modify its non sensitive settings.
+func (p *DatasourcePlan) HasChanges() bool {
+	return !p.Exists || p.Diff != ""
+}
+
+// PlanDatasource fetches the datasource currently in grafana and diffs it against the given one.
+// It does not modify grafana.
+func (c *Client) PlanDatasource(ctx context.Context, datasource *Datasource) (*DatasourcePlan, error) {
+	plan := &DatasourcePlan{Datasource: datasource}
+	existing, ok, err := c.GetDatasource(ctx, datasource.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return plan, nil
+	}
+	plan.Exists = true
+	if plan.Diff, err = diffDatasources(existing, datasource); err != nil {
+		return nil, errors.Wrapf(err, "diffing datasource %s", datasource.Name)
+	}
+	return plan, nil
+}
+
+// diffDatasources returns a human readable diff between the non sensitive settings of an existing datasource and a
+// desired one.
+func diffDatasources(existing *sdk.Datasource, desired *Datasource) (string, error) {
+	existingCopy := &Datasource{
+		Name:      existing.Name,
+		Type:      existing.Type,
+		Access:    existing.Access,
+		URL:       existing.URL,
+		IsDefault: existing.IsDefault,
+		BasicAuth: existing.BasicAuth != nil && *existing.BasicAuth,
+	}
+	if existingCopy.BasicAuth && existing.BasicAuthUser != nil {
+		existingCopy.BasicAuthUser = *existing.BasicAuthUser
+	}
+	if existing.JSONData != nil {
+		bytes, err := json.Marshal(existing.JSONData)
+		if err != nil {
+			return "", errors.Wrap(err, "marshaling existing json data")
+		}
+		if err := json.Unmarshal(bytes, &existingCopy.JSONData); err != nil {
+			return "", errors.Wrap(err, "unmarshaling existing json data")
+		}
+	}
+	desiredCopy := *desired
+	desiredCopy.SecureJSONData = nil
+	if !desiredCopy.BasicAuth {
+		desiredCopy.BasicAuthUser = ""
+	}
+	// Grafana returns empty json data for datasources created without any.
+	if len(existingCopy.JSONData) == 0 {
+		existingCopy.JSONData = nil
+	}
+	if len(desiredCopy.JSONData) == 0 {
+		desiredCopy.JSONData = nil
+	}
+
+	existingBytes, err := json.Marshal(existingCopy)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling existing datasource")
+	}
+	desiredBytes, err := json.Marshal(&desiredCopy)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling desired datasource")
+	}
+	options := jsondiff.DefaultConsoleOptions()
+	if result, diff := jsondiff.Compare(existingBytes, desiredBytes, &options); result != jsondiff.FullMatch {
+		return diff, nil
+	}
+	return "", nil
+}
+
+// GetDatasource returns the datasource with the given name, if it exists.
+func (c *Client) GetDatasource(ctx context.Context, name string) (*sdk.Datasource, bool, error) {
+	datasources, err := c.GetAllDatasources(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting datasources")
+	}
+	for _, datasource := range datasources {
+		if datasource.Name == name {
+			return &datasource, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// CreateOrUpdateDatasource creates the given datasource, or updates the existing datasource with the same name.
+// It returns true if the datasource was created.
+func (c *Client) CreateOrUpdateDatasource(ctx context.Context, datasource *Datasource) (bool, error) {
+	sdkDatasource, err := datasource.toSDK()
+	if err != nil {
+		return false, err
+	}
+	existing, ok, err := c.GetDatasource(ctx, datasource.Name)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		if _, err := c.CreateDatasource(ctx, sdkDatasource); err != nil {
+			return false, errors.Wrapf(err, "creating datasource %s", datasource.Name)
+		}
+		log.Infof("created datasource [%s]", datasource.Name)
+		return true, nil
+	}
+	sdkDatasource.ID = existing.ID
+	sdkDatasource.OrgID = existing.OrgID
+	if _, err := c.UpdateDatasource(ctx, sdkDatasource); err != nil {
+		return false, errors.Wrapf(err, "updating datasource %s", datasource.Name)
+	}
+	log.Infof("updated datasource [%s]", datasource.Name)
+	return false, nil
+}
+
+// interpolateEnv expands `${VAR}` references in the given string, failing if a variable is not set.
+func interpolateEnv(value string) (string, error) {
+	var missing []string
+	interpolated := os.Expand(value, func(key string) string {
+		envValue, ok := os.LookupEnv(key)
+		if !ok {
+			missing = append(missing, key)
+		}
+		return envValue
+	})
+	if len(missing) > 0 {
+		return "", errors.Errorf("environment variables %v are not set", missing)
+	}
+	return interpolated, nil
+}
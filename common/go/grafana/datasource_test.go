@@ -0,0 +1,122 @@
+package grafana
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("GRAFANA_TEST_PASSWORD", "hunter2")
+
+	t.Run("NoVariables", func(t *testing.T) {
+		interpolated, err := interpolateEnv("plain")
+		require.NoError(t, err)
+		require.Equal(t, "plain", interpolated)
+	})
+
+	t.Run("Variable", func(t *testing.T) {
+		interpolated, err := interpolateEnv("password=${GRAFANA_TEST_PASSWORD}")
+		require.NoError(t, err)
+		require.Equal(t, "password=hunter2", interpolated)
+	})
+
+	t.Run("MissingVariable", func(t *testing.T) {
+		_, err := interpolateEnv("${GRAFANA_TEST_PASSWORD}${GRAFANA_TEST_MISSING}")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "GRAFANA_TEST_MISSING")
+	})
+}
+
+func TestDatasourceToSDK(t *testing.T) {
+	t.Setenv("GRAFANA_TEST_PASSWORD", "hunter2")
+	datasource := &Datasource{
+		Name:           "prometheus",
+		Type:           "prometheus",
+		BasicAuth:      true,
+		BasicAuthUser:  "admin",
+		SecureJSONData: map[string]string{"basicAuthPassword": "${GRAFANA_TEST_PASSWORD}"},
+	}
+	sdkDatasource, err := datasource.toSDK()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"basicAuthPassword": "hunter2"}, sdkDatasource.SecureJSONData)
+	require.True(t, *sdkDatasource.BasicAuth)
+	require.Equal(t, "admin", *sdkDatasource.BasicAuthUser)
+	// The declarative config must not be mutated with secrets.
+	require.Equal(t, "${GRAFANA_TEST_PASSWORD}", datasource.SecureJSONData["basicAuthPassword"])
+}
+
+func TestPlanDatasource(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("GRAFANA_TEST_PASSWORD", "hunter2")
+	newDatasource := func() *Datasource {
+		return &Datasource{
+			Name:           "prometheus",
+			Type:           "prometheus",
+			Access:         "proxy",
+			URL:            "http://prometheus:9090",
+			BasicAuth:      true,
+			BasicAuthUser:  "admin",
+			JSONData:       map[string]any{"timeInterval": "15s"},
+			SecureJSONData: map[string]string{"basicAuthPassword": "${GRAFANA_TEST_PASSWORD}"},
+		}
+	}
+
+	t.Run("MissingDatasource", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		plan, err := client.PlanDatasource(ctx, newDatasource())
+		require.NoError(t, err)
+		require.False(t, plan.Exists)
+		require.True(t, plan.HasChanges())
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("UpToDateDatasource", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		created, err := client.CreateOrUpdateDatasource(ctx, newDatasource())
+		require.NoError(t, err)
+		require.True(t, created)
+		grafana.resetMutations()
+
+		// Secure json data is never returned by grafana, and must not register as a change.
+		plan, err := client.PlanDatasource(ctx, newDatasource())
+		require.NoError(t, err)
+		require.True(t, plan.Exists)
+		require.False(t, plan.HasChanges(), plan.Diff)
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("DatasourceWithoutJSONData", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		datasource := &Datasource{Name: "loki", Type: "loki", URL: "http://loki:3100"}
+		_, err := client.CreateOrUpdateDatasource(ctx, datasource)
+		require.NoError(t, err)
+
+		plan, err := client.PlanDatasource(ctx, datasource)
+		require.NoError(t, err)
+		require.False(t, plan.HasChanges(), plan.Diff)
+	})
+
+	t.Run("ChangedDatasource", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		_, err := client.CreateOrUpdateDatasource(ctx, newDatasource())
+		require.NoError(t, err)
+
+		datasource := newDatasource()
+		datasource.URL = "http://prometheus:9091"
+		datasource.JSONData["timeInterval"] = "30s"
+		plan, err := client.PlanDatasource(ctx, datasource)
+		require.NoError(t, err)
+		require.True(t, plan.Exists)
+		require.Contains(t, plan.Diff, "9091")
+		require.Contains(t, plan.Diff, "30s")
+
+		created, err := client.CreateOrUpdateDatasource(ctx, datasource)
+		require.NoError(t, err)
+		require.False(t, created)
+		plan, err = client.PlanDatasource(ctx, datasource)
+		require.NoError(t, err)
+		require.False(t, plan.HasChanges(), plan.Diff)
+	})
+}
@@ -0,0 +1,340 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDashboard is a dashboard stored by the fake grafana.
+type fakeDashboard struct {
+	folderID int
+	board    map[string]any
+}
+
+// fakeGrafana is an in-memory grafana, implementing the subset of its API our client uses.
+type fakeGrafana struct {
+	mutex       sync.Mutex
+	nextID      int
+	folders     []*sdk.Folder
+	dashboards  map[string]*fakeDashboard
+	datasources []*sdk.Datasource
+	annotations []*Annotation
+	// Rule groups keyed by "{folder uid}/{title}".
+	ruleGroups map[string]*ruleGroupPayload
+	// Mutating requests, as "{method} {path}".
+	mutations []string
+}
+
+// newTestClient returns a client of a new fake grafana.
+func newTestClient(t *testing.T) (*Client, *fakeGrafana) {
+	grafana := &fakeGrafana{dashboards: map[string]*fakeDashboard{}, ruleGroups: map[string]*ruleGroupPayload{}}
+	server := httptest.NewServer(grafana)
+	t.Cleanup(server.Close)
+	client, err := NewClient(Opts{APIURL: server.URL, APIKey: "key"})
+	require.NoError(t, err)
+	return client, grafana
+}
+
+func (g *fakeGrafana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if r.Method != http.MethodGet {
+		g.mutations = append(g.mutations, r.Method+" "+r.URL.Path)
+	}
+	status, response := g.handle(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (g *fakeGrafana) id() int {
+	g.nextID++
+	return g.nextID
+}
+
+func notFound() (int, any) {
+	return http.StatusNotFound, map[string]string{"message": "not found"}
+}
+
+func (g *fakeGrafana) handle(r *http.Request) (int, any) {
+	path := r.URL.Path
+	switch {
+	case path == "/api/folders" && r.Method == http.MethodGet:
+		return http.StatusOK, g.folders
+	case path == "/api/folders" && r.Method == http.MethodPost:
+		folder := &sdk.Folder{}
+		if err := json.NewDecoder(r.Body).Decode(folder); err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+		folder.ID = g.id()
+		folder.UID = fmt.Sprintf("folder-%d", folder.ID)
+		g.folders = append(g.folders, folder)
+		return http.StatusOK, folder
+	case strings.HasPrefix(path, "/api/folders/") && r.Method == http.MethodDelete:
+		uid := strings.TrimPrefix(path, "/api/folders/")
+		for i, folder := range g.folders {
+			if folder.UID == uid {
+				g.folders = append(g.folders[:i], g.folders[i+1:]...)
+				return http.StatusOK, map[string]string{"message": "deleted"}
+			}
+		}
+		return notFound()
+	case path == "/api/search":
+		return http.StatusOK, g.search(r)
+	case path == "/api/dashboards/db" && r.Method == http.MethodPost:
+		return g.setDashboard(r)
+	case strings.HasPrefix(path, "/api/dashboards/uid/"):
+		uid := strings.TrimPrefix(path, "/api/dashboards/uid/")
+		dashboard, ok := g.dashboards[uid]
+		if !ok {
+			return notFound()
+		}
+		if r.Method == http.MethodDelete {
+			delete(g.dashboards, uid)
+			return http.StatusOK, map[string]string{"title": dashboard.board["title"].(string)}
+		}
+		return http.StatusOK, map[string]any{"dashboard": dashboard.board, "meta": map[string]any{"folderId": dashboard.folderID}}
+	case path == "/api/annotations":
+		return g.handleAnnotation(r)
+	case strings.HasPrefix(path, "/api/datasources"):
+		return g.handleDatasource(r)
+	case strings.HasPrefix(path, "/api/v1/provisioning/folder/"):
+		return g.handleRuleGroup(r)
+	case strings.HasPrefix(path, "/api/v1/provisioning/alert-rules"):
+		return g.handleAlertRule(r)
+	}
+	return notFound()
+}
+
+func (g *fakeGrafana) handleAnnotation(r *http.Request) (int, any) {
+	if r.Method == http.MethodPost {
+		annotation := &Annotation{}
+		if err := json.NewDecoder(r.Body).Decode(annotation); err != nil {
+			return http.StatusBadRequest, map[string]string{"message": err.Error()}
+		}
+		if annotation.Text == "" {
+			return http.StatusBadRequest, map[string]string{"message": "text field should not be empty"}
+		}
+		annotation.ID = int64(g.id())
+		g.annotations = append(g.annotations, annotation)
+		return http.StatusOK, map[string]any{"id": annotation.ID, "message": "Annotation added"}
+	}
+	query := r.URL.Query()
+	from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+	to, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	annotations := []*Annotation{}
+	for _, annotation := range g.annotations {
+		if dashboardUID := query.Get("dashboardUID"); dashboardUID != "" && annotation.DashboardUID != dashboardUID {
+			continue
+		}
+		if (from != 0 && annotation.Time < from) || (to != 0 && annotation.Time > to) {
+			continue
+		}
+		tags := map[string]bool{}
+		for _, tag := range annotation.Tags {
+			tags[tag] = true
+		}
+		matchesTags := true
+		for _, tag := range query["tags"] {
+			matchesTags = matchesTags && tags[tag]
+		}
+		if !matchesTags {
+			continue
+		}
+		if limit > 0 && len(annotations) == limit {
+			break
+		}
+		annotations = append(annotations, annotation)
+	}
+	return http.StatusOK, annotations
+}
+
+func (g *fakeGrafana) handleDatasource(r *http.Request) (int, any) {
+	if r.Method == http.MethodGet {
+		return http.StatusOK, g.datasources
+	}
+	datasource := &sdk.Datasource{}
+	if err := json.NewDecoder(r.Body).Decode(datasource); err != nil {
+		return http.StatusBadRequest, map[string]string{"message": err.Error()}
+	}
+	// Grafana never returns secure json data.
+	datasource.SecureJSONData = nil
+	if datasource.JSONData == nil {
+		datasource.JSONData = map[string]any{}
+	}
+	if r.Method == http.MethodPost {
+		datasource.ID = uint(g.id())
+		g.datasources = append(g.datasources, datasource)
+		return http.StatusOK, map[string]any{"id": datasource.ID, "message": "Datasource added"}
+	}
+	id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/datasources/"))
+	for i, existing := range g.datasources {
+		if existing.ID == uint(id) {
+			g.datasources[i] = datasource
+			return http.StatusOK, map[string]any{"id": datasource.ID, "message": "Datasource updated"}
+		}
+	}
+	return notFound()
+}
+
+func (g *fakeGrafana) handleRuleGroup(r *http.Request) (int, any) {
+	// /api/v1/provisioning/folder/{folder uid}/rule-groups/{title}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/provisioning/folder/"), "/")
+	key := parts[0] + "/" + parts[2]
+	if r.Method == http.MethodGet {
+		ruleGroup, ok := g.ruleGroups[key]
+		if !ok {
+			return notFound()
+		}
+		return http.StatusOK, ruleGroup
+	}
+	ruleGroup := &ruleGroupPayload{}
+	if err := json.NewDecoder(r.Body).Decode(ruleGroup); err != nil {
+		return http.StatusBadRequest, map[string]string{"message": err.Error()}
+	}
+	for _, rule := range ruleGroup.Rules {
+		fillAlertQueryModels(rule)
+	}
+	g.ruleGroups[key] = ruleGroup
+	return http.StatusOK, ruleGroup
+}
+
+// fillAlertQueryModels fills in the models of a rule's queries, as grafana does.
+func fillAlertQueryModels(rule *ProvisionedAlertRule) {
+	for i, query := range rule.Data {
+		model := map[string]any{}
+		json.Unmarshal(query.Model, &model)
+		model["intervalMs"] = 1000
+		model["maxDataPoints"] = 43200
+		model["refId"] = query.RefID
+		rule.Data[i].Model, _ = json.Marshal(model)
+	}
+}
+
+func (g *fakeGrafana) handleAlertRule(r *http.Request) (int, any) {
+	uid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/provisioning/alert-rules"), "/")
+	rule := &ProvisionedAlertRule{}
+	if r.Method != http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+			return http.StatusBadRequest, map[string]string{"message": err.Error()}
+		}
+		fillAlertQueryModels(rule)
+	}
+	if r.Method == http.MethodPost {
+		key := rule.FolderUID + "/" + rule.RuleGroup
+		if _, ok := g.ruleGroups[key]; !ok {
+			g.ruleGroups[key] = &ruleGroupPayload{Title: rule.RuleGroup, FolderUID: rule.FolderUID, Interval: 60}
+		}
+		g.ruleGroups[key].Rules = append(g.ruleGroups[key].Rules, rule)
+		return http.StatusCreated, rule
+	}
+	for _, ruleGroup := range g.ruleGroups {
+		for i, existing := range ruleGroup.Rules {
+			if existing.UID != uid {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				ruleGroup.Rules = append(ruleGroup.Rules[:i], ruleGroup.Rules[i+1:]...)
+				return http.StatusNoContent, nil
+			}
+			ruleGroup.Rules[i] = rule
+			return http.StatusOK, rule
+		}
+	}
+	return notFound()
+}
+
+func (g *fakeGrafana) search(r *http.Request) []sdk.FoundBoard {
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	folderIDs := map[int]bool{}
+	for _, value := range r.URL.Query()["folderIds"] {
+		folderID, _ := strconv.Atoi(value)
+		folderIDs[folderID] = true
+	}
+	foundBoards := []sdk.FoundBoard{}
+	for uid, dashboard := range g.dashboards {
+		title := dashboard.board["title"].(string)
+		if !strings.Contains(strings.ToLower(title), query) {
+			continue
+		}
+		if len(folderIDs) > 0 && !folderIDs[dashboard.folderID] {
+			continue
+		}
+		foundBoards = append(foundBoards, sdk.FoundBoard{UID: uid, Title: title, Type: "dash-db", FolderID: dashboard.folderID})
+	}
+	return foundBoards
+}
+
+func (g *fakeGrafana) setDashboard(r *http.Request) (int, any) {
+	request := &struct {
+		Dashboard map[string]any `json:"dashboard"`
+		FolderID  int            `json:"folderId"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		return http.StatusBadRequest, map[string]string{"message": err.Error()}
+	}
+	board := request.Dashboard
+	uid, _ := board["uid"].(string)
+	if uid == "" {
+		// Grafana matches dashboards without a uid by title, within their folder.
+		for existingUID, dashboard := range g.dashboards {
+			if dashboard.board["title"] == board["title"] && dashboard.folderID == request.FolderID {
+				uid = existingUID
+			}
+		}
+	}
+	version := 1
+	if existing, ok := g.dashboards[uid]; ok {
+		board["id"] = existing.board["id"]
+		version = int(existing.board["version"].(float64)) + 1
+	} else {
+		if uid == "" {
+			uid = fmt.Sprintf("dashboard-%d", g.nextID+1)
+		}
+		board["id"] = float64(g.id())
+	}
+	board["uid"] = uid
+	board["version"] = float64(version)
+	g.dashboards[uid] = &fakeDashboard{folderID: request.FolderID, board: board}
+	return http.StatusOK, map[string]any{"status": "success", "uid": uid, "url": "/d/" + uid, "version": version}
+}
+
+// folderID returns the id of the folder with the given title, or -1.
+func (g *fakeGrafana) folderID(title string) int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, folder := range g.folders {
+		if folder.Title == title {
+			return folder.ID
+		}
+	}
+	return -1
+}
+
+// dashboardFolderIDs returns the folder id of every dashboard, keyed by title.
+func (g *fakeGrafana) dashboardFolderIDs() map[string]int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	titleToFolderID := map[string]int{}
+	for _, dashboard := range g.dashboards {
+		titleToFolderID[dashboard.board["title"].(string)] = dashboard.folderID
+	}
+	return titleToFolderID
+}
+
+// resetMutations clears the recorded mutations.
+func (g *fakeGrafana) resetMutations() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.mutations = nil
+}
@@ -0,0 +1,245 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"common/go/jsonnet"
+)
+
+const defaultSyncConcurrency = 4
+
+// SyncOpts configures a directory sync.
+type SyncOpts struct {
+	// Maximum number of dashboards planned / uploaded concurrently. Defaults to 4.
+	Concurrency int
+	// If true, dashboards living in a synced folder but absent from the directory are deleted, as are the synced
+	// folders left without dashboards. A subdirectory is synced even if it holds no dashboards anymore.
+	Prune bool
+	// If true, grafana is left untouched and the result only describes what would change.
+	DryRun bool
+	// Library paths, ext vars and top-level arguments used to evaluate jsonnet files.
+	// The synced directory is always searched for imports first.
+	Jsonnet jsonnet.EvaluateOpts
+}
+
+// SyncResult describes the outcome of a directory sync.
+type SyncResult struct {
+	// One plan per dashboard file found in the directory.
+	Plans []*DashboardPlan
+	// Dashboards that were (or, in dry run mode, would be) pruned.
+	Pruned []sdk.FoundBoard
+	// Titles of the folders that were (or, in dry run mode, would be) pruned.
+	PrunedFolders []string
+}
+
+// HasChanges returns true if the sync modified (or, in dry run mode, would modify) grafana.
+func (r *SyncResult) HasChanges() bool {
+	for _, plan := range r.Plans {
+		if plan.HasChanges() {
+			return true
+		}
+	}
+	return len(r.Pruned) > 0 || len(r.PrunedFolders) > 0
+}
+
+// SyncDirectory uploads every dashboard found in the given directory. Files at the root of the directory are uploaded
+// to the default folder, files in a subdirectory are uploaded to a folder named after the subdirectory's relative path.
+// Both `.json` and `.jsonnet` files are supported; jsonnet files can import files relative to the directory.
+func (c *Client) SyncDirectory(ctx context.Context, directory string, opts SyncOpts) (*SyncResult, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultSyncConcurrency
+	}
+	folderToBoards, err := loadDirectory(directory, opts.Jsonnet)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	mutex := sync.Mutex{}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(opts.Concurrency)
+	for folder, boards := range folderToBoards {
+		// Create folders sequentially to avoid racing to create the same folder.
+		if !opts.DryRun && len(boards) > 0 {
+			if _, err := c.GetOrCreateFolder(ctx, folder); err != nil {
+				return nil, err
+			}
+		}
+		for _, board := range boards {
+			folder, board := folder, board
+			errGroup.Go(func() error {
+				plan, err := c.PlanDashboard(groupCtx, board, folder)
+				if err != nil {
+					return errors.Wrapf(err, "planning dashboard %s", board.Title)
+				}
+				if !opts.DryRun {
+					if _, err := c.ApplyDashboard(groupCtx, plan); err != nil {
+						return errors.Wrapf(err, "applying dashboard %s", board.Title)
+					}
+				}
+				mutex.Lock()
+				result.Plans = append(result.Plans, plan)
+				mutex.Unlock()
+				return nil
+			})
+		}
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	sort.Slice(result.Plans, func(i, j int) bool { return result.Plans[i].Name() < result.Plans[j].Name() })
+
+	if opts.Prune {
+		folders := make([]string, 0, len(folderToBoards))
+		for folder := range folderToBoards {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+		if err := c.prune(ctx, folders, result, opts.DryRun); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// prune deletes the dashboards of the given folders which are not part of the result's plans, then the folders left
+// without dashboards.
+func (c *Client) prune(ctx context.Context, folders []string, result *SyncResult, dryRun bool) error {
+	folderToTitles := map[string]map[string]struct{}{}
+	uids := map[string]struct{}{}
+	for _, plan := range result.Plans {
+		if _, ok := folderToTitles[plan.Folder]; !ok {
+			folderToTitles[plan.Folder] = map[string]struct{}{}
+		}
+		folderToTitles[plan.Folder][plan.Board.Title] = struct{}{}
+		if plan.Board.UID != "" {
+			uids[plan.Board.UID] = struct{}{}
+		}
+	}
+
+	for _, folder := range folders {
+		folderID := sdk.DefaultFolderId
+		var grafanaFolder *sdk.Folder
+		if folder != "" {
+			var ok bool
+			var err error
+			grafanaFolder, ok, err = c.getFolder(ctx, folder)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// Folder does not exist (dry run), there is nothing to prune.
+				continue
+			}
+			folderID = grafanaFolder.ID
+		}
+		foundBoards, err := c.Search(ctx, sdk.SearchType(sdk.SearchTypeDashboard), sdk.SearchFolderID(folderID))
+		if err != nil {
+			return errors.Wrap(err, "searching dashboards")
+		}
+		titles := folderToTitles[folder]
+		var numRemainingBoards int
+		for _, foundBoard := range foundBoards {
+			if foundBoard.FolderID != folderID {
+				continue
+			}
+			if _, ok := uids[foundBoard.UID]; ok {
+				numRemainingBoards++
+				continue
+			}
+			if _, ok := titles[foundBoard.Title]; ok {
+				numRemainingBoards++
+				continue
+			}
+			result.Pruned = append(result.Pruned, foundBoard)
+			if dryRun {
+				continue
+			}
+			if _, err := c.DeleteDashboardByUID(ctx, foundBoard.UID); err != nil {
+				return errors.Wrapf(err, "deleting dashboard %s", foundBoard.Title)
+			}
+			log.Infof("pruned dashboard [%s] (%s)", foundBoard.Title, foundBoard.UID)
+		}
+
+		// The default folder cannot be deleted, and folders with dashboards in the directory are kept.
+		if grafanaFolder == nil || numRemainingBoards > 0 || len(titles) > 0 {
+			continue
+		}
+		result.PrunedFolders = append(result.PrunedFolders, folder)
+		if dryRun {
+			continue
+		}
+		if _, err := c.DeleteFolderByUID(ctx, grafanaFolder.UID); err != nil {
+			return errors.Wrapf(err, "deleting folder %s", folder)
+		}
+		log.Infof("pruned folder [%s]", folder)
+	}
+	return nil
+}
+
+// loadDirectory walks a directory and returns the boards it contains, keyed by folder title.
+func loadDirectory(directory string, evaluateOpts jsonnet.EvaluateOpts) (map[string][]*sdk.Board, error) {
+	evaluateOpts.LibraryPaths = append([]string{directory}, evaluateOpts.LibraryPaths...)
+	vm := evaluateOpts.NewVM()
+	folderToBoards := map[string][]*sdk.Board{}
+	walkFN := func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			// Register every subdirectory, so that the folders of removed dashboards are pruned.
+			if path != directory {
+				folder, err := filepath.Rel(directory, path)
+				if err != nil {
+					return err
+				}
+				if _, ok := folderToBoards[folder]; !ok {
+					folderToBoards[folder] = nil
+				}
+			}
+			return nil
+		}
+		var bytes []byte
+		switch filepath.Ext(path) {
+		case ".json":
+			if bytes, err = os.ReadFile(path); err != nil {
+				return errors.Wrapf(err, "reading %s", path)
+			}
+		case ".jsonnet":
+			content, err := vm.EvaluateFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "evaluating %s", path)
+			}
+			bytes = []byte(content)
+		default:
+			return nil
+		}
+		board := &sdk.Board{}
+		if err := json.Unmarshal(bytes, board); err != nil {
+			return errors.Wrapf(err, "unmarshaling board %s", path)
+		}
+		folder, err := filepath.Rel(directory, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if folder == "." {
+			folder = ""
+		}
+		folderToBoards[folder] = append(folderToBoards[folder], board)
+		return nil
+	}
+	if err := filepath.WalkDir(directory, walkFN); err != nil {
+		return nil, errors.Wrapf(err, "walking %s", directory)
+	}
+	return folderToBoards, nil
+}
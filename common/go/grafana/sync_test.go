@@ -0,0 +1,137 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana-tools/sdk"
+	"github.com/stretchr/testify/require"
+
+	"common/go/jsonnet"
+)
+
+// writeDirectory writes the given files, keyed by relative path, to a new directory.
+func writeDirectory(t *testing.T, files map[string]string) string {
+	directory := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(directory, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return directory
+}
+
+func boardJSON(t *testing.T, uid, title string) string {
+	bytes, err := json.Marshal(&sdk.Board{UID: uid, Title: title})
+	require.NoError(t, err)
+	return string(bytes)
+}
+
+func TestLoadDirectory(t *testing.T) {
+	directory := writeDirectory(t, map[string]string{
+		"home.json":                boardJSON(t, "home", "Home"),
+		"library/books.json":       boardJSON(t, "books", "Books"),
+		"library/authors.json":     boardJSON(t, "authors", "Authors"),
+		"library/shelf/racks.json": boardJSON(t, "racks", "Racks"),
+		"archive/lib.libsonnet":    "{}",
+		"README.md":                "# Dashboards",
+	})
+	folderToBoards, err := loadDirectory(directory, jsonnet.EvaluateOpts{})
+	require.NoError(t, err)
+
+	folderToTitles := map[string][]string{}
+	for folder, boards := range folderToBoards {
+		folderToTitles[folder] = []string{}
+		for _, board := range boards {
+			folderToTitles[folder] = append(folderToTitles[folder], board.Title)
+		}
+	}
+	expected := map[string][]string{
+		"":              {"Home"},
+		"library":       {"Authors", "Books"},
+		"library/shelf": {"Racks"},
+		// Directories without dashboards are still synced, so that they can be pruned.
+		"archive": {},
+	}
+	require.Equal(t, expected, folderToTitles)
+}
+
+func TestSyncDirectory(t *testing.T) {
+	ctx := context.Background()
+	directory := writeDirectory(t, map[string]string{
+		"home.json":          boardJSON(t, "home", "Home"),
+		"library/books.json": boardJSON(t, "books", "Books"),
+	})
+
+	t.Run("DryRunDoesNotModifyGrafana", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		result, err := client.SyncDirectory(ctx, directory, SyncOpts{DryRun: true, Prune: true})
+		require.NoError(t, err)
+		require.True(t, result.HasChanges())
+		require.Len(t, result.Plans, 2)
+		require.Empty(t, grafana.mutations)
+	})
+
+	t.Run("UploadsDashboardsToTheirFolders", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		result, err := client.SyncDirectory(ctx, directory, SyncOpts{})
+		require.NoError(t, err)
+		require.True(t, result.HasChanges())
+		require.Equal(t, "General/Home", result.Plans[0].Name())
+		require.Equal(t, "library/Books", result.Plans[1].Name())
+		expected := map[string]int{"Home": sdk.DefaultFolderId, "Books": grafana.folderID("library")}
+		require.Equal(t, expected, grafana.dashboardFolderIDs())
+
+		result, err = client.SyncDirectory(ctx, directory, SyncOpts{})
+		require.NoError(t, err)
+		require.False(t, result.HasChanges())
+	})
+
+	t.Run("PrunesStaleDashboardsOfSyncedFolders", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		_, err := client.UploadDashboard(ctx, &sdk.Board{UID: "stale", Title: "Stale"}, "library")
+		require.NoError(t, err)
+		_, err = client.UploadDashboard(ctx, &sdk.Board{UID: "unsynced", Title: "Unsynced"}, "team")
+		require.NoError(t, err)
+
+		result, err := client.SyncDirectory(ctx, directory, SyncOpts{Prune: true, DryRun: true})
+		require.NoError(t, err)
+		require.Len(t, result.Pruned, 1)
+		require.Equal(t, "Stale", result.Pruned[0].Title)
+		require.Contains(t, grafana.dashboardFolderIDs(), "Stale")
+
+		result, err = client.SyncDirectory(ctx, directory, SyncOpts{Prune: true})
+		require.NoError(t, err)
+		require.Len(t, result.Pruned, 1)
+		titleToFolderID := grafana.dashboardFolderIDs()
+		require.NotContains(t, titleToFolderID, "Stale")
+		require.Contains(t, titleToFolderID, "Books")
+		require.Contains(t, titleToFolderID, "Unsynced")
+		require.Empty(t, result.PrunedFolders)
+	})
+
+	t.Run("PrunesFoldersLeftWithoutDashboards", func(t *testing.T) {
+		client, grafana := newTestClient(t)
+		_, err := client.UploadDashboard(ctx, &sdk.Board{UID: "old", Title: "Old"}, "archive")
+		require.NoError(t, err)
+		directory := writeDirectory(t, map[string]string{
+			"home.json":             boardJSON(t, "home", "Home"),
+			"archive/lib.libsonnet": "{}",
+		})
+
+		result, err := client.SyncDirectory(ctx, directory, SyncOpts{Prune: true, DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"archive"}, result.PrunedFolders)
+		require.NotEqual(t, -1, grafana.folderID("archive"))
+
+		result, err = client.SyncDirectory(ctx, directory, SyncOpts{Prune: true})
+		require.NoError(t, err)
+		require.Len(t, result.Pruned, 1)
+		require.Equal(t, []string{"archive"}, result.PrunedFolders)
+		require.Equal(t, -1, grafana.folderID("archive"))
+		require.Equal(t, map[string]int{"Home": sdk.DefaultFolderId}, grafana.dashboardFolderIDs())
+	})
+}
@@ -0,0 +1,327 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	apiKeyMetadataKey        = "x-api-key"
+	authorizationMetadataKey = "authorization"
+	bearerPrefix             = "bearer "
+	healthServicePrefix      = "/grpc.health.v1.Health/"
+)
+
+// ErrNoCredentials is returned by authenticators when an RPC carries none of the credentials they handle.
+var ErrNoCredentials = errors.New("no credentials")
+
+// AuthOpts holds authentication opts. Authentication is disabled unless an API key or a JWT issuer is configured.
+type AuthOpts struct {
+	APIKeys     map[string]string `long:"auth-api-key" description:"Static API key allowed to call this server, as key:subject. Can be repeated."`
+	JWTIssuer   string            `long:"auth-jwt-issuer" description:"OIDC issuer whose JWTs are accepted, e.g. https://accounts.google.com."`
+	JWTAudience string            `long:"auth-jwt-audience" description:"Audience JWTs must be issued for."`
+	JWKSURL     string            `long:"auth-jwks-url" description:"Overrides the JWKS url, which is otherwise discovered from the issuer."`
+}
+
+// Caller is the authenticated identity of an RPC's caller.
+type Caller struct {
+	// Subject identifies the caller, e.g. the `sub` claim of a JWT or the subject of an API key.
+	Subject string
+	// Claims holds the JWT claims of callers authenticated with a JWT.
+	Claims map[string]any
+}
+
+type callerContextKey struct{}
+
+// ContextWithCaller returns a context holding the given caller.
+func ContextWithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFromContext returns the caller authenticated by the auth interceptors.
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerContextKey{}).(*Caller)
+	return caller, ok
+}
+
+// Authenticator authenticates the caller of an RPC from its incoming metadata.
+// It returns `ErrNoCredentials` if the RPC carries none of the credentials it handles.
+type Authenticator interface {
+	Authenticate(ctx context.Context, md metadata.MD) (*Caller, error)
+}
+
+// APIKeyAuthenticator authenticates callers presenting a static API key in the `x-api-key` metadata.
+type APIKeyAuthenticator struct {
+	keyToSubject map[string]string
+}
+
+// NewAPIKeyAuthenticator instantiates and returns a new API key authenticator from key to subject pairs.
+func NewAPIKeyAuthenticator(keyToSubject map[string]string) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{keyToSubject: keyToSubject}
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, md metadata.MD) (*Caller, error) {
+	values := md.Get(apiKeyMetadataKey)
+	if len(values) == 0 {
+		return nil, ErrNoCredentials
+	}
+	// Compare against every key in constant time, to avoid leaking keys through timing.
+	var subject string
+	for key, keySubject := range a.keyToSubject {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(values[0])) == 1 {
+			subject = keySubject
+		}
+	}
+	if subject == "" {
+		return nil, errors.New("invalid api key")
+	}
+	return &Caller{Subject: subject}, nil
+}
+
+// ParentScopeFN returns the resource parents a caller may access, e.g. "organizations/1". A request is only allowed
+// if every resource it names lives under one of these parents. See `resourceNames` for how resources are resolved.
+type ParentScopeFN func(caller *Caller) []string
+
+// Auth authenticates RPCs and authorizes them against a per-method policy.
+// By default, every method requires an authenticated caller, apart from the grpc health service.
+type Auth struct {
+	authenticators  []Authenticator
+	publicMethods   map[string]struct{}
+	methodToSubject map[string]map[string]struct{}
+	parentScopeFN   ParentScopeFN
+	unscopedMethods map[string]struct{}
+}
+
+// NewAuth instantiates and returns a new auth from opts. It returns nil if no authentication is configured.
+func NewAuth(opts AuthOpts) (*Auth, error) {
+	auth := &Auth{
+		publicMethods:   map[string]struct{}{},
+		methodToSubject: map[string]map[string]struct{}{},
+		unscopedMethods: map[string]struct{}{},
+	}
+	if len(opts.APIKeys) > 0 {
+		auth.authenticators = append(auth.authenticators, NewAPIKeyAuthenticator(opts.APIKeys))
+	}
+	if opts.JWTIssuer != "" {
+		jwtAuthenticator, err := NewJWTAuthenticator(opts.JWTIssuer, opts.JWTAudience, opts.JWKSURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "instantiating jwt authenticator")
+		}
+		auth.authenticators = append(auth.authenticators, jwtAuthenticator)
+	}
+	if len(auth.authenticators) == 0 {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// MustNewAuth calls NewAuth and panics on error.
+func MustNewAuth(opts AuthOpts) *Auth {
+	auth, err := NewAuth(opts)
+	if err != nil {
+		log.Panic(err)
+	}
+	return auth
+}
+
+// WithAuthenticators adds authenticators. Authenticators are tried in order.
+func (a *Auth) WithAuthenticators(authenticators ...Authenticator) *Auth {
+	a.authenticators = append(a.authenticators, authenticators...)
+	return a
+}
+
+// WithPublicMethods allows the given full methods to be called without credentials.
+func (a *Auth) WithPublicMethods(fullMethods ...string) *Auth {
+	for _, fullMethod := range fullMethods {
+		a.publicMethods[fullMethod] = struct{}{}
+	}
+	return a
+}
+
+// WithMethodSubjects restricts the given full method to the given subjects.
+func (a *Auth) WithMethodSubjects(fullMethod string, subjects ...string) *Auth {
+	if _, ok := a.methodToSubject[fullMethod]; !ok {
+		a.methodToSubject[fullMethod] = map[string]struct{}{}
+	}
+	for _, subject := range subjects {
+		a.methodToSubject[fullMethod][subject] = struct{}{}
+	}
+	return a
+}
+
+// WithParentScope restricts callers to resources living under the parents returned by the given function.
+// Requests naming no resource are rejected, unless their method is declared with `WithUnscopedMethods`.
+func (a *Auth) WithParentScope(parentScopeFN ParentScopeFN) *Auth {
+	a.parentScopeFN = parentScopeFN
+	return a
+}
+
+// WithUnscopedMethods exempts the given full methods from parent scoping, e.g. methods acting on no resource.
+func (a *Auth) WithUnscopedMethods(fullMethods ...string) *Auth {
+	for _, fullMethod := range fullMethods {
+		a.unscopedMethods[fullMethod] = struct{}{}
+	}
+	return a
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that authenticates and authorizes RPCs.
+func (a *Auth) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.authorizeRequest(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that authenticates and authorizes streams.
+// Parent scoping applies to every message received on the stream.
+func (a *Auth) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authServerStream{ServerStream: stream, ctx: ctx, auth: a, method: info.FullMethod})
+	}
+}
+
+// authenticate returns a context holding the caller, or an error if the caller is not allowed to call the method.
+func (a *Auth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	_, isPublic := a.publicMethods[method]
+	if isPublic || strings.HasPrefix(method, healthServicePrefix) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var caller *Caller
+	for _, authenticator := range a.authenticators {
+		var err error
+		caller, err = authenticator.Authenticate(ctx, md)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "authenticating: %v", err)
+		}
+		break
+	}
+	if caller == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if subjects, ok := a.methodToSubject[method]; ok {
+		if _, ok := subjects[caller.Subject]; !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "%s may not call %s", caller.Subject, method)
+		}
+	}
+	return ContextWithCaller(ctx, caller), nil
+}
+
+// authorizeRequest enforces the parent scope on requests. It fails closed: requests whose resources cannot be
+// resolved are rejected.
+func (a *Auth) authorizeRequest(ctx context.Context, method string, req any) error {
+	caller, ok := CallerFromContext(ctx)
+	if a.parentScopeFN == nil || !ok {
+		return nil
+	}
+	if _, ok := a.unscopedMethods[method]; ok {
+		return nil
+	}
+	message, ok := req.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "cannot resolve the resources of %T", req)
+	}
+	resources := resourceNames(message.ProtoReflect())
+	if len(resources) == 0 {
+		return status.Errorf(codes.PermissionDenied, "cannot resolve the resources of %s", message.ProtoReflect().Descriptor().FullName())
+	}
+	parents := a.parentScopeFN(caller)
+	for _, resource := range resources {
+		if !isUnderAnyParent(resource, parents) {
+			return status.Errorf(codes.PermissionDenied, "%s may not access %s", caller.Subject, resource)
+		}
+	}
+	return nil
+}
+
+func isUnderAnyParent(resource string, parents []string) bool {
+	for _, parent := range parents {
+		if resource == parent || strings.HasPrefix(resource, strings.TrimSuffix(parent, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceNames returns the names of the resources a request targets, following AIP conventions:
+//   - its `parent` and `name` fields (Get, List, Create, Delete and custom methods).
+//   - the `name` of its resource fields (e.g. `book.name` of an Update).
+//   - its `names` field (BatchGet), and the resources of its `requests` (batch methods).
+func resourceNames(message protoreflect.Message) []string {
+	var names []string
+	appendName := func(name string) {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	fields := message.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		switch {
+		case field.IsMap():
+			continue
+		case field.IsList():
+			list := message.Get(field).List()
+			for j := 0; j < list.Len(); j++ {
+				switch {
+				case field.Kind() == protoreflect.StringKind && field.Name() == "names":
+					appendName(list.Get(j).String())
+				case field.Kind() == protoreflect.MessageKind && field.Name() == "requests":
+					names = append(names, resourceNames(list.Get(j).Message())...)
+				}
+			}
+		case field.Kind() == protoreflect.StringKind && (field.Name() == "parent" || field.Name() == "name"):
+			appendName(message.Get(field).String())
+		case field.Kind() == protoreflect.MessageKind && message.Has(field):
+			nameField := field.Message().Fields().ByName("name")
+			if nameField != nil && nameField.Kind() == protoreflect.StringKind && !nameField.IsList() {
+				appendName(message.Get(field).Message().Get(nameField).String())
+			}
+		}
+	}
+	return names
+}
+
+// authServerStream holds the authenticated context, and authorizes every message it receives.
+type authServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	auth   *Auth
+	method string
+}
+
+// Context implements the grpc.ServerStream interface.
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// RecvMsg implements the grpc.ServerStream interface.
+func (s *authServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.auth.authorizeRequest(s.ctx, s.method, m)
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	jwtLeeway = time.Minute
+	// Signing keys are refetched at most this often, including when a token uses an unknown key.
+	jwksMinRefreshInterval = time.Minute
+	jwksFetchTimeout       = 10 * time.Second
+)
+
+// JWTAuthenticator authenticates callers presenting a JWT bearer token in the `authorization` metadata.
+// Tokens must be signed by one of the issuer's keys, published in its JWKS.
+type JWTAuthenticator struct {
+	issuer     string
+	audience   string
+	jwksURL    string
+	httpClient *http.Client
+
+	jwksMutex     sync.RWMutex
+	jwks          *jose.JSONWebKeySet
+	jwksFetchTime time.Time
+}
+
+// NewJWTAuthenticator instantiates and returns a new JWT authenticator. If the JWKS url is empty,
+// it is discovered from the issuer's OIDC configuration.
+func NewJWTAuthenticator(issuer, audience, jwksURL string) (*JWTAuthenticator, error) {
+	authenticator := &JWTAuthenticator{
+		issuer:     issuer,
+		audience:   audience,
+		jwksURL:    jwksURL,
+		httpClient: &http.Client{Timeout: jwksFetchTimeout},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	if authenticator.jwksURL == "" {
+		if err := authenticator.discoverJWKSURL(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := authenticator.refreshJWKS(ctx); err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, md metadata.MD) (*Caller, error) {
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 || !strings.HasPrefix(strings.ToLower(values[0]), bearerPrefix) {
+		return nil, ErrNoCredentials
+	}
+	token, err := jwt.ParseSigned(values[0][len(bearerPrefix):])
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing jwt")
+	}
+	if len(token.Headers) == 0 {
+		return nil, errors.New("jwt has no header")
+	}
+	key, err := a.getKey(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.Claims{}
+	allClaims := map[string]any{}
+	if err := token.Claims(key, &claims, &allClaims); err != nil {
+		return nil, errors.Wrap(err, "verifying jwt")
+	}
+	expected := jwt.Expected{Issuer: a.issuer, Time: time.Now()}
+	if a.audience != "" {
+		expected.Audience = jwt.Audience{a.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, jwtLeeway); err != nil {
+		return nil, errors.Wrap(err, "validating jwt claims")
+	}
+	// Policies match callers by subject, so callers without one cannot be authenticated.
+	if claims.Subject == "" {
+		return nil, errors.New("jwt has no subject")
+	}
+	return &Caller{Subject: claims.Subject, Claims: allClaims}, nil
+}
+
+// getKey returns the signing key with the given id, refetching the JWKS if the key is unknown, as issuers rotate keys.
+func (a *JWTAuthenticator) getKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	a.jwksMutex.RLock()
+	keys := a.jwks.Key(keyID)
+	canRefresh := time.Since(a.jwksFetchTime) > jwksMinRefreshInterval
+	a.jwksMutex.RUnlock()
+	if len(keys) == 0 && canRefresh {
+		if err := a.refreshJWKS(ctx); err != nil {
+			return nil, err
+		}
+		a.jwksMutex.RLock()
+		keys = a.jwks.Key(keyID)
+		a.jwksMutex.RUnlock()
+	}
+	if len(keys) == 0 {
+		return nil, errors.Errorf("unknown signing key %q", keyID)
+	}
+	return &keys[0], nil
+}
+
+func (a *JWTAuthenticator) discoverJWKSURL(ctx context.Context) error {
+	configuration := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	url := strings.TrimSuffix(a.issuer, "/") + "/.well-known/openid-configuration"
+	if err := a.getJSON(ctx, url, &configuration); err != nil {
+		return errors.Wrap(err, "fetching openid configuration")
+	}
+	if configuration.JWKSURI == "" {
+		return errors.Errorf("openid configuration @%s has no jwks_uri", url)
+	}
+	a.jwksURL = configuration.JWKSURI
+	return nil
+}
+
+func (a *JWTAuthenticator) refreshJWKS(ctx context.Context) error {
+	jwks := &jose.JSONWebKeySet{}
+	if err := a.getJSON(ctx, a.jwksURL, jwks); err != nil {
+		return errors.Wrap(err, "fetching jwks")
+	}
+	a.jwksMutex.Lock()
+	defer a.jwksMutex.Unlock()
+	a.jwks = jwks
+	a.jwksFetchTime = time.Now()
+	return nil
+}
+
+func (a *JWTAuthenticator) getJSON(ctx context.Context, url string, v any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s", url)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("getting %s: status %d", url, response.StatusCode)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "decoding %s", url)
+	}
+	return nil
+}
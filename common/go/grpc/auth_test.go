@@ -0,0 +1,257 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Request shapes following AIP conventions, used to exercise parent scoping.
+const authTestFileDescriptor = `
+name: "auth_test.proto"
+package: "test"
+syntax: "proto3"
+message_type { name: "Book" field { name: "name" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "name" } }
+message_type { name: "GetBookRequest" field { name: "name" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "name" } }
+message_type { name: "ListBooksRequest" field { name: "parent" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "parent" } }
+message_type { name: "UpdateBookRequest" field { name: "book" number: 1 type: TYPE_MESSAGE type_name: ".test.Book" label: LABEL_OPTIONAL json_name: "book" } }
+message_type {
+  name: "BatchGetBooksRequest"
+  field { name: "parent" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "parent" }
+  field { name: "names" number: 2 type: TYPE_STRING label: LABEL_REPEATED json_name: "names" }
+}
+message_type { name: "BatchUpdateBooksRequest" field { name: "requests" number: 1 type: TYPE_MESSAGE type_name: ".test.UpdateBookRequest" label: LABEL_REPEATED json_name: "requests" } }
+message_type { name: "PingRequest" }
+`
+
+// newTestRequest returns a message of the given type of `authTestFileDescriptor`, unmarshaled from the given JSON.
+func newTestRequest(t *testing.T, messageName, json string) proto.Message {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(authTestFileDescriptor), fileDescriptorProto))
+	fileDescriptor, err := protodesc.NewFile(fileDescriptorProto, nil)
+	require.NoError(t, err)
+	messageDescriptor := fileDescriptor.Messages().ByName(protoreflect.Name(messageName))
+	require.NotNil(t, messageDescriptor, messageName)
+	message := dynamicpb.NewMessage(messageDescriptor)
+	require.NoError(t, protojson.Unmarshal([]byte(json), message))
+	return message
+}
+
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+// newTestIssuer starts an OIDC issuer publishing the JWKS of a single signing key, "key-1".
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T, key *rsa.PrivateKey, keyID string, claims jwt.Claims) metadata.MD {
+	signingKey := jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: keyID}}
+	signer, err := jose.NewSigner(signingKey, (&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Claims(map[string]any{"org": "1"}).CompactSerialize()
+	require.NoError(t, err)
+	return metadata.Pairs(authorizationMetadataKey, "Bearer "+token)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t)
+	authenticator, err := NewJWTAuthenticator(issuer.server.URL, "library", "")
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Now()
+	validClaims := jwt.Claims{
+		Issuer:   issuer.server.URL,
+		Subject:  "user-1",
+		Audience: jwt.Audience{"library"},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	caller, err := authenticator.Authenticate(ctx, issuer.sign(t, issuer.key, "key-1", validClaims))
+	require.NoError(t, err)
+	require.Equal(t, "user-1", caller.Subject)
+	require.Equal(t, "1", caller.Claims["org"])
+
+	_, err = authenticator.Authenticate(ctx, metadata.MD{})
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	_, err = authenticator.Authenticate(ctx, metadata.Pairs(authorizationMetadataKey, "Bearer not-a-jwt"))
+	require.ErrorContains(t, err, "parsing jwt")
+
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, otherKey, "key-1", validClaims))
+	require.ErrorContains(t, err, "verifying jwt")
+
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, otherKey, "key-2", validClaims))
+	require.ErrorContains(t, err, `unknown signing key "key-2"`)
+
+	expiredClaims := validClaims
+	expiredClaims.Expiry = jwt.NewNumericDate(now.Add(-time.Hour))
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, issuer.key, "key-1", expiredClaims))
+	require.ErrorContains(t, err, "validating jwt claims")
+
+	wrongIssuerClaims := validClaims
+	wrongIssuerClaims.Issuer = "https://other.example.com"
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, issuer.key, "key-1", wrongIssuerClaims))
+	require.ErrorContains(t, err, "validating jwt claims")
+
+	wrongAudienceClaims := validClaims
+	wrongAudienceClaims.Audience = jwt.Audience{"other"}
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, issuer.key, "key-1", wrongAudienceClaims))
+	require.ErrorContains(t, err, "validating jwt claims")
+
+	noSubjectClaims := validClaims
+	noSubjectClaims.Subject = ""
+	_, err = authenticator.Authenticate(ctx, issuer.sign(t, issuer.key, "key-1", noSubjectClaims))
+	require.ErrorContains(t, err, "jwt has no subject")
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	authenticator := NewAPIKeyAuthenticator(map[string]string{"key-1": "alice"})
+
+	caller, err := authenticator.Authenticate(ctx, metadata.Pairs(apiKeyMetadataKey, "key-1"))
+	require.NoError(t, err)
+	require.Equal(t, "alice", caller.Subject)
+
+	_, err = authenticator.Authenticate(ctx, metadata.Pairs(apiKeyMetadataKey, "key-2"))
+	require.ErrorContains(t, err, "invalid api key")
+
+	_, err = authenticator.Authenticate(ctx, metadata.MD{})
+	require.ErrorIs(t, err, ErrNoCredentials)
+}
+
+// callWithAuth calls the given method through the auth's unary interceptor, returning the caller seen by the handler.
+func callWithAuth(auth *Auth, method, apiKey string, req any) (*Caller, error) {
+	ctx := context.Background()
+	if apiKey != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(apiKeyMetadataKey, apiKey))
+	}
+	var caller *Caller
+	handler := func(ctx context.Context, req any) (any, error) {
+		caller, _ = CallerFromContext(ctx)
+		return nil, nil
+	}
+	_, err := auth.UnaryServerInterceptor()(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	return caller, err
+}
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	t.Helper()
+	require.Equal(t, code, status.Code(err), "%v", err)
+}
+
+func TestAuthPolicy(t *testing.T) {
+	auth, err := NewAuth(AuthOpts{APIKeys: map[string]string{"key-alice": "alice", "key-bob": "bob"}})
+	require.NoError(t, err)
+	auth.WithPublicMethods("/test.Library/Public").WithMethodSubjects("/test.Library/Admin", "alice")
+	request := newTestRequest(t, "PingRequest", `{}`)
+
+	caller, err := callWithAuth(auth, "/test.Library/Public", "", request)
+	require.NoError(t, err)
+	require.Nil(t, caller)
+	_, err = callWithAuth(auth, "/grpc.health.v1.Health/Check", "", request)
+	require.NoError(t, err)
+
+	_, err = callWithAuth(auth, "/test.Library/Get", "", request)
+	requireCode(t, codes.Unauthenticated, err)
+	_, err = callWithAuth(auth, "/test.Library/Get", "key-unknown", request)
+	requireCode(t, codes.Unauthenticated, err)
+	caller, err = callWithAuth(auth, "/test.Library/Get", "key-bob", request)
+	require.NoError(t, err)
+	require.Equal(t, "bob", caller.Subject)
+
+	_, err = callWithAuth(auth, "/test.Library/Admin", "key-bob", request)
+	requireCode(t, codes.PermissionDenied, err)
+	caller, err = callWithAuth(auth, "/test.Library/Admin", "key-alice", request)
+	require.NoError(t, err)
+	require.Equal(t, "alice", caller.Subject)
+
+	// Nothing configured means no authentication.
+	auth, err = NewAuth(AuthOpts{})
+	require.NoError(t, err)
+	require.Nil(t, auth)
+}
+
+func TestAuthParentScope(t *testing.T) {
+	auth, err := NewAuth(AuthOpts{APIKeys: map[string]string{"key-alice": "alice"}})
+	require.NoError(t, err)
+	auth.WithParentScope(func(caller *Caller) []string { return []string{"organizations/1"} })
+
+	for _, testCase := range []struct {
+		messageName  string
+		json         string
+		expectedCode codes.Code
+	}{
+		{messageName: "GetBookRequest", json: `{"name": "organizations/1/books/1"}`, expectedCode: codes.OK},
+		{messageName: "GetBookRequest", json: `{"name": "organizations/2/books/1"}`, expectedCode: codes.PermissionDenied},
+		{messageName: "GetBookRequest", json: `{"name": "organizations/10/books/1"}`, expectedCode: codes.PermissionDenied},
+		{messageName: "ListBooksRequest", json: `{"parent": "organizations/1"}`, expectedCode: codes.OK},
+		{messageName: "ListBooksRequest", json: `{"parent": "organizations/2"}`, expectedCode: codes.PermissionDenied},
+		{messageName: "UpdateBookRequest", json: `{"book": {"name": "organizations/1/books/1"}}`, expectedCode: codes.OK},
+		{messageName: "UpdateBookRequest", json: `{"book": {"name": "organizations/2/books/1"}}`, expectedCode: codes.PermissionDenied},
+		{messageName: "UpdateBookRequest", json: `{"book": {}}`, expectedCode: codes.PermissionDenied},
+		{
+			messageName:  "BatchGetBooksRequest",
+			json:         `{"parent": "organizations/1", "names": ["organizations/1/books/1", "organizations/2/books/1"]}`,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			messageName:  "BatchUpdateBooksRequest",
+			json:         `{"requests": [{"book": {"name": "organizations/1/books/1"}}, {"book": {"name": "organizations/1/books/2"}}]}`,
+			expectedCode: codes.OK,
+		},
+		{
+			messageName:  "BatchUpdateBooksRequest",
+			json:         `{"requests": [{"book": {"name": "organizations/1/books/1"}}, {"book": {"name": "organizations/2/books/1"}}]}`,
+			expectedCode: codes.PermissionDenied,
+		},
+		// Requests whose resources cannot be resolved are rejected.
+		{messageName: "PingRequest", json: `{}`, expectedCode: codes.PermissionDenied},
+	} {
+		_, err := callWithAuth(auth, "/test.Library/Method", "key-alice", newTestRequest(t, testCase.messageName, testCase.json))
+		requireCode(t, testCase.expectedCode, err)
+	}
+
+	auth.WithUnscopedMethods("/test.Library/Ping")
+	_, err = callWithAuth(auth, "/test.Library/Ping", "key-alice", newTestRequest(t, "PingRequest", `{}`))
+	require.NoError(t, err)
+}
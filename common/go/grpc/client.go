@@ -2,7 +2,9 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/bufbuild/protovalidate-go"
@@ -13,8 +15,11 @@ import (
 	"google.golang.org/grpc/balancer/roundrobin"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/health" // Registers client-side health checking.
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
@@ -30,6 +35,8 @@ const (
 	retryBackoff = 100 * time.Millisecond
 	// defaultTimeout defines the default client timeout for RPCs.
 	defaultTimeout = 10 * time.Second
+	// staticResolverScheme is the scheme of the resolver used by clients with extra targets.
+	staticResolverScheme = "static"
 )
 
 var (
@@ -71,7 +78,7 @@ func NewClient(opts Opts, certsOpts certs.Opts, prometheusOpts prometheus.Opts)
 	}
 
 	// Default options.
-	client.options = append(client.options, grpc.WithMaxMsgSize(maximumMessageSize), withDNSBalancer())
+	client.options = append(client.options, grpc.WithMaxMsgSize(maximumMessageSize), withServiceConfig(opts))
 	if opts.DisableTLS {
 		if !DisableLogging {
 			log.Warningf("Starting gRPC client using insecure gRPC dial")
@@ -82,6 +89,9 @@ func NewClient(opts Opts, certsOpts certs.Opts, prometheusOpts prometheus.Opts)
 		if err != nil {
 			log.Panicf("Could not load client TLS config: %v", err)
 		}
+		if opts.TLSServerName != "" {
+			tlsConfig.ServerName = opts.TLSServerName
+		}
 		client.options = append(client.options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	}
 
@@ -91,7 +101,7 @@ func NewClient(opts Opts, certsOpts certs.Opts, prometheusOpts prometheus.Opts)
 		client.streamInterceptors = append(client.streamInterceptors, grpc_prometheus.StreamClientInterceptor)
 		grpc_prometheus.EnableClientHandlingTimeHistogram()
 	}
-	client.unaryInterceptors = append(client.unaryInterceptors, unaryClientValidateInterceptor(), withTimeout, withUnaryRetry())
+	client.unaryInterceptors = append(client.unaryInterceptors, unaryClientValidateInterceptor(), withTimeout, unaryClientRetryPolicyInterceptor(opts), withUnaryRetry())
 	return client
 }
 
@@ -136,7 +146,7 @@ func (c *Client) Connect() (*grpc.ClientConn, health.Check) {
 	}
 
 	// Connect.
-	url := fmt.Sprintf("%s:%d", c.opts.Host, c.opts.Port)
+	url := c.target()
 	connection, err := grpc.Dial(url, c.options...)
 	if err != nil {
 		log.Panicf("Failed to dial grpc [%s]: %v", url, err)
@@ -148,6 +158,39 @@ func (c *Client) Connect() (*grpc.ClientConn, health.Check) {
 	return c.connection, c.HealthCheck
 }
 
+// target returns the target to dial. If this client has extra targets, it registers a static resolver
+// that load balances across all of them.
+func (c *Client) target() string {
+	hostPort := fmt.Sprintf("%s:%d", c.opts.Host, c.opts.Port)
+	if len(c.opts.Targets) == 0 {
+		if c.opts.Resolver == "" {
+			return hostPort
+		}
+		return fmt.Sprintf("%s:///%s", c.opts.Resolver, hostPort)
+	}
+	if c.opts.Resolver != "" {
+		log.Panicf("cannot use resolver [%s] with extra targets", c.opts.Resolver)
+	}
+
+	hostPorts := c.opts.Targets
+	if c.opts.Host != "" {
+		hostPorts = append([]string{hostPort}, hostPorts...)
+	}
+	addresses := make([]resolver.Address, 0, len(hostPorts))
+	for _, hostPort := range hostPorts {
+		host, _, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			log.Panicf("invalid target [%s]: %v", hostPort, err)
+		}
+		// Each backend's certificate is verified against its own host, unless the server name is overridden.
+		addresses = append(addresses, resolver.Address{Addr: hostPort, ServerName: host})
+	}
+	builder := manual.NewBuilderWithScheme(staticResolverScheme)
+	builder.InitialState(resolver.State{Addresses: addresses})
+	c.options = append(c.options, grpc.WithResolvers(builder))
+	return fmt.Sprintf("%s:///%s", staticResolverScheme, hostPorts[0])
+}
+
 // HealthCheck calls the `Check` method of the grpc server.
 func (c *Client) HealthCheck(ctx context.Context) error {
 	healthClient := grpc_health_v1.NewHealthClient(c.connection)
@@ -180,12 +223,34 @@ func withStreamRetry() grpc.StreamClientInterceptor {
 	)
 }
 
-// withDNSBalancer returns gRPC DialOption that does client-side load balancing based on DNS.
-func withDNSBalancer() grpc.DialOption {
+// serviceConfig is a gRPC client service config.
+// See https://github.com/grpc/grpc/blob/master/doc/service_config.md.
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]any   `json:"loadBalancingConfig"`
+	HealthCheckConfig   *healthCheckConfig `json:"healthCheckConfig,omitempty"`
+}
+
+type healthCheckConfig struct {
+	// An empty service name checks the health of the server as a whole.
+	ServiceName string `json:"serviceName"`
+}
+
+// withServiceConfig returns gRPC DialOption that does client-side round robin load balancing across all resolved
+// addresses, optionally skipping the ones that do not report SERVING.
+func withServiceConfig(opts Opts) grpc.DialOption {
 	// Must set the grpc server address resolver to dns.
 	kuberesolver.RegisterInCluster()
-	serviceConfig := fmt.Sprintf(`{"loadBalancingConfig": [{"%s":{}}]}`, roundrobin.Name)
-	return grpc.WithDefaultServiceConfig(serviceConfig)
+	config := &serviceConfig{
+		LoadBalancingConfig: []map[string]any{{roundrobin.Name: struct{}{}}},
+	}
+	if opts.HealthCheck {
+		config.HealthCheckConfig = &healthCheckConfig{}
+	}
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		log.Panicf("could not marshal service config: %v", err)
+	}
+	return grpc.WithDefaultServiceConfig(string(bytes))
 }
 
 func withTimeout(
@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"common/go/certs"
+	"common/go/prometheus"
+)
+
+// countingHealthServer counts the checks it answers and reports the given status to health watches.
+func countingHealthServer(servingStatus grpc_health_v1.HealthCheckResponse_ServingStatus) (*healthServer, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	return &healthServer{
+		check: func(context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+		watch: func(stream grpc_health_v1.Health_WatchServer) error {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			<-stream.Context().Done()
+			return nil
+		},
+	}, calls
+}
+
+// withTestBackends serves each health server over an in-memory connection, and returns a dial option that routes
+// each address to its server.
+func withTestBackends(t *testing.T, addressToServer map[string]*healthServer) grpc.DialOption {
+	addressToListener := map[string]*bufconn.Listener{}
+	for address, server := range addressToServer {
+		listener := bufconn.Listen(1024 * 1024)
+		grpcServer := grpc.NewServer()
+		grpc_health_v1.RegisterHealthServer(grpcServer, server)
+		go grpcServer.Serve(listener)
+		t.Cleanup(grpcServer.Stop)
+		addressToListener[address] = listener
+	}
+	return grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+		return addressToListener[address].DialContext(ctx)
+	})
+}
+
+func newTestClient(t *testing.T, opts Opts, options ...grpc.DialOption) grpc_health_v1.HealthClient {
+	opts.DisableTLS = true
+	client := NewClient(opts, certs.Opts{}, prometheus.Opts{Disable: true}).WithOptions(options...)
+	connection, _ := client.Connect()
+	t.Cleanup(func() { connection.Close() })
+	return grpc_health_v1.NewHealthClient(connection)
+}
+
+func TestClientTarget(t *testing.T) {
+	t.Run("HostPort", func(t *testing.T) {
+		client := &Client{opts: Opts{Host: "books", Port: 9090}}
+		require.Equal(t, "books:9090", client.target())
+		require.Empty(t, client.options)
+	})
+
+	t.Run("Resolver", func(t *testing.T) {
+		client := &Client{opts: Opts{Host: "books", Port: 9090, Resolver: "dns"}}
+		require.Equal(t, "dns:///books:9090", client.target())
+	})
+
+	t.Run("ExtraTargets", func(t *testing.T) {
+		client := &Client{opts: Opts{Host: "books", Port: 9090, Targets: []string{"authors:9090"}}}
+		require.Equal(t, "static:///books:9090", client.target())
+		// The static resolver is registered on the client's connection only.
+		require.Len(t, client.options, 1)
+	})
+
+	t.Run("ExtraTargetsOnly", func(t *testing.T) {
+		client := &Client{opts: Opts{Targets: []string{"authors:9090", "shelves:9090"}}}
+		require.Equal(t, "static:///authors:9090", client.target())
+	})
+
+	t.Run("ExtraTargetsWithResolver", func(t *testing.T) {
+		client := &Client{opts: Opts{Host: "books", Port: 9090, Resolver: "dns", Targets: []string{"authors:9090"}}}
+		require.Panics(t, func() { client.target() })
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		client := &Client{opts: Opts{Targets: []string{"authors"}}}
+		require.Panics(t, func() { client.target() })
+	})
+}
+
+func TestClientLoadBalancing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RoundRobinsAcrossTargets", func(t *testing.T) {
+		books, booksCalls := countingHealthServer(grpc_health_v1.HealthCheckResponse_SERVING)
+		authors, authorsCalls := countingHealthServer(grpc_health_v1.HealthCheckResponse_SERVING)
+		backends := withTestBackends(t, map[string]*healthServer{"books:9090": books, "authors:9090": authors})
+		client := newTestClient(t, Opts{Host: "books", Port: 9090, Targets: []string{"authors:9090"}}, backends)
+
+		// Backends are picked as their connections become ready.
+		require.Eventually(t, func() bool {
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+			require.NoError(t, err)
+			return booksCalls.Load() > 0 && authorsCalls.Load() > 0
+		}, 5*time.Second, time.Millisecond)
+	})
+
+	t.Run("SkipsBackendsThatAreNotServing", func(t *testing.T) {
+		books, booksCalls := countingHealthServer(grpc_health_v1.HealthCheckResponse_SERVING)
+		authors, authorsCalls := countingHealthServer(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		backends := withTestBackends(t, map[string]*healthServer{"books:9090": books, "authors:9090": authors})
+		client := newTestClient(t, Opts{Host: "books", Port: 9090, Targets: []string{"authors:9090"}, HealthCheck: true}, backends)
+
+		for i := 0; i < 20; i++ {
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(20), booksCalls.Load())
+		require.Zero(t, authorsCalls.Load())
+	})
+}
+
+func TestClientHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	books, _ := countingHealthServer(grpc_health_v1.HealthCheckResponse_SERVING)
+	client := NewClient(Opts{Host: "books", Port: 9090, DisableTLS: true}, certs.Opts{}, prometheus.Opts{Disable: true})
+	connection, check := client.WithOptions(withTestBackends(t, map[string]*healthServer{"books:9090": books})).Connect()
+	t.Cleanup(func() { connection.Close() })
+	require.NoError(t, check(ctx))
+}
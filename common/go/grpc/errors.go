@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/protovalidate-go"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Error is a gRPC error carrying machine-readable details (google.rpc error details), built with the `With*` methods.
+// It implements the `error` interface, and converts to a status through `status.FromError`.
+//
+//	return nil, grpc.Errorf(codes.FailedPrecondition, "book %s is checked out", name).
+//		WithErrorInfo("library.example.com", "BOOK_CHECKED_OUT", map[string]string{"book": name})
+type Error struct {
+	code         codes.Code
+	message      string
+	badRequest   *errdetails.BadRequest
+	quotaFailure *errdetails.QuotaFailure
+	details      []protoiface.MessageV1
+}
+
+// Errorf instantiates and returns a new error with the given code and formatted message.
+func Errorf(code codes.Code, format string, args ...any) *Error {
+	return &Error{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// WithFieldViolation adds a `BadRequest` field violation. Field violations are grouped in a single `BadRequest`.
+func (e *Error) WithFieldViolation(field, description string) *Error {
+	if e.badRequest == nil {
+		e.badRequest = &errdetails.BadRequest{}
+	}
+	e.badRequest.FieldViolations = append(e.badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+	return e
+}
+
+// WithValidationError adds a `BadRequest` field violation for every violation of the given protovalidate error.
+// Other errors are ignored.
+func (e *Error) WithValidationError(err error) *Error {
+	validationError := &protovalidate.ValidationError{}
+	if !errors.As(err, &validationError) {
+		return e
+	}
+	for _, violation := range validationError.Violations {
+		e.WithFieldViolation(violation.GetFieldPath(), violation.GetMessage())
+	}
+	return e
+}
+
+// WithErrorInfo adds an `ErrorInfo`, identifying the error by a reason (e.g. "BOOK_CHECKED_OUT") unique within the
+// given domain (e.g. "library.example.com"). Clients should branch on the reason, never on the message.
+func (e *Error) WithErrorInfo(domain, reason string, metadata map[string]string) *Error {
+	e.details = append(e.details, &errdetails.ErrorInfo{Domain: domain, Reason: reason, Metadata: metadata})
+	return e
+}
+
+// WithRetryInfo adds a `RetryInfo`, telling clients how long to wait before retrying.
+func (e *Error) WithRetryInfo(retryDelay time.Duration) *Error {
+	e.details = append(e.details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	return e
+}
+
+// WithQuotaViolation adds a `QuotaFailure` violation. Quota violations are grouped in a single `QuotaFailure`.
+func (e *Error) WithQuotaViolation(subject, description string) *Error {
+	if e.quotaFailure == nil {
+		e.quotaFailure = &errdetails.QuotaFailure{}
+	}
+	e.quotaFailure.Violations = append(e.quotaFailure.Violations, &errdetails.QuotaFailure_Violation{
+		Subject:     subject,
+		Description: description,
+	})
+	return e
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// GRPCStatus returns the status of this error, holding its details.
+func (e *Error) GRPCStatus() *status.Status {
+	details := e.details
+	if e.badRequest != nil {
+		details = append([]protoiface.MessageV1{e.badRequest}, details...)
+	}
+	if e.quotaFailure != nil {
+		details = append(details, e.quotaFailure)
+	}
+	return withDetails(status.New(e.code, e.message), details...)
+}
+
+// withDetails returns the given status with the given details. Details are dropped if they cannot be marshalled,
+// which only happens with a broken proto registry: the status itself remains more useful than that error.
+func withDetails(s *status.Status, details ...protoiface.MessageV1) *status.Status {
+	if len(details) == 0 {
+		return s
+	}
+	withDetails, err := s.WithDetails(details...)
+	if err != nil {
+		log.Errorf("adding details to status: %v", err)
+		return s
+	}
+	return withDetails
+}
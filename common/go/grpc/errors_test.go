@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestError(t *testing.T) {
+	err := error(Errorf(codes.InvalidArgument, "invalid book %q", "b").
+		WithFieldViolation("book.title", "must be set").
+		WithFieldViolation("book.author", "must be set").
+		WithErrorInfo("library.example.com", "INVALID_BOOK", map[string]string{"book": "b"}).
+		WithRetryInfo(time.Second).
+		WithQuotaViolation("project:1", "too many books"))
+
+	s, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, s.Code())
+	require.Equal(t, `invalid book "b"`, s.Message())
+	details := s.Details()
+	require.Len(t, details, 4)
+	require.Len(t, details[0].(*errdetails.BadRequest).GetFieldViolations(), 2)
+	require.Equal(t, "INVALID_BOOK", details[1].(*errdetails.ErrorInfo).GetReason())
+	require.Equal(t, time.Second, details[2].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+	require.Equal(t, "project:1", details[3].(*errdetails.QuotaFailure).GetViolations()[0].GetSubject())
+}
+
+func TestRequestID(t *testing.T) {
+	ctx, requestID := injectRequestID(context.Background())
+	require.NotEmpty(t, requestID)
+	contextRequestID, ok := RequestIDFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, requestID, contextRequestID)
+	md, _ := metadata.FromIncomingContext(ctx)
+	require.Equal(t, []string{requestID}, md.Get(RequestIDMetadataKey))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "request-1"))
+	_, requestID = injectRequestID(ctx)
+	require.Equal(t, "request-1", requestID)
+
+	err := withRequestInfo(Errorf(codes.NotFound, "not found").WithErrorInfo("library.example.com", "NOT_FOUND", nil), requestID)
+	s := status.Convert(err)
+	require.Equal(t, codes.NotFound, s.Code())
+	require.Len(t, s.Details(), 2)
+	require.Equal(t, "request-1", s.Details()[1].(*errdetails.RequestInfo).GetRequestId())
+	// Request info is only attached once.
+	require.Len(t, status.Convert(withRequestInfo(err, "request-2")).Details(), 2)
+	require.NoError(t, withRequestInfo(nil, requestID))
+}
@@ -3,15 +3,18 @@ package grpc
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	"common/go/certs"
@@ -50,11 +53,12 @@ func NewGateway(opts GatewayOpts, certsOpts certs.Opts, prometheusOpts prometheu
 			runtime.WithMetadata(GatewayCookie{}.forwardInOption),
 			withCustomMarshaler(),
 			withHTTPPatternAnnotation(),
+			runtime.WithErrorHandler(errorHandler),
 		},
 	}
 
 	// Default dial options.
-	gateway.dialOptions = append(gateway.dialOptions, withDNSBalancer()) // Currently makes no sense as we are dialing to localhost.
+	gateway.dialOptions = append(gateway.dialOptions, withServiceConfig(opts.GRPC)) // Currently makes no sense as we are dialing to localhost.
 	if opts.GRPC.DisableTLS {
 		log.Warningf("Starting gRPC client using insecure gRPC dial")
 		gateway.dialOptions = append(gateway.dialOptions, grpc.WithInsecure())
@@ -138,6 +142,13 @@ func (g *Gateway) Serve() {
 	}
 }
 
+// ServeWithGateway serves the given gRPC server, and a gateway transcoding REST/JSON requests to it, in the same process.
+// Blocking call.
+func ServeWithGateway(server *Server, gateway *Gateway) {
+	go gateway.Serve()
+	server.Serve()
+}
+
 // /////////////////////////////////////////////////////////////////////////////////////////
 // //////////////////////////// VARIOUS GATEWAY OPTIONS BELOW //////////////////////////////
 // /////////////////////////////////////////////////////////////////////////////////////////
@@ -218,6 +229,21 @@ func preflightHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 }
 
+// errorHandler writes gRPC errors the same way as the default handler, i.e. as a JSON status with its details.
+// On top of that, it maps RetryInfo details to a `Retry-After` header.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if s, ok := status.FromError(err); ok {
+		for _, detail := range s.Details() {
+			if retryInfo, ok := detail.(*errdetails.RetryInfo); ok && retryInfo.GetRetryDelay() != nil {
+				// Retry-After is in seconds, so we round up.
+				seconds := (retryInfo.GetRetryDelay().AsDuration() + time.Second - 1) / time.Second
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+			}
+		}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
 func withCustomMarshaler() runtime.ServeMuxOption {
 	return runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 		MarshalOptions: protojson.MarshalOptions{
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// handleError writes the given error through the gateway error handler.
+func handleError(err error) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/v1/books", nil)
+	errorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, recorder, request, err)
+	return recorder
+}
+
+func TestGatewayErrorHandler(t *testing.T) {
+	t.Run("MapsRetryInfoToRetryAfter", func(t *testing.T) {
+		recorder := handleError(Errorf(codes.ResourceExhausted, "rate limit exceeded").WithRetryInfo(1500 * time.Millisecond))
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		// Retry-After is rounded up to the second.
+		require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+
+		// The body is the JSON status with its details, as written by the default handler.
+		body := map[string]any{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		require.Equal(t, float64(codes.ResourceExhausted), body["code"])
+		require.Equal(t, "rate limit exceeded", body["message"])
+		require.Len(t, body["details"], 1)
+	})
+
+	t.Run("WholeSeconds", func(t *testing.T) {
+		recorder := handleError(Errorf(codes.Unavailable, "draining").WithRetryInfo(3 * time.Second))
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		require.Equal(t, "3", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("ErrorsWithoutRetryInfo", func(t *testing.T) {
+		recorder := handleError(Errorf(codes.NotFound, "book not found"))
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.Empty(t, recorder.Header().Get("Retry-After"))
+	})
+}
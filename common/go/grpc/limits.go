@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var requestLimitedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "grpc_server_request_limited_total",
+		Help: "RPCs rejected for exceeding a request limit",
+	},
+	[]string{"grpc_method", "limit"},
+)
+
+// Limits caps the size of requests and the length of streams. Zero values are unlimited.
+type Limits struct {
+	// Maximum size, in bytes, of a request message. For streams, this applies to each message received.
+	MaxRequestBytes int
+	// Maximum number of messages a client can send on a stream.
+	MaxStreamMessages int
+	// Maximum duration of a stream.
+	MaxStreamDuration time.Duration
+}
+
+// RequestLimiter enforces limits per full method.
+// Note that messages are checked once decoded, so the server's maximum message size remains the first line of defense.
+type RequestLimiter struct {
+	defaultLimits Limits
+	methodLimits  map[string]Limits
+}
+
+// NewRequestLimiter instantiates and returns a new request limiter. By default, no method is limited.
+func NewRequestLimiter() *RequestLimiter {
+	return &RequestLimiter{methodLimits: map[string]Limits{}}
+}
+
+// WithDefaultLimits sets the limits applied to methods without method limits.
+func (r *RequestLimiter) WithDefaultLimits(limits Limits) *RequestLimiter {
+	r.defaultLimits = limits
+	return r
+}
+
+// WithMethodLimits sets the limits of the given full method, e.g. "/library.Library/CreateBook".
+func (r *RequestLimiter) WithMethodLimits(fullMethod string, limits Limits) *RequestLimiter {
+	r.methodLimits[fullMethod] = limits
+	return r
+}
+
+func (r *RequestLimiter) getLimits(method string) Limits {
+	if limits, ok := r.methodLimits[method]; ok {
+		return limits
+	}
+	return r.defaultLimits
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that rejects requests over limit with `ResourceExhausted`.
+func (r *RequestLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkRequestBytes(info.FullMethod, r.getLimits(info.FullMethod), req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that rejects messages over limit, and ends streams
+// that send too many messages or last too long, with `ResourceExhausted`.
+func (r *RequestLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		limits := r.getLimits(info.FullMethod)
+		if limits == (Limits{}) {
+			return handler(srv, stream)
+		}
+		ctx := stream.Context()
+		if limits.MaxStreamDuration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, limits.MaxStreamDuration)
+			defer cancel()
+		}
+		wrappedStream := &limitedServerStream{
+			WrappedServerStream: grpc_middleware.WrapServerStream(stream),
+			method:              info.FullMethod,
+			limits:              limits,
+		}
+		wrappedStream.WrappedContext = ctx
+		err := handler(srv, wrappedStream)
+		// Only blame the limit if it failed the stream, and the client's own deadline did not expire first.
+		if limits.MaxStreamDuration > 0 && isDeadlineExceeded(err) && ctx.Err() == context.DeadlineExceeded && stream.Context().Err() == nil {
+			requestLimitedCounter.WithLabelValues(info.FullMethod, "stream_duration").Inc()
+			return status.Errorf(codes.ResourceExhausted, "stream exceeded its maximum duration of %s", limits.MaxStreamDuration)
+		}
+		return err
+	}
+}
+
+// isDeadlineExceeded returns true if the given error is a context or gRPC deadline error.
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// limitedServerStream enforces limits on the messages it receives.
+type limitedServerStream struct {
+	*grpc_middleware.WrappedServerStream
+	method           string
+	limits           Limits
+	receivedMessages int
+}
+
+// RecvMsg implements the grpc.ServerStream interface.
+func (s *limitedServerStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.receivedMessages++
+	if s.limits.MaxStreamMessages > 0 && s.receivedMessages > s.limits.MaxStreamMessages {
+		requestLimitedCounter.WithLabelValues(s.method, "stream_messages").Inc()
+		return status.Errorf(codes.ResourceExhausted, "stream exceeded its maximum of %d messages", s.limits.MaxStreamMessages)
+	}
+	return checkRequestBytes(s.method, s.limits, m)
+}
+
+func checkRequestBytes(method string, limits Limits, req any) error {
+	if limits.MaxRequestBytes <= 0 {
+		return nil
+	}
+	message, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if size := proto.Size(message); size > limits.MaxRequestBytes {
+		requestLimitedCounter.WithLabelValues(method, "request_bytes").Inc()
+		return status.Errorf(codes.ResourceExhausted, "request of %d bytes exceeds the maximum of %d bytes", size, limits.MaxRequestBytes)
+	}
+	return nil
+}
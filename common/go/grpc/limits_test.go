@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const healthWatchMethod = "/grpc.health.v1.Health/Watch"
+
+func newRequestLimiterClient(t *testing.T, server *healthServer, limiter *RequestLimiter) grpc_health_v1.HealthClient {
+	serverOptions := []grpc.ServerOption{
+		grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(limiter.StreamServerInterceptor()),
+	}
+	return newTestHealthClient(t, server, serverOptions)
+}
+
+// watch opens a watch stream and returns the error that ends it.
+func watch(ctx context.Context, t *testing.T, client grpc_health_v1.HealthClient, request *grpc_health_v1.HealthCheckRequest) error {
+	stream, err := client.Watch(ctx, request)
+	require.NoError(t, err)
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+}
+
+func TestRequestLimiterUnary(t *testing.T) {
+	ctx := context.Background()
+	server := &healthServer{check: func(context.Context) error { return nil }}
+	limiter := NewRequestLimiter().
+		WithDefaultLimits(Limits{MaxRequestBytes: 16}).
+		WithMethodLimits(healthCheckMethod, Limits{MaxRequestBytes: 32})
+	client := newRequestLimiterClient(t, server, limiter)
+
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("a", 20)})
+	require.NoError(t, err)
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("a", 40)})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRequestLimiterStreamDuration(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRequestLimiter().WithMethodLimits(healthWatchMethod, Limits{MaxStreamDuration: 50 * time.Millisecond})
+
+	t.Run("EndsStreamsThatLastTooLong", func(t *testing.T) {
+		server := &healthServer{
+			watch: func(stream grpc_health_v1.Health_WatchServer) error {
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			},
+		}
+		client := newRequestLimiterClient(t, server, limiter)
+		err := watch(ctx, t, client, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("MapsDeadlineStatuses", func(t *testing.T) {
+		server := &healthServer{
+			watch: func(stream grpc_health_v1.Health_WatchServer) error {
+				<-stream.Context().Done()
+				return status.FromContextError(stream.Context().Err()).Err()
+			},
+		}
+		client := newRequestLimiterClient(t, server, limiter)
+		err := watch(ctx, t, client, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("KeepsSuccessfulStreams", func(t *testing.T) {
+		server := &healthServer{
+			watch: func(stream grpc_health_v1.Health_WatchServer) error {
+				// The stream completes despite outliving its limit.
+				<-stream.Context().Done()
+				return nil
+			},
+		}
+		client := newRequestLimiterClient(t, server, limiter)
+		err := watch(ctx, t, client, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("KeepsUnrelatedErrors", func(t *testing.T) {
+		server := &healthServer{
+			watch: func(stream grpc_health_v1.Health_WatchServer) error {
+				<-stream.Context().Done()
+				return status.Error(codes.FailedPrecondition, "unrelated")
+			},
+		}
+		client := newRequestLimiterClient(t, server, limiter)
+		err := watch(ctx, t, client, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("DoesNotBlameClientDeadlines", func(t *testing.T) {
+		limiter := NewRequestLimiter().WithMethodLimits(healthWatchMethod, Limits{MaxStreamDuration: time.Hour})
+		server := &healthServer{
+			watch: func(stream grpc_health_v1.Health_WatchServer) error {
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			},
+		}
+		client := newRequestLimiterClient(t, server, limiter)
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := watch(ctx, t, client, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+}
+
+// fakeServerStream receives `messages` empty messages.
+type fakeServerStream struct {
+	grpc.ServerStream
+	messages int
+}
+
+func (s *fakeServerStream) Context() context.Context { return context.Background() }
+
+func (s *fakeServerStream) RecvMsg(m any) error {
+	if s.messages == 0 {
+		return io.EOF
+	}
+	s.messages--
+	return nil
+}
+
+func TestRequestLimiterStreamMessages(t *testing.T) {
+	limiter := NewRequestLimiter().WithDefaultLimits(Limits{MaxStreamMessages: 2, MaxRequestBytes: 16})
+	interceptor := limiter.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/library.Library/ImportBooks"}
+
+	receive := func(messages int, request *grpc_health_v1.HealthCheckRequest) error {
+		return interceptor(nil, &fakeServerStream{messages: messages}, info, func(_ any, stream grpc.ServerStream) error {
+			for {
+				if err := stream.RecvMsg(request); err != nil {
+					if err == io.EOF {
+						return nil
+					}
+					return err
+				}
+			}
+		})
+	}
+
+	require.NoError(t, receive(2, &grpc_health_v1.HealthCheckRequest{}))
+	require.Equal(t, codes.ResourceExhausted, status.Code(receive(3, &grpc_health_v1.HealthCheckRequest{})))
+	require.Equal(t, codes.ResourceExhausted, status.Code(receive(1, &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("a", 20)})))
+}
@@ -1,6 +1,8 @@
 package grpc
 
 import (
+	"time"
+
 	"common/go/logging"
 )
 
@@ -11,6 +13,23 @@ type Opts struct {
 	Port       int    `long:"port" description:"Port to serve gRPC on." default:"9090"`
 	Host       string `long:"host" description:"Host for a client to connect to."`
 	DisableTLS bool   `long:"disable-tls" description:"Set to true in order to disable TLS for this service."`
+
+	// Server only options.
+	DrainPeriod         time.Duration `long:"drain-period" description:"How long a server reports NOT_SERVING before it stops accepting RPCs on shutdown." default:"0s"`
+	GracefulStopTimeout time.Duration `long:"graceful-stop-timeout" description:"How long a server waits for in-flight RPCs on shutdown before closing them." default:"10s"`
+
+	// Client only options.
+	Targets       []string `long:"target" description:"Extra host:port targets for a client to load balance across, on top of host:port. Can be repeated."`
+	Resolver      string   `long:"resolver" description:"Name resolver scheme for a client to use, e.g. dns or kubernetes. Defaults to passthrough."`
+	TLSServerName string   `long:"tls-server-name" description:"Overrides the server name a client verifies server certificates against."`
+	HealthCheck   bool     `long:"health-check" description:"Set to true for a client to only send RPCs to backends that report SERVING."`
+
+	// Client retry policy, replacing the default retry behaviour of unary RPCs if max attempts is >= 2.
+	RetryMaxAttempts       int           `long:"retry-max-attempts" description:"Maximum attempts of a client's unary RPCs. Disabled if < 2."`
+	RetryPerAttemptTimeout time.Duration `long:"retry-per-attempt-timeout" description:"Timeout of each attempt of a client's unary RPCs."`
+	RetryHedgingDelay      time.Duration `long:"retry-hedging-delay" description:"If set, a client sends another attempt of its unary RPCs every delay, without waiting for outstanding attempts to fail."`
+	// Per full method retry policies, overriding the retry flags above.
+	MethodRetryPolicies map[string]*RetryPolicy `no-flag:"true"`
 }
 
 // GatewayOpts holds a gRPC gateway server opts.
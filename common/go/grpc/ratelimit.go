@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"common/go/limiter"
+)
+
+var rateLimitedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "grpc_server_rate_limited_total",
+		Help: "RPCs rejected by the rate limiter",
+	},
+	[]string{"grpc_method"},
+)
+
+// CallerFN returns the identity of the caller of an RPC. RPCs are rate limited per caller.
+type CallerFN func(ctx context.Context) string
+
+// CallerFromPeer identifies callers by their IP address.
+func CallerFromPeer(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// CallerFromMetadata identifies callers by the value of the given incoming metadata key.
+func CallerFromMetadata(key string) CallerFN {
+	return func(ctx context.Context) string {
+		values := metadata.ValueFromIncomingContext(ctx, key)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+}
+
+// RateLimiter rate limits RPCs per full method and per caller.
+type RateLimiter struct {
+	defaultLimiter *limiter.TokenBuckets
+	methodLimiters map[string]*limiter.TokenBuckets
+	callerFN       CallerFN
+}
+
+// NewRateLimiter instantiates and returns a new rate limiter. By default, no method is rate limited and callers are
+// identified by their IP address.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		methodLimiters: map[string]*limiter.TokenBuckets{},
+		callerFN:       CallerFromPeer,
+	}
+}
+
+// WithDefaultLimit sets the limit applied to methods without a method limit.
+func (r *RateLimiter) WithDefaultLimit(rate limiter.Rate) *RateLimiter {
+	r.defaultLimiter = limiter.NewTokenBuckets(rate)
+	return r
+}
+
+// WithMethodLimit sets the limit of the given full method, e.g. "/library.Library/ListBooks".
+func (r *RateLimiter) WithMethodLimit(fullMethod string, rate limiter.Rate) *RateLimiter {
+	r.methodLimiters[fullMethod] = limiter.NewTokenBuckets(rate)
+	return r
+}
+
+// WithCallerFN sets the function used to identify callers.
+func (r *RateLimiter) WithCallerFN(callerFN CallerFN) *RateLimiter {
+	r.callerFN = callerFN
+	return r
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that rejects RPCs over limit with `ResourceExhausted`.
+func (r *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := r.allow(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that rejects streams over limit with `ResourceExhausted`.
+func (r *RateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := r.allow(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// allow takes a token from the bucket of this method and caller, or returns a `ResourceExhausted` error
+// detailing when to retry.
+func (r *RateLimiter) allow(ctx context.Context, method string) error {
+	tokenBuckets, ok := r.methodLimiters[method]
+	if !ok {
+		tokenBuckets = r.defaultLimiter
+	}
+	if tokenBuckets == nil {
+		return nil
+	}
+	// The default limiter is shared by all methods, so we key on the method too.
+	ok, retryAfter := tokenBuckets.Allow(method + "|" + r.callerFN(ctx))
+	if ok {
+		return nil
+	}
+
+	rateLimitedCounter.WithLabelValues(method).Inc()
+	return Errorf(codes.ResourceExhausted, "rate limit exceeded").WithRetryInfo(retryAfter)
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"common/go/limiter"
+)
+
+const callerMetadataKey = "x-caller"
+
+// oneRequestPerHour allows a single request, then rejects the next ones for an hour.
+var oneRequestPerHour = limiter.Rate{PerSecond: 1.0 / 3600, Burst: 1}
+
+func newRateLimiterClient(t *testing.T, rateLimiter *RateLimiter) grpc_health_v1.HealthClient {
+	server := &healthServer{
+		check: func(context.Context) error { return nil },
+		watch: func(grpc_health_v1.Health_WatchServer) error { return nil },
+	}
+	serverOptions := []grpc.ServerOption{
+		grpc.UnaryInterceptor(rateLimiter.WithCallerFN(CallerFromMetadata(callerMetadataKey)).UnaryServerInterceptor()),
+		grpc.StreamInterceptor(rateLimiter.StreamServerInterceptor()),
+	}
+	return newTestHealthClient(t, server, serverOptions)
+}
+
+func checkAs(client grpc_health_v1.HealthClient, caller string) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), callerMetadataKey, caller)
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("RejectsCallersOverLimit", func(t *testing.T) {
+		client := newRateLimiterClient(t, NewRateLimiter().WithMethodLimit(healthCheckMethod, oneRequestPerHour))
+		require.NoError(t, checkAs(client, "alice"))
+		err := checkAs(client, "alice")
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		// Clients are told when to retry.
+		details := status.Convert(err).Details()
+		require.Len(t, details, 1)
+		retryInfo, ok := details[0].(*errdetails.RetryInfo)
+		require.True(t, ok)
+		require.InDelta(t, time.Hour, retryInfo.GetRetryDelay().AsDuration(), float64(time.Second))
+	})
+
+	t.Run("LimitsCallersIndependently", func(t *testing.T) {
+		client := newRateLimiterClient(t, NewRateLimiter().WithMethodLimit(healthCheckMethod, oneRequestPerHour))
+		require.NoError(t, checkAs(client, "alice"))
+		require.NoError(t, checkAs(client, "bob"))
+		require.Equal(t, codes.ResourceExhausted, status.Code(checkAs(client, "bob")))
+	})
+
+	t.Run("DoesNotLimitMethodsWithoutLimit", func(t *testing.T) {
+		client := newRateLimiterClient(t, NewRateLimiter().WithMethodLimit("/library.Library/ListBooks", oneRequestPerHour))
+		for i := 0; i < 5; i++ {
+			require.NoError(t, checkAs(client, "alice"))
+		}
+	})
+
+	t.Run("MethodLimitsOverrideTheDefaultLimit", func(t *testing.T) {
+		rateLimiter := NewRateLimiter().
+			WithDefaultLimit(oneRequestPerHour).
+			WithMethodLimit(healthCheckMethod, limiter.Rate{PerSecond: 1, Burst: 5})
+		client := newRateLimiterClient(t, rateLimiter)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, checkAs(client, "alice"))
+		}
+		require.Equal(t, codes.ResourceExhausted, status.Code(checkAs(client, "alice")))
+	})
+
+	t.Run("DefaultLimitIsPerMethod", func(t *testing.T) {
+		client := newRateLimiterClient(t, NewRateLimiter().WithDefaultLimit(oneRequestPerHour))
+		require.NoError(t, checkAs(client, "alice"))
+		ctx := metadata.AppendToOutgoingContext(context.Background(), callerMetadataKey, "alice")
+		stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NotEqual(t, codes.ResourceExhausted, status.Code(err))
+
+		// Streams are limited too.
+		stream, err = client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+}
+
+func TestCallerFromPeer(t *testing.T) {
+	require.Empty(t, CallerFromPeer(context.Background()))
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4321}})
+	require.Equal(t, "10.0.0.1", CallerFromPeer(ctx))
+}
+
+func TestCallerFromMetadata(t *testing.T) {
+	callerFN := CallerFromMetadata(callerMetadataKey)
+	require.Empty(t, callerFN(context.Background()))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(callerMetadataKey, "alice"))
+	require.Equal(t, "alice", callerFN(ctx))
+}
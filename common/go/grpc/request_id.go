@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"common/go/uuid"
+)
+
+// RequestIDMetadataKey is the metadata key holding the id of a request. It is propagated to downstream calls,
+// returned as a response header, and attached to errors as a `RequestInfo` detail.
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDContextKey struct{}
+
+// RequestIDFromContext returns the id of the request being served.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok
+}
+
+// unaryServerRequestIDInterceptor assigns every RPC a request id and attaches it to errors.
+func unaryServerRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, requestID := injectRequestID(ctx)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID)); err != nil {
+			log.Warningf("setting request id header: %v", err)
+		}
+		response, err := handler(ctx, req)
+		return response, withRequestInfo(err, requestID)
+	}
+}
+
+// streamServerRequestIDInterceptor assigns every stream a request id and attaches it to errors.
+func streamServerRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestID := injectRequestID(stream.Context())
+		if err := stream.SetHeader(metadata.Pairs(RequestIDMetadataKey, requestID)); err != nil {
+			log.Warningf("setting request id header: %v", err)
+		}
+		err := handler(srv, &grpc_middleware.WrappedServerStream{ServerStream: stream, WrappedContext: ctx})
+		return withRequestInfo(err, requestID)
+	}
+}
+
+// injectRequestID returns a context holding the incoming request id, or a new one if the caller did not set any.
+// New ids are added to the incoming metadata, so that they are propagated to downstream calls.
+func injectRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(RequestIDMetadataKey); len(values) > 0 && values[0] != "" {
+		return context.WithValue(ctx, requestIDContextKey{}, values[0]), values[0]
+	}
+	requestID := uuid.MustNew()
+	md = md.Copy()
+	md.Set(RequestIDMetadataKey, requestID)
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return context.WithValue(ctx, requestIDContextKey{}, requestID), requestID
+}
+
+// withRequestInfo attaches a `RequestInfo` detail holding the request id to the given error, if it has none yet.
+func withRequestInfo(err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+	s := status.Convert(err)
+	for _, detail := range s.Details() {
+		if _, ok := detail.(*errdetails.RequestInfo); ok {
+			return err
+		}
+	}
+	return withDetails(s, &errdetails.RequestInfo{RequestId: requestID}).Err()
+}
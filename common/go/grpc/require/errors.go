@@ -2,10 +2,12 @@ package require
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // Error is a convenience utility function to assert
@@ -16,3 +18,34 @@ func Error(t *testing.T, code codes.Code, err error) {
 	require.True(t, ok)
 	require.Equal(t, code, status.Code())
 }
+
+// ErrorWithDetail asserts an error is a gRPC error matching the given gRPC code,
+// and carrying a detail of type T, which it returns.
+func ErrorWithDetail[T proto.Message](t *testing.T, code codes.Code, err error) T {
+	Error(t, code, err)
+	status, _ := status.FromError(err)
+	for _, detail := range status.Details() {
+		if detail, ok := detail.(T); ok {
+			return detail
+		}
+	}
+	var zero T
+	require.Failf(t, "missing error detail", "error %v has no %T detail", err, zero)
+	return zero
+}
+
+// EventuallyCode asserts fn eventually returns an error matching the given gRPC code, calling it every tick
+// until waitFor has elapsed. Use codes.OK to wait for fn to succeed.
+func EventuallyCode(t *testing.T, code codes.Code, fn func() error, waitFor, tick time.Duration) {
+	deadline := time.Now().Add(waitFor)
+	for {
+		err := fn()
+		if status.Code(err) == code {
+			return
+		}
+		if time.Now().After(deadline) {
+			require.FailNowf(t, "unexpected code", "expected code %s within %s, last error: %v", code, waitFor, err)
+		}
+		time.Sleep(tick)
+	}
+}
@@ -0,0 +1,53 @@
+package require
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestErrorWithDetail(t *testing.T) {
+	s, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(
+		&errdetails.ErrorInfo{Reason: "RATE_LIMITED"},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)},
+	)
+	require.NoError(t, err)
+
+	retryInfo := ErrorWithDetail[*errdetails.RetryInfo](t, codes.ResourceExhausted, s.Err())
+	require.Equal(t, time.Second, retryInfo.GetRetryDelay().AsDuration())
+	errorInfo := ErrorWithDetail[*errdetails.ErrorInfo](t, codes.ResourceExhausted, s.Err())
+	require.Equal(t, "RATE_LIMITED", errorInfo.GetReason())
+}
+
+func TestEventuallyCode(t *testing.T) {
+	t.Run("WaitsForTheCode", func(t *testing.T) {
+		calls := 0
+		fn := func() error {
+			calls++
+			if calls < 3 {
+				return status.Error(codes.Unavailable, "starting")
+			}
+			return status.Error(codes.NotFound, "book not found")
+		}
+		EventuallyCode(t, codes.NotFound, fn, time.Second, time.Millisecond)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("OKWaitsForSuccess", func(t *testing.T) {
+		calls := 0
+		fn := func() error {
+			calls++
+			if calls < 2 {
+				return status.Error(codes.Unavailable, "starting")
+			}
+			return nil
+		}
+		EventuallyCode(t, codes.OK, fn, time.Second, time.Millisecond)
+		require.Equal(t, 2, calls)
+	})
+}
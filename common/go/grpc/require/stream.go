@@ -0,0 +1,48 @@
+package require
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Receiver is implemented by gRPC client streams.
+type Receiver[T any] interface {
+	Recv() (T, error)
+}
+
+// StreamRecvAll receives messages from a stream until it ends, and returns them.
+// It fails the test if the stream returns an error, or does not end within the given timeout.
+func StreamRecvAll[T any](t *testing.T, stream Receiver[T], timeout time.Duration) []T {
+	type result struct {
+		messages []T
+		err      error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var messages []T
+		for {
+			message, err := stream.Recv()
+			if err == io.EOF {
+				ch <- result{messages: messages}
+				return
+			}
+			if err != nil {
+				ch <- result{messages: messages, err: err}
+				return
+			}
+			messages = append(messages, message)
+		}
+	}()
+
+	select {
+	case result := <-ch:
+		require.NoError(t, result.err)
+		return result.messages
+	case <-time.After(timeout):
+		require.FailNowf(t, "stream did not end", "stream did not end within %s", timeout)
+		return nil
+	}
+}
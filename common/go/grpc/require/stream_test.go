@@ -0,0 +1,47 @@
+package require
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStream returns its messages, then the given error.
+type fakeStream struct {
+	messages []string
+	err      error
+	// If set, Recv blocks on it once the messages are exhausted.
+	block chan struct{}
+}
+
+func (s *fakeStream) Recv() (string, error) {
+	if len(s.messages) == 0 {
+		if s.block != nil {
+			<-s.block
+		}
+		return "", s.err
+	}
+	message := s.messages[0]
+	s.messages = s.messages[1:]
+	return message, nil
+}
+
+func TestStreamRecvAll(t *testing.T) {
+	t.Run("ReceivesUntilEOF", func(t *testing.T) {
+		stream := &fakeStream{messages: []string{"books", "authors"}, err: io.EOF}
+		require.Equal(t, []string{"books", "authors"}, StreamRecvAll[string](t, stream, time.Second))
+	})
+
+	t.Run("EmptyStream", func(t *testing.T) {
+		require.Empty(t, StreamRecvAll[string](t, &fakeStream{err: io.EOF}, time.Second))
+	})
+
+	t.Run("WaitsForSlowStreams", func(t *testing.T) {
+		block := make(chan struct{})
+		stream := &fakeStream{messages: []string{"books"}, err: io.EOF, block: block}
+		time.AfterFunc(10*time.Millisecond, func() { close(block) })
+		require.Equal(t, []string{"books"}, StreamRecvAll[string](t, stream, time.Second))
+	})
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryPolicy configures how a client retries or hedges a unary RPC.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one. The policy is disabled if this is < 2.
+	MaxAttempts int
+	// Timeout of each attempt. If 0, attempts are only bounded by the deadline of the RPC.
+	PerAttemptTimeout time.Duration
+	// Codes that trigger another attempt. Defaults to `ResourceExhausted` and `Unavailable`.
+	RetryableCodes []codes.Code
+	// Delay between a failed attempt and the next one. Defaults to 100ms. Ignored when hedging.
+	Backoff time.Duration
+	// If set, the client hedges: it sends another attempt every `HedgingDelay`, without waiting for outstanding attempts
+	// to fail. The first successful attempt wins. Only use this on idempotent methods.
+	HedgingDelay time.Duration
+}
+
+// retryPolicy returns the retry policy of the given method, or nil if it has none.
+func (o Opts) retryPolicy(method string) *RetryPolicy {
+	if policy, ok := o.MethodRetryPolicies[method]; ok {
+		return policy
+	}
+	if o.RetryMaxAttempts < 2 {
+		return nil
+	}
+	return &RetryPolicy{
+		MaxAttempts:       o.RetryMaxAttempts,
+		PerAttemptTimeout: o.RetryPerAttemptTimeout,
+		Backoff:           retryBackoff,
+		HedgingDelay:      o.RetryHedgingDelay,
+	}
+}
+
+// unaryClientRetryPolicyInterceptor returns a unary client interceptor that applies retry policies.
+// Methods with a policy bypass the default retry interceptor, which must therefore come after this one.
+func unaryClientRetryPolicyInterceptor(opts Opts) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOptions ...grpc.CallOption) error {
+		policy := opts.retryPolicy(method)
+		if policy == nil || policy.MaxAttempts < 2 {
+			return invoker(ctx, method, req, reply, cc, callOptions...)
+		}
+		callOptions = append(callOptions, grpc_retry.Disable())
+		invoke := func(ctx context.Context, reply any) error {
+			if policy.PerAttemptTimeout > 0 {
+				var cancel func()
+				ctx, cancel = context.WithTimeout(ctx, policy.PerAttemptTimeout)
+				defer cancel()
+			}
+			return invoker(ctx, method, req, reply, cc, callOptions...)
+		}
+		if policy.HedgingDelay > 0 {
+			return policy.hedge(ctx, reply.(proto.Message), invoke)
+		}
+		return policy.retry(ctx, reply, invoke)
+	}
+}
+
+func (p *RetryPolicy) retry(ctx context.Context, reply any, invoke func(context.Context, any) error) error {
+	var err error
+	for attempt := 0; attempt < p.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(p.backoff()):
+			}
+		}
+		if err = invoke(ctx, reply); err == nil || !p.isRetryable(ctx, err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *RetryPolicy) hedge(ctx context.Context, reply proto.Message, invoke func(context.Context, any) error) error {
+	type result struct {
+		reply proto.Message
+		err   error
+	}
+	// Cancels outstanding attempts once we return.
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, p.MaxAttempts)
+
+	var launched, outstanding int
+	var hedgingTimer <-chan time.Time
+	launch := func() {
+		launched++
+		outstanding++
+		// Attempts run concurrently so each one needs its own reply.
+		attemptReply := reply.ProtoReflect().New().Interface()
+		go func() { results <- result{reply: attemptReply, err: invoke(attemptCtx, attemptReply)} }()
+		hedgingTimer = nil
+		if launched < p.MaxAttempts {
+			hedgingTimer = time.After(p.HedgingDelay)
+		}
+	}
+
+	launch()
+	for {
+		select {
+		case <-hedgingTimer:
+			launch()
+		case result := <-results:
+			outstanding--
+			if result.err == nil {
+				proto.Reset(reply)
+				proto.Merge(reply, result.reply)
+				return nil
+			}
+			if !p.isRetryable(ctx, result.err) {
+				return result.err
+			}
+			if outstanding == 0 {
+				if launched == p.MaxAttempts {
+					return result.err
+				}
+				// No need to wait for the hedging delay.
+				launch()
+			}
+		}
+	}
+}
+
+// backoff returns the delay between attempts, never retrying in a tight loop.
+func (p *RetryPolicy) backoff() time.Duration {
+	if p.Backoff <= 0 {
+		return retryBackoff
+	}
+	return p.Backoff
+}
+
+// isRetryable returns true if the given attempt error warrants another attempt of the RPC with the given context.
+func (p *RetryPolicy) isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	code := status.Code(err)
+	// The attempt timed out but the RPC did not.
+	if code == codes.DeadlineExceeded && p.PerAttemptTimeout > 0 {
+		return true
+	}
+	retryableCodes := p.RetryableCodes
+	if len(retryableCodes) == 0 {
+		retryableCodes = retriableCodes
+	}
+	for _, retryableCode := range retryableCodes {
+		if code == retryableCode {
+			return true
+		}
+	}
+	return false
+}
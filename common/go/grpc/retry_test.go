@@ -0,0 +1,194 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const healthCheckMethod = "/grpc.health.v1.Health/Check"
+
+// healthServer answers health checks and watches with the given functions.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	check func(context.Context) error
+	watch func(grpc_health_v1.Health_WatchServer) error
+}
+
+func (s *healthServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if err := s.check(ctx); err != nil {
+		return nil, err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	return s.watch(stream)
+}
+
+// newTestHealthClient serves the given health server over an in-memory connection.
+func newTestHealthClient(t *testing.T, server *healthServer, serverOptions []grpc.ServerOption, dialOptions ...grpc.DialOption) grpc_health_v1.HealthClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(serverOptions...)
+	grpc_health_v1.RegisterHealthServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	dialOptions = append(
+		dialOptions,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	connection, err := grpc.Dial("bufnet", dialOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { connection.Close() })
+	return grpc_health_v1.NewHealthClient(connection)
+}
+
+// failingHealthServer fails the first `failures` checks with the given code.
+func failingHealthServer(failures int32, code codes.Code) (*healthServer, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	return &healthServer{
+		check: func(context.Context) error {
+			if calls.Add(1) <= failures {
+				return status.Error(code, "failing")
+			}
+			return nil
+		},
+	}, calls
+}
+
+func newRetryPolicyClient(t *testing.T, server *healthServer, policy *RetryPolicy) grpc_health_v1.HealthClient {
+	opts := Opts{MethodRetryPolicies: map[string]*RetryPolicy{healthCheckMethod: policy}}
+	return newTestHealthClient(t, server, nil, grpc.WithUnaryInterceptor(unaryClientRetryPolicyInterceptor(opts)))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RetriesUntilSuccess", func(t *testing.T) {
+		server, calls := failingHealthServer(2, codes.Unavailable)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		server, calls := failingHealthServer(5, codes.Unavailable)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("DoesNotRetryNonRetryableCodes", func(t *testing.T) {
+		server, calls := failingHealthServer(5, codes.InvalidArgument)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("CustomRetryableCodes", func(t *testing.T) {
+		server, calls := failingHealthServer(1, codes.Aborted)
+		policy := &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, RetryableCodes: []codes.Code{codes.Aborted}}
+		client := newRetryPolicyClient(t, server, policy)
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("ZeroBackoffDefaults", func(t *testing.T) {
+		server, calls := failingHealthServer(2, codes.Unavailable)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3})
+		start := time.Now()
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), calls.Load())
+		require.GreaterOrEqual(t, time.Since(start), 2*retryBackoff)
+	})
+
+	t.Run("RetriesTimedOutAttempts", func(t *testing.T) {
+		calls := &atomic.Int32{}
+		server := &healthServer{
+			check: func(ctx context.Context) error {
+				if calls.Add(1) == 1 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			},
+		}
+		policy := &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, PerAttemptTimeout: 50 * time.Millisecond}
+		client := newRetryPolicyClient(t, server, policy)
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestRetryPolicyHedging(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("FirstSuccessWinsAndCancelsOutstandingAttempts", func(t *testing.T) {
+		calls := &atomic.Int32{}
+		cancelled := make(chan struct{})
+		server := &healthServer{
+			check: func(ctx context.Context) error {
+				if calls.Add(1) == 1 {
+					// The first attempt hangs until the hedged attempt wins.
+					<-ctx.Done()
+					close(cancelled)
+					return ctx.Err()
+				}
+				return nil
+			},
+		}
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, HedgingDelay: 20 * time.Millisecond})
+		response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("outstanding attempt was not cancelled")
+		}
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("RelaunchesFailedAttemptsImmediately", func(t *testing.T) {
+		server, calls := failingHealthServer(2, codes.Unavailable)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, HedgingDelay: time.Hour})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		server, calls := failingHealthServer(5, codes.Unavailable)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, HedgingDelay: time.Hour})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("DoesNotHedgeNonRetryableErrors", func(t *testing.T) {
+		server, calls := failingHealthServer(5, codes.NotFound)
+		client := newRetryPolicyClient(t, server, &RetryPolicy{MaxAttempts: 3, HedgingDelay: time.Hour})
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		require.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bufbuild/protovalidate-go"
@@ -26,7 +28,7 @@ import (
 const (
 	// maximum message size for the server (20 MB)
 	maximumMessageSize         = 20 * 1024 * 1024
-	gracefulStopTimeoutSeconds = 10
+	defaultGracefulStopTimeout = 10 * time.Second
 )
 
 var (
@@ -63,15 +65,33 @@ type Server struct {
 	unaryInterceptors []grpc.UnaryServerInterceptor
 	// The first interceptor is called first.
 	streamInterceptors []grpc.StreamServerInterceptor
-	options            []grpc.ServerOption
+	// Authentication interceptors, chained right after the first `authInterceptorIndex` interceptors.
+	unaryAuthInterceptor  grpc.UnaryServerInterceptor
+	streamAuthInterceptor grpc.StreamServerInterceptor
+	authInterceptorIndex  int
+	options               []grpc.ServerOption
+	shutdownHooks         []ShutdownHook
+	// Set once the server starts shutting down.
+	draining atomic.Bool
+	// Closed once the server is shut down.
+	stopped chan struct{}
+	// Closed when the server is stopped forcefully, interrupting any graceful stop in progress.
+	forceStopped  chan struct{}
+	forceStopOnce sync.Once
 }
 
+// ShutdownHook is called when a server stops accepting RPCs, so that services can flush pending work and wrap up
+// in-flight streams. The context expires when the graceful stop timeout does.
+type ShutdownHook func(ctx context.Context) error
+
 // NewServer creates and returns a new Server.
 func NewServer(opts Opts, certsOpts certs.Opts, prometheusOpts prometheus.Opts, register func(*Server)) *Server {
 	server := &Server{
 		opts:           opts,
 		prometheusOpts: prometheusOpts,
 		register:       register,
+		stopped:        make(chan struct{}),
+		forceStopped:   make(chan struct{}),
 	}
 
 	// Default options.
@@ -86,23 +106,32 @@ func NewServer(opts Opts, certsOpts certs.Opts, prometheusOpts prometheus.Opts,
 		log.Warningf("Starting gRPC server without TLS")
 	}
 
-	// Default interceptors.
+	// Default interceptors. Request ids come first, so that every error carries one.
+	server.unaryInterceptors = append(server.unaryInterceptors, unaryServerRequestIDInterceptor())
+	server.streamInterceptors = append(server.streamInterceptors, streamServerRequestIDInterceptor())
 	if !prometheusOpts.Disable {
 		server.unaryInterceptors = append(server.unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
 		server.streamInterceptors = append(server.streamInterceptors, grpc_prometheus.StreamServerInterceptor)
 	}
-	// Always pass logging first, so that subsequent interceptors have error logging enabled :).
+	// Authentication comes next, so that anonymous callers are rejected before their requests are logged or validated.
+	server.authInterceptorIndex = len(server.unaryInterceptors)
+	// Always pass logging next, so that subsequent interceptors have error logging enabled :).
 	server.unaryInterceptors = append(server.unaryInterceptors, unaryServerLoggingInterceptor(), unaryServerContextPropagationInterceptor(), unaryServerValidateInterceptor())
 	server.streamInterceptors = append(server.streamInterceptors, streamServerLoggingInterceptor(), streamServerContextPropagationInterceptor(), streamServerValidateInterceptor())
 	return server
 }
 
-// WithHealthCheck adds grpc health check capabilitites to the server.
+// WithHealthCheck sets the check the grpc health service reports the status of.
 func (s *Server) WithHealthCheck(healthCheck health.Check) *Server {
 	s.healthCheck = healthCheck
 	return s
 }
 
+// WithHealthRegistry sets the registry's readiness probes as the check the grpc health service reports the status of.
+func (s *Server) WithHealthRegistry(registry *health.Registry) *Server {
+	return s.WithHealthCheck(registry.Readiness())
+}
+
 // WithOptions adds options to this gRPC server.
 func (s *Server) WithOptions(options ...grpc.ServerOption) *Server {
 	s.options = append(s.options, options...)
@@ -121,29 +150,90 @@ func (s *Server) WithStreamInterceptors(interceptors ...grpc.StreamServerInterce
 	return s
 }
 
+// WithAuthInterceptors sets the interceptors authenticating RPCs. Unlike interceptors added through
+// `WithUnaryInterceptors` and `WithStreamInterceptors`, they run before the logging and validation interceptors.
+func (s *Server) WithAuthInterceptors(unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *Server {
+	s.unaryAuthInterceptor = unary
+	s.streamAuthInterceptor = stream
+	return s
+}
+
+// WithShutdownHooks adds hooks called when this server stops accepting RPCs.
+func (s *Server) WithShutdownHooks(hooks ...ShutdownHook) *Server {
+	s.shutdownHooks = append(s.shutdownHooks, hooks...)
+	return s
+}
+
+// gracefulStop shuts the server down in phases:
+// 1. Health checks report NOT_SERVING, so that load balancers stop routing new RPCs to this server.
+// 2. We wait for the drain period, while still serving RPCs.
+// 3. The server stops accepting RPCs and shutdown hooks are called, while in-flight RPCs complete.
+// 4. In-flight RPCs are closed once the graceful stop timeout is exhausted.
+// A forceful stop interrupts any of these phases.
 func (s *Server) gracefulStop(server *grpc.Server) {
-	ch := make(chan struct{})
+	defer close(s.stopped)
+	s.draining.Store(true)
+	if s.opts.DrainPeriod > 0 {
+		log.Infof("draining server for %s", s.opts.DrainPeriod)
+		timer := time.NewTimer(s.opts.DrainPeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.forceStopped:
+			log.Infof("drain interrupted, server stopped")
+			return
+		}
+	}
+
+	timeout := s.opts.GracefulStopTimeout
+	if timeout <= 0 {
+		timeout = defaultGracefulStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ch := make(chan struct{}, 1)
 	go func() {
-		log.Infof("attempting to gracefully stop server, with a grace period of %d seconds", gracefulStopTimeoutSeconds)
+		log.Infof("attempting to gracefully stop server, with a grace period of %s", timeout)
+		var wg sync.WaitGroup
+		for _, hook := range s.shutdownHooks {
+			hook := hook
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := hook(ctx); err != nil {
+					log.Errorf("shutdown hook: %v", err)
+				}
+			}()
+		}
 		server.GracefulStop()
+		wg.Wait()
 		log.Info("server stopped")
 		ch <- struct{}{}
 	}()
 	select {
-	case <-time.After(time.Duration(gracefulStopTimeoutSeconds) * time.Second):
+	case <-ctx.Done():
 		log.Infof("grace period exhausted, stopping server")
 		server.Stop()
+	case <-s.forceStopped:
+		log.Infof("graceful stop interrupted, server stopped")
 	case <-ch:
 	}
 }
 
+// stop closes all connections and in-flight RPCs immediately.
+func (s *Server) stop(server *grpc.Server) {
+	s.forceStopOnce.Do(func() { close(s.forceStopped) })
+	server.Stop()
+}
+
 // Serve instantiates the gRPC server and blocks forever.
 func (s *Server) Serve() {
-	if len(s.unaryInterceptors) > 0 {
-		s.options = append(s.options, grpc.ChainUnaryInterceptor(s.unaryInterceptors...))
+	unaryInterceptors, streamInterceptors := s.interceptors()
+	if len(unaryInterceptors) > 0 {
+		s.options = append(s.options, grpc.ChainUnaryInterceptor(unaryInterceptors...))
 	}
-	if len(s.streamInterceptors) > 0 {
-		s.options = append(s.options, grpc.ChainStreamInterceptor(s.streamInterceptors...))
+	if len(streamInterceptors) > 0 {
+		s.options = append(s.options, grpc.ChainStreamInterceptor(streamInterceptors...))
 	}
 
 	// Connect.
@@ -156,10 +246,9 @@ func (s *Server) Serve() {
 
 	s.Raw = grpc.NewServer(s.options...)
 	s.register(s)
-	if s.healthCheck != nil {
-		grpc_health_v1.RegisterHealthServer(s.Raw, s)
-	}
-	go handleSignals(func() { s.gracefulStop(s.Raw) }, s.Raw.Stop)
+	// The health service is always registered, so that load balancers stop routing RPCs to draining servers.
+	grpc_health_v1.RegisterHealthServer(s.Raw, s)
+	go handleSignals(func() { s.gracefulStop(s.Raw) }, func() { s.stop(s.Raw) })
 	if !s.prometheusOpts.Disable {
 		grpc_prometheus.Register(s.Raw)
 		grpc_prometheus.EnableHandlingTimeHistogram()
@@ -167,13 +256,37 @@ func (s *Server) Serve() {
 	if err := s.Raw.Serve(listener); err != nil {
 		log.Panicf("gRPC server exited with error: %v", err)
 	}
+	// Serve returns as soon as the server stops accepting RPCs, so we wait for in-flight RPCs and shutdown hooks.
+	if s.draining.Load() {
+		<-s.stopped
+	}
 }
 
-// Check implements the grpc health v1 interface.
+// interceptors returns the interceptors to chain, with the authentication interceptors inserted after the request id
+// and metrics interceptors.
+func (s *Server) interceptors() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	unaryInterceptors := append([]grpc.UnaryServerInterceptor{}, s.unaryInterceptors[:s.authInterceptorIndex]...)
+	streamInterceptors := append([]grpc.StreamServerInterceptor{}, s.streamInterceptors[:s.authInterceptorIndex]...)
+	if s.unaryAuthInterceptor != nil {
+		unaryInterceptors = append(unaryInterceptors, s.unaryAuthInterceptor)
+	}
+	if s.streamAuthInterceptor != nil {
+		streamInterceptors = append(streamInterceptors, s.streamAuthInterceptor)
+	}
+	unaryInterceptors = append(unaryInterceptors, s.unaryInterceptors[s.authInterceptorIndex:]...)
+	streamInterceptors = append(streamInterceptors, s.streamInterceptors[s.authInterceptorIndex:]...)
+	return unaryInterceptors, streamInterceptors
+}
+
+// Check implements the grpc health v1 interface. Servers without a health check report SERVING until they drain.
 func (s *Server) Check(ctx context.Context, in *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
 	status := grpc_health_v1.HealthCheckResponse_SERVING
-	if err := s.healthCheck(ctx); err != nil {
+	if s.draining.Load() {
 		status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	} else if s.healthCheck != nil {
+		if err := s.healthCheck(ctx); err != nil {
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
 	}
 	return &grpc_health_v1.HealthCheckResponse{Status: status}, nil
 }
@@ -234,7 +347,7 @@ func unaryServerValidateInterceptor() grpc.UnaryServerInterceptor {
 	}
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		if err := validator.Validate(req.(proto.Message)); err != nil {
-			return nil, status.Error(codes.InvalidArgument, err.Error())
+			return nil, Errorf(codes.InvalidArgument, "%v", err).WithValidationError(err)
 		}
 		return handler(ctx, req)
 	}
@@ -269,7 +382,7 @@ func (s *recvWrapperValidate) RecvMsg(m any) error {
 		return err
 	}
 	if err := s.validator.Validate(m.(proto.Message)); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
+		return Errorf(codes.InvalidArgument, "%v", err).WithValidationError(err)
 	}
 	return nil
 }
@@ -0,0 +1,139 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"common/go/certs"
+	"common/go/prometheus"
+)
+
+// newTestServer returns a server that is not listening, along with its raw gRPC server.
+func newTestServer(opts Opts) (*Server, *grpc.Server) {
+	server := &Server{
+		opts:         opts,
+		healthCheck:  func(context.Context) error { return nil },
+		stopped:      make(chan struct{}),
+		forceStopped: make(chan struct{}),
+	}
+	return server, grpc.NewServer()
+}
+
+func requireClosed(t *testing.T, ch <-chan struct{}, message string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal(message)
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DrainsThenCallsShutdownHooks", func(t *testing.T) {
+		server, raw := newTestServer(Opts{DrainPeriod: 50 * time.Millisecond})
+		var hookCalled atomic.Bool
+		server.WithShutdownHooks(func(context.Context) error {
+			hookCalled.Store(true)
+			return nil
+		})
+
+		start := time.Now()
+		go server.gracefulStop(raw)
+		require.Eventually(t, server.draining.Load, time.Second, time.Millisecond)
+		response, err := server.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, response.Status)
+
+		requireClosed(t, server.stopped, "server did not stop")
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		require.True(t, hookCalled.Load())
+	})
+
+	t.Run("StopInterruptsDrain", func(t *testing.T) {
+		server, raw := newTestServer(Opts{DrainPeriod: time.Hour})
+		var hookCalled atomic.Bool
+		server.WithShutdownHooks(func(context.Context) error {
+			hookCalled.Store(true)
+			return nil
+		})
+
+		go server.gracefulStop(raw)
+		require.Eventually(t, server.draining.Load, time.Second, time.Millisecond)
+		server.stop(raw)
+		requireClosed(t, server.stopped, "stop did not interrupt the drain period")
+		require.False(t, hookCalled.Load())
+	})
+
+	t.Run("StopInterruptsGracePeriod", func(t *testing.T) {
+		server, raw := newTestServer(Opts{GracefulStopTimeout: time.Hour})
+		hookStarted := make(chan struct{})
+		hookCancelled := make(chan struct{})
+		server.WithShutdownHooks(func(ctx context.Context) error {
+			close(hookStarted)
+			<-ctx.Done()
+			close(hookCancelled)
+			return ctx.Err()
+		})
+
+		go server.gracefulStop(raw)
+		requireClosed(t, hookStarted, "shutdown hook was not called")
+		server.stop(raw)
+		requireClosed(t, server.stopped, "stop did not interrupt the grace period")
+		requireClosed(t, hookCancelled, "shutdown hook context was not cancelled")
+	})
+
+	t.Run("GracePeriodExhausted", func(t *testing.T) {
+		server, raw := newTestServer(Opts{GracefulStopTimeout: 50 * time.Millisecond})
+		server.WithShutdownHooks(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		go server.gracefulStop(raw)
+		requireClosed(t, server.stopped, "grace period was not enforced")
+	})
+}
+
+func TestHealthService(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	// The server has no health check.
+	registered := make(chan *Server, 1)
+	server := NewServer(Opts{Port: port, DisableTLS: true}, certs.Opts{}, prometheus.Opts{Disable: true}, func(server *Server) {
+		registered <- server
+	})
+	go server.Serve()
+	select {
+	case <-registered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server was not registered")
+	}
+	t.Cleanup(server.Raw.Stop)
+	connection, err := grpc.Dial(fmt.Sprintf("localhost:%d", port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { connection.Close() })
+	client := grpc_health_v1.NewHealthClient(connection)
+
+	response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+	server.draining.Store(true)
+	response, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, response.Status)
+}
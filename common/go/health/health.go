@@ -36,13 +36,28 @@ func Serve(opts Opts, handlerFN Check) {
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", httpHandler)
+	serve(opts, mux)
+}
+
+// ServeRegistry serves the registry's probes in a goroutine: readiness on `/healthz` and liveness on `/livez`.
+// Both endpoints report the status and latency of each dependency.
+func ServeRegistry(opts Opts, registry *Registry) {
+	if opts.Disable {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", registry.Handler(Readiness))
+	mux.Handle("/livez", registry.Handler(Liveness))
+	serve(opts, mux)
+}
+
+func serve(opts Opts, mux *http.ServeMux) {
 	log.Infof("Serving health check on [:%d/healthz]", opts.Port)
 	go func() {
 		if err := http.ListenAndServe(fmt.Sprintf(":%d", opts.Port), mux); err != nil {
 			log.Warningf("Health server shutdown unexpectedly : %v", err)
 		}
 	}()
-
 }
 
 // Checks combines several checks into a single one. It runs each health check in parallel.
@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+// Probe types.
+const (
+	// Liveness probes report whether the process is healthy, i.e. whether it should be restarted.
+	Liveness ProbeType = iota
+	// Readiness probes report whether the process can serve traffic. Readiness implies liveness.
+	Readiness
+)
+
+// ProbeType is the type of a probe.
+type ProbeType int
+
+// String implements the stringer interface.
+func (t ProbeType) String() string {
+	if t == Liveness {
+		return "liveness"
+	}
+	return "readiness"
+}
+
+type probe struct {
+	name      string
+	probeType ProbeType
+	check     Check
+}
+
+// Registry holds the probes registered by a server's dependencies (database pools, clients, pubsub, caches, etc).
+type Registry struct {
+	timeout time.Duration
+
+	probesMutex sync.RWMutex
+	probes      []*probe
+}
+
+// NewRegistry instantiates and returns a new registry.
+func NewRegistry() *Registry {
+	return &Registry{timeout: defaultProbeTimeout}
+}
+
+// WithTimeout overrides the maximum duration of a single probe.
+func (r *Registry) WithTimeout(timeout time.Duration) *Registry {
+	r.timeout = timeout
+	return r
+}
+
+// RegisterLiveness registers a liveness probe for the given dependency.
+func (r *Registry) RegisterLiveness(name string, check Check) *Registry {
+	return r.register(name, Liveness, check)
+}
+
+// RegisterReadiness registers a readiness probe for the given dependency.
+func (r *Registry) RegisterReadiness(name string, check Check) *Registry {
+	return r.register(name, Readiness, check)
+}
+
+func (r *Registry) register(name string, probeType ProbeType, check Check) *Registry {
+	r.probesMutex.Lock()
+	defer r.probesMutex.Unlock()
+	r.probes = append(r.probes, &probe{name: name, probeType: probeType, check: check})
+	return r
+}
+
+// DependencyStatus is the outcome of a single probe.
+type DependencyStatus struct {
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	Healthy        bool    `json:"healthy"`
+	Error          string  `json:"error,omitempty"`
+	LatencySeconds float64 `json:"latency_seconds"`
+}
+
+// Report is the outcome of a set of probes.
+type Report struct {
+	Healthy      bool                `json:"healthy"`
+	Dependencies []*DependencyStatus `json:"dependencies"`
+}
+
+// Probe runs every probe of the given type, in parallel. Readiness runs liveness probes too.
+func (r *Registry) Probe(ctx context.Context, probeType ProbeType) *Report {
+	r.probesMutex.RLock()
+	var probes []*probe
+	for _, probe := range r.probes {
+		if probe.probeType <= probeType {
+			probes = append(probes, probe)
+		}
+	}
+	r.probesMutex.RUnlock()
+
+	report := &Report{Healthy: true, Dependencies: make([]*DependencyStatus, len(probes))}
+	waitGroup := sync.WaitGroup{}
+	for i, probe := range probes {
+		i, probe := i, probe
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			report.Dependencies[i] = r.runProbe(ctx, probe)
+		}()
+	}
+	waitGroup.Wait()
+	for _, dependency := range report.Dependencies {
+		report.Healthy = report.Healthy && dependency.Healthy
+	}
+	return report
+}
+
+func (r *Registry) runProbe(ctx context.Context, probe *probe) *DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	err := probe.check(ctx)
+	status := &DependencyStatus{
+		Name:           probe.name,
+		Type:           probe.probeType.String(),
+		Healthy:        err == nil,
+		LatencySeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Liveness returns a check which runs the liveness probes.
+func (r *Registry) Liveness() Check {
+	return r.check(Liveness)
+}
+
+// Readiness returns a check which runs the readiness probes. It can be passed to a grpc server's `WithHealthCheck`.
+func (r *Registry) Readiness() Check {
+	return r.check(Readiness)
+}
+
+func (r *Registry) check(probeType ProbeType) Check {
+	return func(ctx context.Context) error {
+		report := r.Probe(ctx, probeType)
+		for _, dependency := range report.Dependencies {
+			if !dependency.Healthy {
+				return errors.Errorf("%s probe %s failed: %s", dependency.Type, dependency.Name, dependency.Error)
+			}
+		}
+		return nil
+	}
+}
+
+// Handler returns an http handler serving the given probe type's report as JSON.
+// It responds with a 503 if any dependency is unhealthy.
+func (r *Registry) Handler(probeType ProbeType) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		report := r.Probe(request.Context(), probeType)
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			for _, dependency := range report.Dependencies {
+				if !dependency.Healthy {
+					log.Errorf("%s probe %s failed: %s", probeType, dependency.Name, dependency.Error)
+				}
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Errorf("encoding health report: %v", err)
+		}
+	})
+}
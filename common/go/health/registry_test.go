@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	cacheErr := errors.New("cache unreachable")
+	registry := NewRegistry().
+		RegisterLiveness("event-loop", func(context.Context) error { return nil }).
+		RegisterReadiness("database", func(context.Context) error { return nil }).
+		RegisterReadiness("cache", func(context.Context) error { return cacheErr })
+
+	t.Run("liveness ignores readiness probes", func(t *testing.T) {
+		report := registry.Probe(context.Background(), Liveness)
+		require.True(t, report.Healthy)
+		require.Len(t, report.Dependencies, 1)
+		require.NoError(t, registry.Liveness()(context.Background()))
+	})
+
+	t.Run("readiness runs every probe", func(t *testing.T) {
+		report := registry.Probe(context.Background(), Readiness)
+		require.False(t, report.Healthy)
+		require.Len(t, report.Dependencies, 3)
+		require.Equal(t, "cache", report.Dependencies[2].Name)
+		require.Equal(t, cacheErr.Error(), report.Dependencies[2].Error)
+		require.ErrorContains(t, registry.Readiness()(context.Background()), "cache")
+	})
+
+	t.Run("handler reports per dependency status", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		registry.Handler(Readiness).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		report := &Report{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), report))
+		require.Len(t, report.Dependencies, 3)
+	})
+}
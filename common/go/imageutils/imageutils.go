@@ -0,0 +1,176 @@
+package imageutils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const defaultJPEGQuality = 85
+
+// Format is an image encoding format.
+type Format string
+
+// Supported formats. WebP images can be encoded, losslessly, but not decoded.
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+	GIF  Format = "gif"
+	WebP Format = "webp"
+)
+
+// MIMEType returns the format's mime type.
+func (f Format) MIMEType() string {
+	return "image/" + string(f)
+}
+
+// EncodeOpts configures image encoding.
+type EncodeOpts struct {
+	// JPEG quality, from 1 to 100. Defaults to 85.
+	JPEGQuality int
+}
+
+// Decode decodes an image, returning its format.
+func Decode(data []byte) (image.Image, Format, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "decoding image")
+	}
+	return img, Format(format), nil
+}
+
+// Encode encodes an image into the given format. Encoding never carries over metadata (EXIF, etc).
+func Encode(img image.Image, format Format, opts EncodeOpts) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	var err error
+	switch format {
+	case PNG:
+		err = png.Encode(buffer, img)
+	case JPEG:
+		quality := opts.JPEGQuality
+		if quality <= 0 {
+			quality = defaultJPEGQuality
+		}
+		err = jpeg.Encode(buffer, flatten(img), &jpeg.Options{Quality: quality})
+	case GIF:
+		err = gif.Encode(buffer, img, nil)
+	case WebP:
+		err = encodeWebP(buffer, img)
+	default:
+		return nil, errors.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding %s image", format)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Convert re-encodes an image into the given format.
+func Convert(data []byte, format Format, opts EncodeOpts) ([]byte, error) {
+	img, _, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(img, format, opts)
+}
+
+// StripMetadata re-encodes an image in its own format, dropping any metadata such as EXIF location data.
+func StripMetadata(data []byte, opts EncodeOpts) ([]byte, error) {
+	img, format, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(img, format, opts)
+}
+
+// Fit downscales an image so that it fits within the given dimensions, preserving its aspect ratio.
+// Images that already fit are returned as is: Fit never upscales.
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	return Resize(img, atLeastOne(int(float64(width)*scale)), atLeastOne(int(float64(height)*scale)))
+}
+
+// Resize resizes an image to the given dimensions. Each destination pixel averages the source pixels it covers,
+// which avoids the aliasing of nearest neighbour sampling when downscaling.
+func Resize(img image.Image, width, height int) image.Image {
+	src := image.NewRGBA64(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	bounds := src.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	xRatio := float64(bounds.Dx()) / float64(width)
+	yRatio := float64(bounds.Dy()) / float64(height)
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + int(float64(y)*yRatio)
+		y1 := y0 + atLeastOne(bounds.Min.Y+int(float64(y+1)*yRatio)-y0)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + int(float64(x)*xRatio)
+			x1 := x0 + atLeastOne(bounds.Min.X+int(float64(x+1)*xRatio)-x0)
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1 && sy < bounds.Max.Y; sy++ {
+				for sx := x0; sx < x1 && sx < bounds.Max.X; sx++ {
+					pixel := src.RGBA64At(sx, sy)
+					r, g, b, a = r+uint64(pixel.R), g+uint64(pixel.G), b+uint64(pixel.B), a+uint64(pixel.A)
+					count++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / count), G: uint16(g / count), B: uint16(b / count), A: uint16(a / count)})
+		}
+	}
+	return dst
+}
+
+// Thumbnail decodes an image and returns a thumbnail fitting within a square of the given size.
+// Opaque images are encoded as JPEG; images with transparency as PNG.
+func Thumbnail(data []byte, size int, opts EncodeOpts) ([]byte, Format, error) {
+	img, _, err := Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	thumbnail := Fit(img, size, size)
+	format := JPEG
+	if !isOpaque(thumbnail) {
+		format = PNG
+	}
+	bytes, err := Encode(thumbnail, format, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes, format, nil
+}
+
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+// JPEG has no alpha channel, so transparent pixels are composited onto white rather than black.
+func flatten(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+	flattened := image.NewRGBA(img.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flattened
+}
+
+func atLeastOne(value int) int {
+	if value < 1 {
+		return 1
+	}
+	return value
+}
@@ -0,0 +1,41 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	data, err := Encode(img, PNG, EncodeOpts{})
+	require.NoError(t, err)
+
+	thumbnail, format, err := Thumbnail(data, 100, EncodeOpts{})
+	require.NoError(t, err)
+	require.Equal(t, JPEG, format)
+	decoded, decodedFormat, err := Decode(thumbnail)
+	require.NoError(t, err)
+	require.Equal(t, JPEG, decodedFormat)
+	require.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+
+	// Transparent images keep their alpha channel.
+	img.Set(0, 0, color.RGBA{})
+	data, err = Encode(img, PNG, EncodeOpts{})
+	require.NoError(t, err)
+	_, format, err = Thumbnail(data, 100, EncodeOpts{})
+	require.NoError(t, err)
+	require.Equal(t, PNG, format)
+}
+
+func TestFitNeverUpscales(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	require.Equal(t, img, Fit(img, 100, 100))
+}
@@ -0,0 +1,278 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Lossless WebP (VP8L) encoding, as specified by RFC 9649. Pixels are stored as literals behind the subtract green
+// transform, without backward references or color caches: the output is larger than that of libwebp, but encoding is
+// fast and needs no cgo.
+const (
+	webpMaxDimension     = 1 << 14
+	vp8lSignature        = 0x2f
+	vp8lSubtractGreen    = 2
+	vp8lMaxCodeLength    = 15
+	vp8lMaxCodeLengthLen = 7
+	// Green codes also cover the 24 length prefixes of backward references, which we never emit.
+	vp8lGreenAlphabetSize    = 256 + 24
+	vp8lDistanceAlphabetSize = 40
+)
+
+// vp8lCodeLengthOrder is the order in which the code lengths of the code length code are stored.
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP encodes an image as a lossless WebP.
+func encodeWebP(writer io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > webpMaxDimension || height > webpMaxDimension {
+		return errors.Errorf("webp images must be 1 to %d pixels wide and high, got %dx%d", webpMaxDimension, width, height)
+	}
+	// WebP stores non-premultiplied colors.
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+
+	// Channels in the order their codes are stored: green, red, blue, alpha.
+	var histograms [4][]int
+	histograms[0] = make([]int, vp8lGreenAlphabetSize)
+	for i := 1; i < 4; i++ {
+		histograms[i] = make([]int, 256)
+	}
+	pixels := make([][4]uint8, 0, width*height)
+	alphaIsUsed := false
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		r, g, b, a := nrgba.Pix[i], nrgba.Pix[i+1], nrgba.Pix[i+2], nrgba.Pix[i+3]
+		pixel := [4]uint8{g, r - g, b - g, a}
+		for channel, value := range pixel {
+			histograms[channel][value]++
+		}
+		alphaIsUsed = alphaIsUsed || a != 0xff
+		pixels = append(pixels, pixel)
+	}
+
+	bits := &bitWriter{}
+	bits.write(vp8lSignature, 8)
+	bits.write(uint32(width-1), 14)
+	bits.write(uint32(height-1), 14)
+	bits.writeBool(alphaIsUsed)
+	bits.write(0, 3) // Version.
+	bits.writeBool(true)
+	bits.write(vp8lSubtractGreen, 2)
+	bits.writeBool(false) // No more transforms.
+	bits.writeBool(false) // No color cache.
+	bits.writeBool(false) // No meta prefix codes.
+	var codes [4]*prefixCode
+	for channel, histogram := range histograms {
+		codes[channel] = writePrefixCode(bits, histogram)
+	}
+	distanceHistogram := make([]int, vp8lDistanceAlphabetSize)
+	distanceHistogram[0] = 1
+	writePrefixCode(bits, distanceHistogram)
+	for _, pixel := range pixels {
+		for channel, value := range pixel {
+			codes[channel].write(bits, int(value))
+		}
+	}
+
+	payload := bits.bytes()
+	chunkSize := len(payload)
+	padding := chunkSize % 2
+	header := make([]byte, 0, 20)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+chunkSize+padding))
+	header = append(header, "WEBPVP8L"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(chunkSize))
+	for _, chunk := range [][]byte{header, payload, make([]byte, padding)} {
+		if _, err := writer.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bitWriter packs bits least significant first, as VP8L streams are read.
+type bitWriter struct {
+	buffer bytes.Buffer
+	bits   uint64
+	n      uint
+}
+
+func (w *bitWriter) write(value uint32, n uint) {
+	w.bits |= uint64(value) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.buffer.WriteByte(byte(w.bits))
+		w.bits >>= 8
+		w.n -= 8
+	}
+}
+
+func (w *bitWriter) writeBool(value bool) {
+	if value {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.buffer.WriteByte(byte(w.bits))
+		w.bits, w.n = 0, 0
+	}
+	return w.buffer.Bytes()
+}
+
+// prefixCode is a canonical Huffman code.
+type prefixCode struct {
+	lengths []int
+	// Codes are bit reversed, as they are read one bit at a time.
+	codes []uint32
+	// Codes with a single symbol take no bits.
+	singleSymbol bool
+}
+
+func newPrefixCode(lengths []int) *prefixCode {
+	code := &prefixCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+	var lengthCounts [vp8lMaxCodeLength + 1]int
+	used := 0
+	for _, length := range lengths {
+		if length > 0 {
+			lengthCounts[length]++
+			used++
+		}
+	}
+	code.singleSymbol = used == 1
+	var nextCodes [vp8lMaxCodeLength + 2]uint32
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		nextCodes[length+1] = (nextCodes[length] + uint32(lengthCounts[length])) << 1
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		canonical := nextCodes[length]
+		nextCodes[length]++
+		for i := 0; i < length; i++ {
+			code.codes[symbol] = code.codes[symbol]<<1 | (canonical>>i)&1
+		}
+	}
+	return code
+}
+
+func (c *prefixCode) write(bits *bitWriter, symbol int) {
+	if !c.singleSymbol {
+		bits.write(c.codes[symbol], uint(c.lengths[symbol]))
+	}
+}
+
+// writePrefixCode writes the prefix code of a histogram, and returns it.
+func writePrefixCode(bits *bitWriter, histogram []int) *prefixCode {
+	var symbols []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	// Simple codes hold up to two symbols, the first of which may be stored in a single bit.
+	if len(symbols) <= 2 && symbols[len(symbols)-1] < 256 {
+		bits.writeBool(true)
+		bits.write(uint32(len(symbols)-1), 1)
+		if symbols[0] < 2 {
+			bits.write(0, 1)
+			bits.write(uint32(symbols[0]), 1)
+		} else {
+			bits.write(1, 1)
+			bits.write(uint32(symbols[0]), 8)
+		}
+		if len(symbols) == 2 {
+			bits.write(uint32(symbols[1]), 8)
+		}
+		lengths := make([]int, len(histogram))
+		for _, symbol := range symbols {
+			lengths[symbol] = 1
+		}
+		return newPrefixCode(lengths)
+	}
+
+	bits.writeBool(false)
+	lengths := huffmanLengths(histogram, vp8lMaxCodeLength)
+	// Code lengths are themselves stored with a prefix code, without run lengths.
+	codeLengthHistogram := make([]int, len(vp8lCodeLengthOrder))
+	for _, length := range lengths {
+		codeLengthHistogram[length]++
+	}
+	codeLengthLengths := huffmanLengths(codeLengthHistogram, vp8lMaxCodeLengthLen)
+	numCodeLengths := 4
+	for i, symbol := range vp8lCodeLengthOrder {
+		if codeLengthLengths[symbol] > 0 && i+1 > numCodeLengths {
+			numCodeLengths = i + 1
+		}
+	}
+	bits.write(uint32(numCodeLengths-4), 4)
+	for _, symbol := range vp8lCodeLengthOrder[:numCodeLengths] {
+		bits.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+	bits.writeBool(false) // Lengths are stored for every symbol.
+	codeLengthCode := newPrefixCode(codeLengthLengths)
+	for _, length := range lengths {
+		codeLengthCode.write(bits, length)
+	}
+	return newPrefixCode(lengths)
+}
+
+// huffmanLengths returns the code lengths of a Huffman code of the histogram, no longer than maxLength. Frequencies
+// are flattened until the code fits.
+func huffmanLengths(histogram []int, maxLength int) []int {
+	counts := append([]int(nil), histogram...)
+	for {
+		lengths, longest := huffmanTreeLengths(counts)
+		if longest <= maxLength {
+			return lengths
+		}
+		for symbol, count := range counts {
+			if count > 0 {
+				counts[symbol] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanTreeLengths returns the depths of the symbols in a Huffman tree of the histogram, and the largest one.
+func huffmanTreeLengths(histogram []int) ([]int, int) {
+	type node struct {
+		count   int
+		symbols []int
+	}
+	var nodes []node
+	for symbol, count := range histogram {
+		if count > 0 {
+			nodes = append(nodes, node{count: count, symbols: []int{symbol}})
+		}
+	}
+	lengths := make([]int, len(histogram))
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbols[0]] = 1
+		return lengths, 1
+	}
+	longest := 0
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].count < nodes[j].count })
+		merged := node{count: nodes[0].count + nodes[1].count, symbols: append(append([]int(nil), nodes[0].symbols...), nodes[1].symbols...)}
+		for _, symbol := range merged.symbols {
+			lengths[symbol]++
+			if lengths[symbol] > longest {
+				longest = lengths[symbol]
+			}
+		}
+		nodes = append([]node{merged}, nodes[2:]...)
+	}
+	return lengths, longest
+}
@@ -0,0 +1,75 @@
+package imageutils
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	t.Run("EncodesTwoColorImagesWithSimpleCodes", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5; x++ {
+				if (x+y)%2 == 0 {
+					img.Set(x, y, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+				} else {
+					img.Set(x, y, color.NRGBA{R: 200, G: 20, B: 31, A: 255})
+				}
+			}
+		}
+		data, err := Encode(img, WebP, EncodeOpts{})
+		require.NoError(t, err)
+		// Decoded by libwebp to the original pixels.
+		expected := []byte{
+			0x52, 0x49, 0x46, 0x46, 0x1e, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50, 0x56, 0x50, 0x38, 0x4c,
+			0x12, 0x00, 0x00, 0x00, 0x2f, 0x04, 0x80, 0x00, 0x00, 0x45, 0x29, 0x4e, 0x6b, 0x7f, 0x85, 0x85,
+			0xfe, 0x47, 0x66, 0x66, 0x66, 0x06,
+		}
+		require.Equal(t, expected, data)
+	})
+
+	t.Run("WritesAValidContainer", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 97, 61))
+		rand.New(rand.NewSource(1)).Read(img.Pix)
+		data, err := Encode(img, WebP, EncodeOpts{})
+		require.NoError(t, err)
+		require.Equal(t, "RIFF", string(data[:4]))
+		require.EqualValues(t, len(data)-8, binary.LittleEndian.Uint32(data[4:8]))
+		require.Equal(t, "WEBPVP8L", string(data[8:16]))
+		chunkSize := int(binary.LittleEndian.Uint32(data[16:20]))
+		require.Equal(t, len(data)-20, chunkSize+chunkSize%2)
+		require.EqualValues(t, vp8lSignature, data[20])
+		// 14 bits of width - 1, 14 bits of height - 1, then the alpha hint.
+		header := binary.LittleEndian.Uint32(data[21:25])
+		require.EqualValues(t, 96, header&0x3fff)
+		require.EqualValues(t, 60, (header>>14)&0x3fff)
+		require.EqualValues(t, 1, (header>>28)&1)
+	})
+
+	t.Run("RejectsImagesTooLarge", func(t *testing.T) {
+		_, err := Encode(image.NewNRGBA(image.Rect(0, 0, webpMaxDimension+1, 1)), WebP, EncodeOpts{})
+		require.Error(t, err)
+	})
+}
+
+func TestHuffmanLengths(t *testing.T) {
+	// Skewed frequencies would need codes longer than the limit.
+	histogram := make([]int, 20)
+	for i := range histogram {
+		histogram[i] = 1 << i
+	}
+	lengths := huffmanLengths(histogram, 7)
+	kraftSum := 0.0
+	for _, length := range lengths {
+		require.LessOrEqual(t, length, 7)
+		require.Greater(t, length, 0)
+		kraftSum += 1 / float64(int(1)<<length)
+	}
+	// The code is complete, as decoders require.
+	require.Equal(t, 1.0, kraftSum)
+}
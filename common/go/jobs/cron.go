@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cron expressions that never match within this horizon are rejected, e.g. `0 0 30 2 *`.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	// Both 0 and 7 are Sunday.
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Cron is a parsed cron expression, evaluated in UTC.
+type Cron struct {
+	expression  string
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// As in standard cron, a day matches either field when both are restricted.
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+// ParseCron parses a standard 5 field cron expression (minute, hour, day of month, month and day of week), e.g.
+// `*/15 9-17 * * 1-5`. Fields hold `*`, values, ranges and steps, separated by commas. The `@hourly`, `@daily`,
+// `@weekly`, `@monthly` and `@yearly` descriptors are also supported.
+func ParseCron(expression string) (*Cron, error) {
+	fields := strings.Fields(expression)
+	if len(fields) == 1 {
+		if descriptor, ok := cronDescriptors[fields[0]]; ok {
+			fields = strings.Fields(descriptor)
+		}
+	}
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("cron expression [%s] must have %d fields", expression, len(cronFields))
+	}
+	cron := &Cron{expression: expression}
+	bitsets := []*uint64{&cron.minutes, &cron.hours, &cron.daysOfMonth, &cron.months, &cron.daysOfWeek}
+	for i, field := range fields {
+		bitset, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing cron expression [%s]", expression)
+		}
+		*bitsets[i] = bitset
+	}
+	// Sunday is both 0 and 7.
+	if cron.daysOfWeek&(1<<7) != 0 {
+		cron.daysOfWeek |= 1
+	}
+	cron.daysOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	cron.daysOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+	if cron.Next(time.Unix(0, 0)).IsZero() {
+		return nil, errors.Errorf("cron expression [%s] never matches", expression)
+	}
+	return cron, nil
+}
+
+// MustParseCron parses a cron expression and panics if it is invalid.
+func MustParseCron(expression string) *Cron {
+	cron, err := ParseCron(expression)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	return cron
+}
+
+func parseCronField(field string, cronField cronField) (uint64, error) {
+	var bitset uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, errors.Errorf("invalid step [%s] in %s field", stepPart, cronField.name)
+			}
+		}
+		start, end := cronField.min, cronField.max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(startPart, cronField); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = parseCronValue(endPart, cronField); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// `5/10` is short for `5-max/10`.
+				end = cronField.max
+			}
+			if start > end {
+				return 0, errors.Errorf("invalid range [%s] in %s field", rangePart, cronField.name)
+			}
+		}
+		for value := start; value <= end; value += step {
+			bitset |= 1 << value
+		}
+	}
+	return bitset, nil
+}
+
+func parseCronValue(value string, cronField cronField) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < cronField.min || number > cronField.max {
+		return 0, errors.Errorf("invalid value [%s] in %s field, expected %d-%d", value, cronField.name, cronField.min, cronField.max)
+	}
+	return number, nil
+}
+
+// String implements the fmt.Stringer interface.
+func (c *Cron) String() string {
+	return c.expression
+}
+
+// Next returns the first time matching this expression strictly after the given time, in UTC.
+// Returns the zero time if there is none within the next five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(cronHorizon)
+	for t.Before(end) {
+		switch {
+		case c.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case c.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	matchesDayOfMonth := c.daysOfMonth&(1<<uint(t.Day())) != 0
+	matchesDayOfWeek := c.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if c.daysOfMonthRestricted && c.daysOfWeekRestricted {
+		return matchesDayOfMonth || matchesDayOfWeek
+	}
+	return matchesDayOfMonth && matchesDayOfWeek
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expression := range []string{
+		"* * * * *",
+		"*/15 9-17 * * 1-5",
+		"0,30 * * * *",
+		"5/10 * * * *",
+		"0 0 29 2 *",
+		"0 0 * * 7",
+		"@daily",
+		"  0  12 1 *  * ",
+	} {
+		_, err := ParseCron(expression)
+		require.NoError(t, err, expression)
+	}
+
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@never",
+		"0 0 30 2 *",
+	} {
+		_, err := ParseCron(expression)
+		require.Error(t, err, expression)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 15, 0, 0, time.UTC)},
+		{"5/10 * * * *", time.Date(2024, time.January, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Saturday.
+		{"0 0 * * 6", time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC)},
+		// Sunday, as 0 and 7.
+		{"0 0 * * 0", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * 1-5", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		// Either the 15th or a Friday, as both day fields are restricted.
+		{"0 0 15 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		// Both an odd day and a Friday, as `*/2` does not restrict days.
+		{"0 0 */2 * 5", time.Date(2024, time.February, 9, 0, 0, 0, 0, time.UTC)},
+	} {
+		cron, err := ParseCron(tc.expression)
+		require.NoError(t, err, tc.expression)
+		require.Equal(t, tc.expected, cron.Next(now), tc.expression)
+	}
+
+	// Times are evaluated in UTC.
+	location := time.FixedZone("UTC+2", 2*60*60)
+	cron := MustParseCron("0 9 * * *")
+	require.Equal(t, time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC), cron.Next(now.In(location)))
+}
+
+func TestScheduleRunTimes(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC)
+
+	intervalSchedule := &schedule{interval: time.Hour}
+	require.Equal(t, now, intervalSchedule.firstRunTime(now))
+	require.Equal(t, now.Add(time.Hour), intervalSchedule.nextRunTime(now))
+
+	cronSchedule := &schedule{cron: MustParseCron("0 * * * *")}
+	require.Equal(t, time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC), cronSchedule.firstRunTime(now))
+	require.Equal(t, time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC), cronSchedule.nextRunTime(now))
+}
@@ -0,0 +1,64 @@
+// Package jobs implements a postgres backed job queue, with retries, dead lettering and scheduled jobs.
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+const defaultMaxAttempts = 5
+
+// Querier is implemented by postgres clients and transactions, so that jobs can be enqueued within a transaction.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Job is a job claimed by a worker.
+type Job struct {
+	ID      int64
+	Queue   string
+	Payload []byte
+	// Attempt of this job, starting at 1.
+	Attempt     int
+	MaxAttempts int
+}
+
+// Unmarshal unmarshals the payload of this job into the given proto message.
+func (j *Job) Unmarshal(message proto.Message) error {
+	return errors.Wrapf(proto.Unmarshal(j.Payload, message), "unmarshaling payload of job [%d]", j.ID)
+}
+
+// EnqueueOpts configures an enqueued job.
+type EnqueueOpts struct {
+	// When the job should run. Defaults to now.
+	RunTime time.Time
+	// Maximum number of attempts before the job is dead lettered. Defaults to 5.
+	MaxAttempts int
+}
+
+// Enqueue enqueues a job with the given payload, and returns its ID.
+func Enqueue(ctx context.Context, querier Querier, queue string, payload proto.Message, opts EnqueueOpts) (int64, error) {
+	bytes, err := proto.Marshal(payload)
+	if err != nil {
+		return 0, errors.Wrap(err, "marshaling payload")
+	}
+	if opts.RunTime.IsZero() {
+		opts.RunTime = time.Now()
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	var id int64
+	if err := querier.QueryRow(ctx, insertJobQuery, queue, bytes, opts.MaxAttempts, opts.RunTime).Scan(&id); err != nil {
+		return 0, errors.Wrap(err, "inserting job")
+	}
+	return id, nil
+}
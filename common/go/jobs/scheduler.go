@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"common/go/postgres"
+	"common/go/routine"
+)
+
+const defaultSchedulerTickerMs = 1000
+
+type schedule struct {
+	name     string
+	queue    string
+	interval time.Duration
+	cron     *Cron
+	payload  proto.Message
+	opts     EnqueueOpts
+}
+
+// firstRunTime returns the run time of a new schedule.
+func (s *schedule) firstRunTime(now time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(now)
+	}
+	return now
+}
+
+// nextRunTime returns the run time following a run at the given time.
+func (s *schedule) nextRunTime(now time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(now)
+	}
+	return now.Add(s.interval)
+}
+
+// Scheduler enqueues jobs at fixed intervals or on cron schedules. Schedules are stored in postgres, so each run is enqueued once no matter
+// how many replicas run a scheduler.
+type Scheduler struct {
+	client    *postgres.Client
+	schedules []*schedule
+	tickerMs  int
+	routine   *routine.Routine
+}
+
+// NewScheduler instantiates and returns a new Scheduler.
+func NewScheduler(client *postgres.Client) *Scheduler {
+	return &Scheduler{client: client, tickerMs: defaultSchedulerTickerMs}
+}
+
+// WithSchedule enqueues a job with the given payload on the given queue every interval. The first run is enqueued as
+// soon as the scheduler starts, unless the schedule already exists.
+func (s *Scheduler) WithSchedule(name, queue string, interval time.Duration, payload proto.Message, opts EnqueueOpts) *Scheduler {
+	s.schedules = append(s.schedules, &schedule{name: name, queue: queue, interval: interval, payload: payload, opts: opts})
+	return s
+}
+
+// WithCronSchedule enqueues a job with the given payload on the given queue at the times matching the cron expression.
+func (s *Scheduler) WithCronSchedule(name, queue string, cron *Cron, payload proto.Message, opts EnqueueOpts) *Scheduler {
+	s.schedules = append(s.schedules, &schedule{name: name, queue: queue, cron: cron, payload: payload, opts: opts})
+	return s
+}
+
+// Start starts the scheduler. Non-blocking call.
+func (s *Scheduler) Start(ctx context.Context) (*Scheduler, error) {
+	for _, schedule := range s.schedules {
+		if _, err := s.client.Exec(ctx, insertScheduleQuery, schedule.name, schedule.firstRunTime(time.Now())); err != nil {
+			return nil, errors.Wrapf(err, "inserting schedule [%s]", schedule.name)
+		}
+	}
+	s.routine = routine.New("jobs_scheduler", s.enqueueDueJobs).WithTickerMs(s.tickerMs).Start(ctx)
+	return s, nil
+}
+
+// Close stops the scheduler. Blocking call.
+func (s *Scheduler) Close() {
+	if s.routine != nil {
+		s.routine.Close()
+	}
+}
+
+func (s *Scheduler) enqueueDueJobs(ctx context.Context) error {
+	for _, schedule := range s.schedules {
+		schedule := schedule
+		transactionFN := func(ctx context.Context, tx postgres.Tx) error {
+			var name string
+			err := tx.QueryRow(ctx, advanceScheduleQuery, schedule.name, schedule.nextRunTime(time.Now())).Scan(&name)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "advancing schedule")
+			}
+			_, err = Enqueue(ctx, tx, schedule.queue, schedule.payload, schedule.opts)
+			return err
+		}
+		if err := s.client.RunInTx(ctx, transactionFN); err != nil {
+			return errors.Wrapf(err, "enqueuing job of schedule [%s]", schedule.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package jobs
+
+// CreateTablesQuery creates the job tables. Services using jobs should add it to their migrations.
+const CreateTablesQuery = `
+CREATE TABLE IF NOT EXISTS job(
+  id BIGSERIAL PRIMARY KEY,
+  queue TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  attempt INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL,
+  run_time TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  dead BOOLEAN NOT NULL DEFAULT FALSE,
+  last_error TEXT,
+  create_time TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS job_queue_run_time ON job(queue, run_time) WHERE NOT dead;
+CREATE TABLE IF NOT EXISTS job_schedule(
+  name TEXT PRIMARY KEY,
+  next_run_time TIMESTAMPTZ NOT NULL
+);
+`
+
+const insertJobQuery = `
+INSERT INTO job (queue, payload, max_attempts, run_time) VALUES ($1, $2, $3, $4) RETURNING id
+`
+
+// Claims a job by pushing its run time back by the visibility timeout (in ms), so that no other worker claims it
+// unless this one dies. Jobs that exhausted their attempts are never claimed again.
+const claimJobQuery = `
+UPDATE job SET attempt = attempt + 1, run_time = NOW() + $2 * INTERVAL '1 millisecond'
+WHERE id = (
+  SELECT id FROM job WHERE queue = $1 AND NOT dead AND run_time <= NOW() AND attempt < max_attempts
+  ORDER BY run_time LIMIT 1 FOR UPDATE SKIP LOCKED
+)
+RETURNING id, payload, attempt, max_attempts
+`
+
+// Dead letters jobs whose last attempt outlived its visibility timeout, i.e. whose worker died.
+const killExhaustedJobsQuery = `
+UPDATE job SET dead = TRUE, last_error = 'last attempt exceeded its visibility timeout'
+WHERE queue = $1 AND NOT dead AND run_time <= NOW() AND attempt >= max_attempts
+`
+
+const deleteJobQuery = `DELETE FROM job WHERE id = $1`
+
+const retryJobQuery = `
+UPDATE job SET run_time = NOW() + $2 * INTERVAL '1 millisecond', last_error = $3 WHERE id = $1
+`
+
+const killJobQuery = `UPDATE job SET dead = TRUE, last_error = $2 WHERE id = $1`
+
+const insertScheduleQuery = `
+INSERT INTO job_schedule (name, next_run_time) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING
+`
+
+// Moves a due schedule forward to its next run time, returning a row only if it was due, so that a single replica
+// enqueues each run.
+const advanceScheduleQuery = `
+UPDATE job_schedule SET next_run_time = $2
+WHERE name = $1 AND next_run_time <= NOW()
+RETURNING name
+`
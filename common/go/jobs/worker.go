@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"common/go/postgres"
+	"common/go/routine"
+)
+
+const (
+	defaultConcurrency       = 1
+	defaultPollIntervalMs    = 1000
+	defaultVisibilityTimeout = time.Minute
+	retryBaseBackoff         = time.Second
+	retryMaxBackoff          = time.Hour
+)
+
+var (
+	jobsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobs_processed_total",
+			Help: "Jobs processed, by outcome",
+		},
+		[]string{"queue", "outcome"},
+	)
+	jobsDurationHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "jobs_duration_seconds",
+			Help: "Duration of job handlers",
+		},
+		[]string{"queue"},
+	)
+)
+
+// Handler handles a job. Returning an error retries the job with exponential backoff, until it exhausts its attempts,
+// at which point it is dead lettered: it stays in the table, marked as dead, for inspection.
+type Handler func(ctx context.Context, job *Job) error
+
+// workerClient is implemented by postgres clients.
+type workerClient interface {
+	Querier
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Worker processes the jobs of a queue.
+type Worker struct {
+	client            workerClient
+	queue             string
+	handler           Handler
+	concurrency       int
+	pollIntervalMs    int
+	visibilityTimeout time.Duration
+	routines          []*routine.Routine
+}
+
+// NewWorker instantiates and returns a new Worker.
+func NewWorker(client *postgres.Client, queue string, handler Handler) *Worker {
+	return &Worker{
+		client:            client,
+		queue:             queue,
+		handler:           handler,
+		concurrency:       defaultConcurrency,
+		pollIntervalMs:    defaultPollIntervalMs,
+		visibilityTimeout: defaultVisibilityTimeout,
+	}
+}
+
+// WithConcurrency sets the number of jobs processed concurrently.
+func (w *Worker) WithConcurrency(concurrency int) *Worker {
+	w.concurrency = concurrency
+	return w
+}
+
+// WithPollIntervalMs sets how often an idle worker polls for jobs.
+func (w *Worker) WithPollIntervalMs(pollIntervalMs int) *Worker {
+	w.pollIntervalMs = pollIntervalMs
+	return w
+}
+
+// WithVisibilityTimeout sets how long a job is hidden from other workers once claimed. It also bounds handlers.
+func (w *Worker) WithVisibilityTimeout(visibilityTimeout time.Duration) *Worker {
+	w.visibilityTimeout = visibilityTimeout
+	return w
+}
+
+// Start starts the worker. Non-blocking call.
+func (w *Worker) Start(ctx context.Context) *Worker {
+	for i := 0; i < w.concurrency; i++ {
+		name := fmt.Sprintf("jobs_worker_%s_%d", w.queue, i)
+		w.routines = append(w.routines, routine.New(name, w.processJobs).WithTickerMs(w.pollIntervalMs).Start(ctx))
+	}
+	return w
+}
+
+// Close stops the worker, waiting for in-flight jobs. Blocking call.
+func (w *Worker) Close() {
+	routine.CloseInParallelFN(w.routines)()
+}
+
+// Shutdown stops the worker, waiting for in-flight jobs until the context is done. Its signature matches server
+// shutdown hooks.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "waiting for workers of queue [%s]", w.queue)
+	}
+}
+
+// processJobs processes jobs until the queue has none due.
+func (w *Worker) processJobs(ctx context.Context) error {
+	if err := w.killExhaustedJobs(ctx); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		job, err := w.claimJob(ctx)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+		if err := w.processJob(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// killExhaustedJobs dead letters the jobs whose worker died during their last attempt, as they cannot be claimed again.
+func (w *Worker) killExhaustedJobs(ctx context.Context) error {
+	result, err := w.client.Exec(ctx, killExhaustedJobsQuery, w.queue)
+	if err != nil {
+		return errors.Wrap(err, "dead lettering exhausted jobs")
+	}
+	if killed := result.RowsAffected(); killed > 0 {
+		log.Errorf("Dead lettered %d jobs of queue [%s] whose last attempt exceeded its visibility timeout", killed, w.queue)
+		jobsCounter.WithLabelValues(w.queue, "dead").Add(float64(killed))
+	}
+	return nil
+}
+
+func (w *Worker) claimJob(ctx context.Context) (*Job, error) {
+	job := &Job{Queue: w.queue}
+	err := w.client.QueryRow(ctx, claimJobQuery, w.queue, w.visibilityTimeout.Milliseconds()).Scan(&job.ID, &job.Payload, &job.Attempt, &job.MaxAttempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "claiming job")
+	}
+	return job, nil
+}
+
+func (w *Worker) processJob(ctx context.Context, job *Job) error {
+	handlerCtx, cancel := context.WithTimeout(ctx, w.visibilityTimeout)
+	start := time.Now()
+	handlerErr := w.handler(handlerCtx, job)
+	cancel()
+	jobsDurationHistogram.WithLabelValues(w.queue).Observe(time.Since(start).Seconds())
+
+	if handlerErr == nil {
+		jobsCounter.WithLabelValues(w.queue, "success").Inc()
+		_, err := w.client.Exec(ctx, deleteJobQuery, job.ID)
+		return errors.Wrapf(err, "deleting job [%d]", job.ID)
+	}
+
+	if job.Attempt >= job.MaxAttempts {
+		log.Errorf("Job [%d] of queue [%s] failed its last attempt %d/%d: %v", job.ID, w.queue, job.Attempt, job.MaxAttempts, handlerErr)
+		jobsCounter.WithLabelValues(w.queue, "dead").Inc()
+		_, err := w.client.Exec(ctx, killJobQuery, job.ID, handlerErr.Error())
+		return errors.Wrapf(err, "dead lettering job [%d]", job.ID)
+	}
+	log.Warningf("Job [%d] of queue [%s] failed attempt %d/%d: %v", job.ID, w.queue, job.Attempt, job.MaxAttempts, handlerErr)
+	jobsCounter.WithLabelValues(w.queue, "retry").Inc()
+	_, err := w.client.Exec(ctx, retryJobQuery, job.ID, retryBackoff(job.Attempt).Milliseconds(), handlerErr.Error())
+	return errors.Wrapf(err, "retrying job [%d]", job.ID)
+}
+
+// retryBackoff returns the exponential backoff after the given attempt.
+func retryBackoff(attempt int) time.Duration {
+	backoff := float64(retryBaseBackoff) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(retryMaxBackoff) {
+		return retryMaxBackoff
+	}
+	return time.Duration(backoff)
+}
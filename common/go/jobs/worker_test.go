@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type execCall struct {
+	sql  string
+	args []any
+}
+
+// fakeWorkerClient records the statements it executes.
+type fakeWorkerClient struct {
+	Querier
+	calls []execCall
+	err   error
+}
+
+func (c *fakeWorkerClient) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	c.calls = append(c.calls, execCall{sql: sql, args: args})
+	return pgconn.NewCommandTag("UPDATE 1"), c.err
+}
+
+func newTestWorker(handler Handler) (*Worker, *fakeWorkerClient) {
+	client := &fakeWorkerClient{}
+	worker := NewWorker(nil, "queue", handler)
+	worker.client = client
+	return worker, client
+}
+
+func TestRetryBackoff(t *testing.T) {
+	require.Equal(t, time.Second, retryBackoff(1))
+	require.Equal(t, 2*time.Second, retryBackoff(2))
+	require.Equal(t, 4*time.Second, retryBackoff(3))
+	require.Equal(t, 512*time.Second, retryBackoff(10))
+	require.Equal(t, time.Hour, retryBackoff(13))
+	require.Equal(t, time.Hour, retryBackoff(1000))
+}
+
+func TestProcessJob(t *testing.T) {
+	ctx := context.Background()
+	handlerErr := errors.New("handler failed")
+
+	t.Run("DeletesSuccessfulJobs", func(t *testing.T) {
+		worker, client := newTestWorker(func(context.Context, *Job) error { return nil })
+		require.NoError(t, worker.processJob(ctx, &Job{ID: 1, Attempt: 1, MaxAttempts: 3}))
+		require.Equal(t, []execCall{{sql: deleteJobQuery, args: []any{int64(1)}}}, client.calls)
+	})
+
+	t.Run("RetriesFailedJobsWithBackoff", func(t *testing.T) {
+		worker, client := newTestWorker(func(context.Context, *Job) error { return handlerErr })
+		require.NoError(t, worker.processJob(ctx, &Job{ID: 1, Attempt: 2, MaxAttempts: 3}))
+		expectedArgs := []any{int64(1), retryBackoff(2).Milliseconds(), handlerErr.Error()}
+		require.Equal(t, []execCall{{sql: retryJobQuery, args: expectedArgs}}, client.calls)
+	})
+
+	t.Run("DeadLettersJobsOnTheirLastAttempt", func(t *testing.T) {
+		worker, client := newTestWorker(func(context.Context, *Job) error { return handlerErr })
+		require.NoError(t, worker.processJob(ctx, &Job{ID: 1, Attempt: 3, MaxAttempts: 3}))
+		require.Equal(t, []execCall{{sql: killJobQuery, args: []any{int64(1), handlerErr.Error()}}}, client.calls)
+	})
+
+	t.Run("BoundsHandlersByTheVisibilityTimeout", func(t *testing.T) {
+		worker, client := newTestWorker(func(ctx context.Context, _ *Job) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		worker.WithVisibilityTimeout(10 * time.Millisecond)
+		require.NoError(t, worker.processJob(ctx, &Job{ID: 1, Attempt: 1, MaxAttempts: 3}))
+		require.Len(t, client.calls, 1)
+		require.Equal(t, retryJobQuery, client.calls[0].sql)
+	})
+
+	t.Run("ReturnsQueryErrors", func(t *testing.T) {
+		worker, client := newTestWorker(func(context.Context, *Job) error { return nil })
+		client.err = errors.New("connection lost")
+		require.ErrorContains(t, worker.processJob(ctx, &Job{ID: 1, Attempt: 1, MaxAttempts: 3}), "connection lost")
+	})
+}
+
+func TestKillExhaustedJobs(t *testing.T) {
+	worker, client := newTestWorker(nil)
+	require.NoError(t, worker.killExhaustedJobs(context.Background()))
+	require.Equal(t, []execCall{{sql: killExhaustedJobsQuery, args: []any{"queue"}}}, client.calls)
+}
@@ -0,0 +1,56 @@
+package jsonnet
+
+import (
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+)
+
+// EvaluateOpts configures a one-off jsonnet evaluation.
+type EvaluateOpts struct {
+	// Directories searched, in order, for imports that cannot be resolved relative to the importing file.
+	LibraryPaths []string `long:"jsonnet-library-path" description:"Directory searched for jsonnet imports. Can be repeated"`
+	// External variables, accessible via `std.extVar`. String values are injected as is, code values are evaluated.
+	ExtVars  map[string]string `long:"jsonnet-ext-str" description:"Jsonnet external string variable, as key:value. Can be repeated"`
+	ExtCodes map[string]string `long:"jsonnet-ext-code" description:"Jsonnet external code variable, as key:value. Can be repeated"`
+	// Top-level arguments, passed to the evaluated file if it is a function.
+	TLAVars  map[string]string `long:"jsonnet-tla-str" description:"Jsonnet top-level string argument, as key:value. Can be repeated"`
+	TLACodes map[string]string `long:"jsonnet-tla-code" description:"Jsonnet top-level code argument, as key:value. Can be repeated"`
+}
+
+// NewVM returns a jsonnet VM configured with these opts.
+func (o EvaluateOpts) NewVM() *jsonnet.VM {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: o.LibraryPaths})
+	for key, value := range o.ExtVars {
+		vm.ExtVar(key, value)
+	}
+	for key, value := range o.ExtCodes {
+		vm.ExtCode(key, value)
+	}
+	for key, value := range o.TLAVars {
+		vm.TLAVar(key, value)
+	}
+	for key, value := range o.TLACodes {
+		vm.TLACode(key, value)
+	}
+	return vm
+}
+
+// EvaluateFile evaluates the given jsonnet file and returns the resulting JSON.
+func EvaluateFile(filename string, opts EvaluateOpts) (string, error) {
+	content, err := opts.NewVM().EvaluateFile(filename)
+	if err != nil {
+		return "", errors.Wrapf(err, "evaluating %s", filename)
+	}
+	return content, nil
+}
+
+// EvaluateSnippet evaluates the given jsonnet snippet and returns the resulting JSON.
+// The filename is used in error messages and to resolve relative imports.
+func EvaluateSnippet(filename, snippet string, opts EvaluateOpts) (string, error) {
+	content, err := opts.NewVM().EvaluateAnonymousSnippet(filename, snippet)
+	if err != nil {
+		return "", errors.Wrapf(err, "evaluating %s", filename)
+	}
+	return content, nil
+}
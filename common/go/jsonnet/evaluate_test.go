@@ -0,0 +1,31 @@
+package jsonnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateFile(t *testing.T) {
+	libraryPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(libraryPath, "lib.libsonnet"), []byte(`{ panel(title):: { title: title } }`), 0o644))
+	filename := filepath.Join(t.TempDir(), "dashboard.jsonnet")
+	snippet := `local lib = import 'lib.libsonnet'; function(uid) { uid: uid, env: std.extVar('env'), panels: [lib.panel('cpu')] }`
+	require.NoError(t, os.WriteFile(filename, []byte(snippet), 0o644))
+
+	opts := EvaluateOpts{
+		LibraryPaths: []string{libraryPath},
+		ExtVars:      map[string]string{"env": "prod"},
+		TLAVars:      map[string]string{"uid": "abc"},
+	}
+	content, err := EvaluateFile(filename, opts)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"uid": "abc", "env": "prod", "panels": [{"title": "cpu"}]}`, content)
+
+	// Without library paths, the import cannot be resolved.
+	opts.LibraryPaths = nil
+	_, err = EvaluateFile(filename, opts)
+	require.Error(t, err)
+}
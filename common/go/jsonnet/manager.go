@@ -296,7 +296,7 @@ func (m *ReloadableConfig) load() error {
 	}
 	start := time.Now()
 	if m.observeEvaluationTimeFN != nil {
-		defer m.observeEvaluationTimeFN(time.Since(start).Seconds())
+		defer func() { m.observeEvaluationTimeFN(time.Since(start).Seconds()) }()
 	}
 	m.vm.Importer(m) // This flushes the internal vm cache, such that we can pick up file changes.
 	// Reset import paths.
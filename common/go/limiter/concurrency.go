@@ -0,0 +1,77 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Semaphores is an in-memory ConcurrencyLimiter holding a semaphore per key.
+type Semaphores struct {
+	max int
+
+	mutex      sync.Mutex
+	semaphores map[string]*semaphore
+}
+
+var _ ConcurrencyLimiter = (*Semaphores)(nil)
+
+type semaphore struct {
+	slots chan struct{}
+	// Number of holders and waiters, so that we can drop unused semaphores.
+	users int
+}
+
+// NewSemaphores instantiates and returns new Semaphores allowing `max` concurrent operations per key.
+// `max` must be positive, as no operation could ever acquire a semaphore otherwise.
+func NewSemaphores(max int) (*Semaphores, error) {
+	if max <= 0 {
+		return nil, errors.Errorf("max concurrent operations must be positive, got %d", max)
+	}
+	return &Semaphores{max: max, semaphores: map[string]*semaphore{}}, nil
+}
+
+// MustNewSemaphores calls NewSemaphores and panics on error.
+func MustNewSemaphores(max int) *Semaphores {
+	semaphores, err := NewSemaphores(max)
+	if err != nil {
+		log.Panic(err)
+	}
+	return semaphores
+}
+
+// Acquire implements the ConcurrencyLimiter interface.
+func (s *Semaphores) Acquire(ctx context.Context, key string) (func(), error) {
+	s.mutex.Lock()
+	sem, ok := s.semaphores[key]
+	if !ok {
+		sem = &semaphore{slots: make(chan struct{}, s.max)}
+		s.semaphores[key] = sem
+	}
+	sem.users++
+	s.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		s.done(key, sem)
+		return nil, ctx.Err()
+	case sem.slots <- struct{}{}:
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			<-sem.slots
+			s.done(key, sem)
+		})
+	}
+	return release, nil
+}
+
+func (s *Semaphores) done(key string, sem *semaphore) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sem.users--; sem.users == 0 {
+		delete(s.semaphores, key)
+	}
+}
@@ -0,0 +1,28 @@
+// Package limiter provides rate and concurrency limits, keyed so that a single limiter can cap many callers or
+// upstreams independently. In-memory limiters cap a single process, while redis backed limiters are shared by all
+// replicas of a service.
+//
+// This package does not depend on a redis client: callers of redis backed limiters must supply one, adapted to the
+// RedisClient interface.
+package limiter
+
+import (
+	"context"
+
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+// RateLimiter limits the rate of operations per key.
+type RateLimiter interface {
+	// Wait blocks until an operation for the given key is allowed, or the context is done.
+	Wait(ctx context.Context, key string) error
+}
+
+// ConcurrencyLimiter limits the number of concurrent operations per key.
+type ConcurrencyLimiter interface {
+	// Acquire blocks until an operation for the given key may start, or the context is done.
+	// The returned function must be called once the operation completes.
+	Acquire(ctx context.Context, key string) (func(), error)
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	tokenBuckets := NewTokenBuckets(Rate{PerSecond: 2, Burst: 2})
+	tokenBuckets.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := tokenBuckets.Allow("a")
+		require.True(t, ok)
+	}
+	ok, wait := tokenBuckets.Allow("a")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// Keys are independent.
+	ok, _ = tokenBuckets.Allow("b")
+	require.True(t, ok)
+
+	now = now.Add(wait)
+	ok, _ = tokenBuckets.Allow("a")
+	require.True(t, ok)
+}
+
+func TestTokenBucketsStayBounded(t *testing.T) {
+	now := time.Unix(0, 0)
+	tokenBuckets := NewTokenBuckets(Rate{PerSecond: 1, Burst: 1})
+	tokenBuckets.now = func() time.Time { return now }
+	ok, _ := tokenBuckets.Allow("hot")
+	require.True(t, ok)
+
+	// Keys that each spend a token, whose buckets never refill.
+	for i := 0; i < 2*maxTokenBuckets; i++ {
+		ok, _ := tokenBuckets.Allow(fmt.Sprintf("key-%d", i))
+		require.True(t, ok)
+		if i%100 == 0 {
+			ok, _ := tokenBuckets.Allow("hot")
+			require.False(t, ok)
+		}
+	}
+	require.Len(t, tokenBuckets.buckets, maxTokenBuckets)
+	require.Equal(t, maxTokenBuckets, tokenBuckets.lru.Len())
+
+	// Recently used keys keep their bucket, while the least recently used ones were evicted.
+	ok, _ = tokenBuckets.Allow("hot")
+	require.False(t, ok)
+	ok, _ = tokenBuckets.Allow("key-0")
+	require.True(t, ok)
+}
+
+func TestSemaphores(t *testing.T) {
+	ctx := context.Background()
+	semaphores, err := NewSemaphores(1)
+	require.NoError(t, err)
+
+	release, err := semaphores.Acquire(ctx, "a")
+	require.NoError(t, err)
+
+	// Keys are independent.
+	releaseB, err := semaphores.Acquire(ctx, "b")
+	require.NoError(t, err)
+	releaseB()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = semaphores.Acquire(timeoutCtx, "a")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = semaphores.Acquire(ctx, "a")
+	require.NoError(t, err)
+	release()
+	require.Empty(t, semaphores.semaphores)
+}
+
+func TestNewSemaphoresValidatesMax(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		_, err := NewSemaphores(max)
+		require.Error(t, err)
+		require.Panics(t, func() { MustNewSemaphores(max) })
+	}
+}
@@ -0,0 +1,236 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/uuid"
+)
+
+const (
+	// redisSemaphorePollInterval is how often a full redis semaphore is polled for a free slot.
+	redisSemaphorePollInterval = 50 * time.Millisecond
+	// redisReleaseTimeout bounds releases, which run even if the context of the operation is done.
+	redisReleaseTimeout = 5 * time.Second
+)
+
+// Scripts use the redis server's clock, so that replicas with skewed clocks share buckets and leases consistently.
+const (
+	// KEYS[1] is the bucket, ARGV is the rate per second and the burst.
+	// Returns whether a token was taken, and the microseconds until a token is available if not.
+	redisTokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`
+	// KEYS[1] is a sorted set of leases scored by expiry, ARGV is the max, the lease and its ttl in milliseconds.
+	// Returns 1 if the lease was added.
+	redisSemaphoreAcquireScript = `
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[1]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+	// KEYS[1] is a sorted set of leases, ARGV is the lease and its ttl in milliseconds.
+	// Returns 0 if the lease had already expired.
+	redisSemaphoreRenewScript = `
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+  return 0
+end
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`
+	// KEYS[1] is a sorted set of leases, ARGV is the lease.
+	redisSemaphoreReleaseScript = `return redis.call('ZREM', KEYS[1], ARGV[1])`
+)
+
+// RedisClient evaluates Lua scripts on a redis server. Any redis client can be adapted to it, e.g. with go-redis:
+//
+//	func (a *adapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return a.client.Eval(ctx, script, keys, args...).Result()
+//	}
+//
+// Integer replies are expected as int64, and array replies as []any.
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// RedisTokenBuckets is a RateLimiter holding a token bucket per key in redis, so that replicas share limits.
+type RedisTokenBuckets struct {
+	client RedisClient
+	prefix string
+	rate   Rate
+}
+
+var _ RateLimiter = (*RedisTokenBuckets)(nil)
+
+// NewRedisTokenBuckets instantiates and returns new RedisTokenBuckets. Buckets are stored under `{prefix}:{key}`.
+func NewRedisTokenBuckets(client RedisClient, prefix string, rate Rate) (*RedisTokenBuckets, error) {
+	if rate.PerSecond <= 0 || rate.Burst <= 0 {
+		return nil, errors.Errorf("rate must have a positive rate per second and burst, got %+v", rate)
+	}
+	return &RedisTokenBuckets{client: client, prefix: prefix, rate: rate}, nil
+}
+
+// Allow takes a token from the bucket of the given key. If the bucket is empty, it returns false and how long until a
+// token is available.
+func (t *RedisTokenBuckets) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	keys := []string{t.prefix + ":" + key}
+	reply, err := t.client.Eval(ctx, redisTokenBucketScript, keys, t.rate.PerSecond, t.rate.Burst)
+	if err != nil {
+		return false, 0, errors.Wrapf(err, "taking token of %s", key)
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, errors.Errorf("unexpected token bucket reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return false, 0, errors.Errorf("unexpected token bucket reply %v", reply)
+	}
+	waitMicroseconds, ok := values[1].(int64)
+	if !ok {
+		return false, 0, errors.Errorf("unexpected token bucket reply %v", reply)
+	}
+	return allowed == 1, time.Duration(waitMicroseconds) * time.Microsecond, nil
+}
+
+// Wait implements the RateLimiter interface.
+func (t *RedisTokenBuckets) Wait(ctx context.Context, key string) error {
+	for {
+		ok, wait, err := t.Allow(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RedisSemaphores is a ConcurrencyLimiter holding a semaphore per key in redis, so that replicas share limits.
+// Each holder has a lease, renewed while it holds the semaphore, so that the slots of crashed holders are freed once
+// their lease expires.
+type RedisSemaphores struct {
+	client   RedisClient
+	prefix   string
+	max      int
+	leaseTTL time.Duration
+}
+
+var _ ConcurrencyLimiter = (*RedisSemaphores)(nil)
+
+// NewRedisSemaphores instantiates and returns new RedisSemaphores allowing `max` concurrent operations per key.
+// Semaphores are stored under `{prefix}:{key}`.
+func NewRedisSemaphores(client RedisClient, prefix string, max int, leaseTTL time.Duration) (*RedisSemaphores, error) {
+	if max <= 0 {
+		return nil, errors.Errorf("max concurrent operations must be positive, got %d", max)
+	}
+	if leaseTTL < time.Millisecond {
+		return nil, errors.Errorf("lease ttl must be at least a millisecond, got %s", leaseTTL)
+	}
+	return &RedisSemaphores{client: client, prefix: prefix, max: max, leaseTTL: leaseTTL}, nil
+}
+
+// Acquire implements the ConcurrencyLimiter interface.
+func (s *RedisSemaphores) Acquire(ctx context.Context, key string) (func(), error) {
+	keys := []string{s.prefix + ":" + key}
+	lease := uuid.MustNew()
+	ttl := s.leaseTTL.Milliseconds()
+	for {
+		reply, err := s.client.Eval(ctx, redisSemaphoreAcquireScript, keys, s.max, lease, ttl)
+		if err != nil {
+			return nil, errors.Wrapf(err, "acquiring semaphore of %s", key)
+		}
+		acquired, ok := reply.(int64)
+		if !ok {
+			return nil, errors.Errorf("unexpected semaphore reply %v", reply)
+		}
+		if acquired == 1 {
+			break
+		}
+		timer := time.NewTimer(redisSemaphorePollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renew(keys, lease, done)
+	}()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			<-renewed
+			ctx, cancel := context.WithTimeout(context.Background(), redisReleaseTimeout)
+			defer cancel()
+			if _, err := s.client.Eval(ctx, redisSemaphoreReleaseScript, keys, lease); err != nil {
+				log.Warningf("Could not release semaphore of %s, it will be freed once its lease expires: %v", key, err)
+			}
+		})
+	}
+	return release, nil
+}
+
+// renew renews a lease every third of its ttl, until done is closed.
+func (s *RedisSemaphores) renew(keys []string, lease string, done <-chan struct{}) {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.leaseTTL/3)
+		reply, err := s.client.Eval(ctx, redisSemaphoreRenewScript, keys, lease, s.leaseTTL.Milliseconds())
+		cancel()
+		switch {
+		case err != nil:
+			log.Warningf("Could not renew semaphore lease of %s: %v", keys[0], err)
+		case reply == int64(0):
+			log.Warningf("Semaphore lease of %s expired before it was renewed", keys[0])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type redisCall struct {
+	script string
+	keys   []string
+	args   []any
+}
+
+// fakeRedis records script evaluations, and replies with the result of the reply function.
+type fakeRedis struct {
+	mutex sync.Mutex
+	calls []*redisCall
+	reply func(call *redisCall) (any, error)
+}
+
+func (r *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	call := &redisCall{script: script, keys: keys, args: args}
+	r.calls = append(r.calls, call)
+	return r.reply(call)
+}
+
+// scriptCalls returns the calls of the given script.
+func (r *fakeRedis) scriptCalls(script string) []*redisCall {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var calls []*redisCall
+	for _, call := range r.calls {
+		if call.script == script {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestNewRedisTokenBuckets(t *testing.T) {
+	_, err := NewRedisTokenBuckets(&fakeRedis{}, "limits", Rate{PerSecond: 0, Burst: 1})
+	require.Error(t, err)
+	_, err = NewRedisTokenBuckets(&fakeRedis{}, "limits", Rate{PerSecond: 1, Burst: 0})
+	require.Error(t, err)
+}
+
+func TestRedisTokenBuckets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Allow", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return []any{int64(0), int64(250000)}, nil }}
+		tokenBuckets, err := NewRedisTokenBuckets(redis, "limits", Rate{PerSecond: 2, Burst: 4})
+		require.NoError(t, err)
+		ok, wait, err := tokenBuckets.Allow(ctx, "openai")
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, 250*time.Millisecond, wait)
+		require.Equal(t, []string{"limits:openai"}, redis.calls[0].keys)
+		require.Equal(t, []any{float64(2), 4}, redis.calls[0].args)
+	})
+
+	t.Run("WaitRetriesUntilAllowed", func(t *testing.T) {
+		redis := &fakeRedis{}
+		redis.reply = func(*redisCall) (any, error) {
+			if len(redis.calls) < 3 {
+				return []any{int64(0), int64(1000)}, nil
+			}
+			return []any{int64(1), int64(0)}, nil
+		}
+		tokenBuckets, err := NewRedisTokenBuckets(redis, "limits", Rate{PerSecond: 1, Burst: 1})
+		require.NoError(t, err)
+		require.NoError(t, tokenBuckets.Wait(ctx, "openai"))
+		require.Len(t, redis.calls, 3)
+	})
+
+	t.Run("WaitStopsWithItsContext", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return []any{int64(0), int64(time.Hour / time.Microsecond)}, nil }}
+		tokenBuckets, err := NewRedisTokenBuckets(redis, "limits", Rate{PerSecond: 1, Burst: 1})
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, tokenBuckets.Wait(ctx, "openai"), context.DeadlineExceeded)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return nil, errors.New("connection refused") }}
+		tokenBuckets, err := NewRedisTokenBuckets(redis, "limits", Rate{PerSecond: 1, Burst: 1})
+		require.NoError(t, err)
+		require.ErrorContains(t, tokenBuckets.Wait(ctx, "openai"), "connection refused")
+
+		redis.reply = func(*redisCall) (any, error) { return int64(1), nil }
+		_, _, err = tokenBuckets.Allow(ctx, "openai")
+		require.ErrorContains(t, err, "unexpected token bucket reply")
+	})
+}
+
+func TestNewRedisSemaphores(t *testing.T) {
+	_, err := NewRedisSemaphores(&fakeRedis{}, "limits", 0, time.Second)
+	require.Error(t, err)
+	_, err = NewRedisSemaphores(&fakeRedis{}, "limits", 1, 0)
+	require.Error(t, err)
+}
+
+func TestRedisSemaphores(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("AcquireAndRelease", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return int64(1), nil }}
+		semaphores, err := NewRedisSemaphores(redis, "limits", 2, time.Minute)
+		require.NoError(t, err)
+		release, err := semaphores.Acquire(ctx, "openai")
+		require.NoError(t, err)
+
+		acquires := redis.scriptCalls(redisSemaphoreAcquireScript)
+		require.Len(t, acquires, 1)
+		require.Equal(t, []string{"limits:openai"}, acquires[0].keys)
+		lease := acquires[0].args[1]
+		require.Equal(t, []any{2, lease, int64(60000)}, acquires[0].args)
+
+		release()
+		release()
+		releases := redis.scriptCalls(redisSemaphoreReleaseScript)
+		require.Len(t, releases, 1)
+		require.Equal(t, []any{lease}, releases[0].args)
+	})
+
+	t.Run("WaitsForAFreeSlot", func(t *testing.T) {
+		redis := &fakeRedis{}
+		redis.reply = func(*redisCall) (any, error) {
+			if len(redis.calls) < 3 {
+				return int64(0), nil
+			}
+			return int64(1), nil
+		}
+		semaphores, err := NewRedisSemaphores(redis, "limits", 1, time.Minute)
+		require.NoError(t, err)
+		release, err := semaphores.Acquire(ctx, "openai")
+		require.NoError(t, err)
+		release()
+		require.Len(t, redis.scriptCalls(redisSemaphoreAcquireScript), 3)
+	})
+
+	t.Run("AcquireStopsWithItsContext", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return int64(0), nil }}
+		semaphores, err := NewRedisSemaphores(redis, "limits", 1, time.Minute)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = semaphores.Acquire(ctx, "openai")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, redis.scriptCalls(redisSemaphoreReleaseScript))
+	})
+
+	t.Run("RenewsLeasesUntilReleased", func(t *testing.T) {
+		redis := &fakeRedis{reply: func(*redisCall) (any, error) { return int64(1), nil }}
+		semaphores, err := NewRedisSemaphores(redis, "limits", 1, 30*time.Millisecond)
+		require.NoError(t, err)
+		release, err := semaphores.Acquire(ctx, "openai")
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return len(redis.scriptCalls(redisSemaphoreRenewScript)) >= 2 }, time.Second, time.Millisecond)
+		release()
+
+		renewals := len(redis.scriptCalls(redisSemaphoreRenewScript))
+		time.Sleep(50 * time.Millisecond)
+		require.Len(t, redis.scriptCalls(redisSemaphoreRenewScript), renewals)
+	})
+}
@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxTokenBuckets is the maximum number of buckets held, above which the least recently used bucket is evicted.
+const maxTokenBuckets = 10000
+
+// Rate is a token bucket rate.
+type Rate struct {
+	// Number of tokens added to a bucket every second.
+	PerSecond float64
+	// Maximum number of tokens in a bucket, i.e. the largest burst of operations allowed.
+	Burst int
+}
+
+// TokenBuckets is an in-memory RateLimiter holding a token bucket per key. At most `maxTokenBuckets` buckets are held,
+// so that high cardinality keys cannot grow it without bound. Evicting a bucket grants its key a full bucket again,
+// which only the least recently used keys get.
+type TokenBuckets struct {
+	rate Rate
+	now  func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*list.Element
+	// Buckets, from most to least recently used.
+	lru *list.List
+}
+
+var _ RateLimiter = (*TokenBuckets)(nil)
+
+// NewTokenBuckets instantiates and returns new TokenBuckets.
+func NewTokenBuckets(rate Rate) *TokenBuckets {
+	return &TokenBuckets{rate: rate, now: time.Now, buckets: map[string]*list.Element{}, lru: list.New()}
+}
+
+// Allow takes a token from the bucket of the given key. If the bucket is empty, it returns false and how long until a
+// token is available.
+func (t *TokenBuckets) Allow(key string) (bool, time.Duration) {
+	now := t.now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	element, ok := t.buckets[key]
+	if ok {
+		t.lru.MoveToFront(element)
+	} else {
+		if len(t.buckets) >= maxTokenBuckets {
+			evicted := t.lru.Remove(t.lru.Back()).(*tokenBucket)
+			delete(t.buckets, evicted.key)
+		}
+		element = t.lru.PushFront(&tokenBucket{key: key, tokens: float64(t.rate.Burst), updated: now})
+		t.buckets[key] = element
+	}
+	return element.Value.(*tokenBucket).take(t.rate, now)
+}
+
+// Wait implements the RateLimiter interface.
+func (t *TokenBuckets) Wait(ctx context.Context, key string) error {
+	for {
+		ok, wait := t.Allow(key)
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+func (b *tokenBucket) refill(rate Rate, now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(float64(rate.Burst), b.tokens+elapsed*rate.PerSecond)
+	b.updated = now
+}
+
+func (b *tokenBucket) take(rate Rate, now time.Time) (bool, time.Duration) {
+	b.refill(rate, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if rate.PerSecond <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration((1 - b.tokens) / rate.PerSecond * float64(time.Second))
+}
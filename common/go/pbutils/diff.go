@@ -0,0 +1,126 @@
+package pbutils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldDiff is a difference between two messages at a given field path.
+type FieldDiff struct {
+	// Path of the field, e.g. "a.b".
+	Path string
+	// Value of the field in each message. Invalid if the field is not set.
+	Before protoreflect.Value
+	After  protoreflect.Value
+}
+
+// String implements the fmt.Stringer interface.
+func (d *FieldDiff) String() string {
+	return fmt.Sprintf("%s: %v -> %v", d.Path, d.Before.Interface(), d.After.Interface())
+}
+
+// Diff returns the fields that differ between two messages of the same type, in field declaration order.
+// Singular message fields set in both messages are diffed recursively. Other fields, including repeated and map
+// fields, are compared as a whole.
+func Diff(a, b proto.Message) ([]*FieldDiff, error) {
+	aReflect, bReflect := a.ProtoReflect(), b.ProtoReflect()
+	if aReflect.Descriptor().FullName() != bReflect.Descriptor().FullName() {
+		return nil, errors.Errorf("cannot diff %s against %s", aReflect.Descriptor().FullName(), bReflect.Descriptor().FullName())
+	}
+	return diff("", aReflect, bReflect), nil
+}
+
+func diff(prefix string, a, b protoreflect.Message) []*FieldDiff {
+	var diffs []*FieldDiff
+	fields := a.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		path := string(field.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		aHas, bHas := a.Has(field), b.Has(field)
+		switch {
+		case !aHas && !bHas:
+			continue
+		case aHas && bHas && field.Message() != nil && field.Cardinality() != protoreflect.Repeated:
+			diffs = append(diffs, diff(path, a.Get(field).Message(), b.Get(field).Message())...)
+			continue
+		case aHas && bHas && fieldEqual(a, b, field):
+			continue
+		}
+		fieldDiff := &FieldDiff{Path: path}
+		if aHas {
+			fieldDiff.Before = a.Get(field)
+		}
+		if bHas {
+			fieldDiff.After = b.Get(field)
+		}
+		diffs = append(diffs, fieldDiff)
+	}
+	return diffs
+}
+
+// fieldEqual compares a field of two messages by copying it into otherwise empty messages.
+func fieldEqual(a, b protoreflect.Message, field protoreflect.FieldDescriptor) bool {
+	aField, bField := a.New(), b.New()
+	aField.Set(field, a.Get(field))
+	bField.Set(field, b.Get(field))
+	return proto.Equal(aField.Interface(), bField.Interface())
+}
+
+// ApplyPatch overwrites the given paths of a message with the values of a patch of the same type. Paths that are not
+// set in the patch are cleared, as per AIP-134. The "*" path replaces the whole message.
+// Note that the given paths are structured as follow: "a.b,a.c" etc.
+func ApplyPatch(message, patch proto.Message, paths string) error {
+	messageReflect, patchReflect := message.ProtoReflect(), patch.ProtoReflect()
+	if messageReflect.Descriptor().FullName() != patchReflect.Descriptor().FullName() {
+		return errors.Errorf("cannot patch %s with %s", messageReflect.Descriptor().FullName(), patchReflect.Descriptor().FullName())
+	}
+	// We don't want the message to share any reference with the patch.
+	patch = proto.Clone(patch)
+	patchReflect = patch.ProtoReflect()
+
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if path == "*" {
+			proto.Reset(message)
+			proto.Merge(message, patch)
+			continue
+		}
+		if err := applyPatch(messageReflect, patchReflect, strings.Split(path, ".")); err != nil {
+			return errors.Wrapf(err, "applying path %s", path)
+		}
+	}
+	return nil
+}
+
+func applyPatch(message, patch protoreflect.Message, names []string) error {
+	field := message.Descriptor().Fields().ByName(protoreflect.Name(names[0]))
+	if field == nil {
+		return errors.Errorf("%s has no field %s", message.Descriptor().FullName(), names[0])
+	}
+	if len(names) == 1 {
+		if patch.Has(field) {
+			message.Set(field, patch.Get(field))
+		} else {
+			message.Clear(field)
+		}
+		return nil
+	}
+	if field.Message() == nil || field.Cardinality() == protoreflect.Repeated {
+		return errors.Errorf("cannot traverse field %s", field.FullName())
+	}
+	// Nothing to clear in the message.
+	if !patch.Has(field) && !message.Has(field) {
+		return nil
+	}
+	return applyPatch(message.Mutable(field).Message(), patch.Get(field).Message(), names[1:])
+}
@@ -0,0 +1,71 @@
+package pbutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDiff(t *testing.T) {
+	a := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Package:    proto.String("a"),
+		Dependency: []string{"b.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("a"), JavaPackage: proto.String("a")},
+	}
+	b := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Dependency: []string{"b.proto", "c.proto"},
+		Syntax:     proto.String("proto3"),
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("b"), JavaPackage: proto.String("a")},
+	}
+
+	diffs, err := Diff(a, b)
+	require.NoError(t, err)
+	var paths []string
+	for _, diff := range diffs {
+		paths = append(paths, diff.Path)
+	}
+	require.Equal(t, []string{"package", "dependency", "options.go_package", "syntax"}, paths)
+	require.Equal(t, "a", diffs[0].Before.String())
+	require.False(t, diffs[0].After.IsValid())
+	require.Equal(t, "b", diffs[2].After.String())
+
+	diffs, err = Diff(a, proto.Clone(a))
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	_, err = Diff(a, &descriptorpb.FileOptions{})
+	require.Error(t, err)
+}
+
+func TestApplyPatch(t *testing.T) {
+	message := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Package: proto.String("a"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("a"), JavaPackage: proto.String("a")},
+	}
+	patch := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("b.proto"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("b")},
+	}
+
+	require.NoError(t, ApplyPatch(message, patch, "package,options.go_package"))
+	expected := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("b"), JavaPackage: proto.String("a")},
+	}
+	require.True(t, proto.Equal(expected, message))
+
+	// The message does not share references with the patch.
+	patch.Options.GoPackage = proto.String("c")
+	require.Equal(t, "b", message.GetOptions().GetGoPackage())
+
+	require.NoError(t, ApplyPatch(message, patch, "*"))
+	require.True(t, proto.Equal(patch, message))
+
+	require.Error(t, ApplyPatch(message, patch, "unknown"))
+	require.Error(t, ApplyPatch(message, patch, "name.unknown"))
+}
@@ -0,0 +1,138 @@
+package pbutils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// gzipMagic prefixes every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// JSONLWriter streams proto messages as JSONL, i.e. one protojson encoded message per line.
+type JSONLWriter struct {
+	writer         io.Writer
+	bufferedWriter *bufio.Writer
+	gzipWriter     *gzip.Writer
+	marshalOptions protojson.MarshalOptions
+}
+
+// NewJSONLWriter instantiates and returns a new JSONL writer. It must be closed to flush its output.
+func NewJSONLWriter(writer io.Writer) *JSONLWriter {
+	return &JSONLWriter{writer: writer, bufferedWriter: bufio.NewWriter(writer)}
+}
+
+// WithGzip gzips the output. It must be called before any message is written.
+func (w *JSONLWriter) WithGzip() *JSONLWriter {
+	w.gzipWriter = gzip.NewWriter(w.writer)
+	w.bufferedWriter = bufio.NewWriter(w.gzipWriter)
+	return w
+}
+
+// WithMarshalOptions sets the options used to encode messages. Multiline output is not allowed.
+func (w *JSONLWriter) WithMarshalOptions(marshalOptions protojson.MarshalOptions) *JSONLWriter {
+	marshalOptions.Multiline = false
+	marshalOptions.Indent = ""
+	w.marshalOptions = marshalOptions
+	return w
+}
+
+// Write writes a message on its own line.
+func (w *JSONLWriter) Write(message proto.Message) error {
+	data, err := w.marshalOptions.Marshal(message)
+	if err != nil {
+		return errors.Wrapf(err, "marshaling %s", message.ProtoReflect().Descriptor().FullName())
+	}
+	if _, err := w.bufferedWriter.Write(data); err != nil {
+		return errors.Wrap(err, "writing message")
+	}
+	if err := w.bufferedWriter.WriteByte('\n'); err != nil {
+		return errors.Wrap(err, "writing message")
+	}
+	return nil
+}
+
+// Close flushes the writer. It does not close the underlying writer.
+func (w *JSONLWriter) Close() error {
+	if err := w.bufferedWriter.Flush(); err != nil {
+		return errors.Wrap(err, "flushing")
+	}
+	if w.gzipWriter != nil {
+		if err := w.gzipWriter.Close(); err != nil {
+			return errors.Wrap(err, "closing gzip writer")
+		}
+	}
+	return nil
+}
+
+// JSONLReader streams proto messages from JSONL. Gzipped input is detected and decompressed transparently.
+// By default, unknown fields are rejected.
+type JSONLReader struct {
+	reader           *bufio.Reader
+	gzipReader       *gzip.Reader
+	unmarshalOptions protojson.UnmarshalOptions
+	line             int
+}
+
+// NewJSONLReader instantiates and returns a new JSONL reader.
+func NewJSONLReader(reader io.Reader) (*JSONLReader, error) {
+	bufferedReader := bufio.NewReader(reader)
+	magic, err := bufferedReader.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "peeking input")
+	}
+	jsonlReader := &JSONLReader{reader: bufferedReader}
+	if bytes.Equal(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return nil, errors.Wrap(err, "instantiating gzip reader")
+		}
+		jsonlReader.gzipReader = gzipReader
+		jsonlReader.reader = bufio.NewReader(gzipReader)
+	}
+	return jsonlReader, nil
+}
+
+// WithLenient discards unknown fields instead of rejecting them, e.g. to read files written by a newer schema.
+func (r *JSONLReader) WithLenient() *JSONLReader {
+	r.unmarshalOptions.DiscardUnknown = true
+	return r
+}
+
+// Read reads the next message into the given message. Blank lines are skipped.
+// It returns io.EOF once every message has been read.
+func (r *JSONLReader) Read(message proto.Message) error {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return errors.Wrap(err, "reading line")
+		}
+		if len(line) == 0 && err == io.EOF {
+			return io.EOF
+		}
+		r.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := r.unmarshalOptions.Unmarshal(line, message); err != nil {
+			return errors.Wrapf(err, "unmarshaling line %d", r.line)
+		}
+		return nil
+	}
+}
+
+// Close closes the reader. It does not close the underlying reader.
+func (r *JSONLReader) Close() error {
+	if r.gzipReader != nil {
+		if err := r.gzipReader.Close(); err != nil {
+			return errors.Wrap(err, "closing gzip reader")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package pbutils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestJSONL(t *testing.T) {
+	messages := []*descriptorpb.FileDescriptorProto{
+		{Name: proto.String("a.proto"), Package: proto.String("a")},
+		{Name: proto.String("b.proto"), Dependency: []string{"a.proto"}},
+		{},
+	}
+	for _, withGzip := range []bool{false, true} {
+		buffer := &bytes.Buffer{}
+		writer := NewJSONLWriter(buffer)
+		if withGzip {
+			writer.WithGzip()
+		}
+		for _, message := range messages {
+			require.NoError(t, writer.Write(message))
+		}
+		require.NoError(t, writer.Close())
+		if !withGzip {
+			require.Equal(t, len(messages), strings.Count(buffer.String(), "\n"))
+		}
+
+		reader, err := NewJSONLReader(buffer)
+		require.NoError(t, err)
+		for _, expected := range messages {
+			message := &descriptorpb.FileDescriptorProto{}
+			require.NoError(t, reader.Read(message))
+			require.True(t, proto.Equal(expected, message))
+		}
+		require.ErrorIs(t, reader.Read(&descriptorpb.FileDescriptorProto{}), io.EOF)
+		require.NoError(t, reader.Close())
+	}
+}
+
+func TestJSONLUnknownFields(t *testing.T) {
+	input := "{\"name\":\"a.proto\",\"unknown\":1}\n\n{\"name\":\"b.proto\"}"
+
+	reader, err := NewJSONLReader(strings.NewReader(input))
+	require.NoError(t, err)
+	require.ErrorContains(t, reader.Read(&descriptorpb.FileDescriptorProto{}), "line 1")
+
+	reader, err = NewJSONLReader(strings.NewReader(input))
+	require.NoError(t, err)
+	reader.WithLenient()
+	message := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, reader.Read(message))
+	require.Equal(t, "a.proto", message.GetName())
+	require.NoError(t, reader.Read(message))
+	require.Equal(t, "b.proto", message.GetName())
+	require.ErrorIs(t, reader.Read(message), io.EOF)
+}
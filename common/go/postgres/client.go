@@ -4,6 +4,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -28,33 +30,57 @@ type Opts struct {
 	Host     string `long:"postgres_host"     env:"POSTGRES_HOST"     default:"database" description:"Postgres host"`
 	Port     int    `long:"postgres_port"     env:"POSTGRES_PORT"     default:"3000"     description:"Postgres port"`
 	User     string `long:"postgres_user"     env:"POSTGRES_USER"     default:"postgres" description:"Postgres username"`
-	Password string `long:"postgres_password" env:"POSTGRES_PASSWORD" default:"postgres" description:"Postgres password"`
+	Password string `long:"postgres_password" env:"POSTGRES_PASSWORD" default:"postgres" description:"Postgres password, or a secret reference" secret-ref:"true"`
 	Database string `long:"postgres_database" env:"POSTGRES_DATABASE" default:"postgres" description:"Postgres database"`
+
+	ReplicaHosts  []string      `long:"postgres_replica_host"    env:"POSTGRES_REPLICA_HOSTS"   env-delim:"," description:"Postgres read replica host[:port], using the primary's credentials"`
+	MaxReplicaLag time.Duration `long:"postgres_max_replica_lag" env:"POSTGRES_MAX_REPLICA_LAG" default:"0s"  description:"Replicas lagging behind by more than this are not read from. Disabled if 0"`
+
+	SlowQueryThreshold time.Duration `long:"postgres_slow_query_threshold" env:"POSTGRES_SLOW_QUERY_THRESHOLD" default:"0s" description:"Queries slower than this are logged. Disabled if 0"`
 }
 
 // Client is a wrapper around sqlx db to avoid importing it in core packages.
 type Client struct {
 	Opts Opts
 	*pgxpool.Pool
+
+	replicas       []*replica
+	replicaCounter atomic.Uint32
+	// Stops monitoring replicas.
+	cancel context.CancelFunc
 }
 
 // NewClient instantiates and returns a new Postgres Client. Returns an error if it fails to ping server.
 func NewClient(opts Opts) (*Client, error) {
+	pool, err := newPool(opts, opts.Host, opts.Port)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{Opts: opts, Pool: pool}
+	if err := client.connectReplicas(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func newPool(opts Opts, host string, port int) (*pgxpool.Pool, error) {
 	psqlInfo := fmt.Sprintf(
 		"host=%s port=%d user=%s dbname=%s password=%s sslmode=disable",
-		opts.Host, opts.Port, opts.User, opts.Database, opts.Password,
+		host, port, opts.User, opts.Database, opts.Password,
 	)
-	log.Infof("Connecting to postgres server on [%s:%d]", opts.Host, opts.Port)
+	log.Infof("Connecting to postgres server on [%s:%d]", host, port)
 	config, err := pgxpool.ParseConfig(psqlInfo)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing configuration")
 	}
+	config.ConnConfig.Tracer = &queryTracer{slowQueryThreshold: opts.SlowQueryThreshold}
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating pool")
 	}
-	log.Infof("Connected to postgres server on [%s:%d]", opts.Host, opts.Port)
-	return &Client{Opts: opts, Pool: pool}, nil
+	log.Infof("Connected to postgres server on [%s:%d]", host, port)
+	return pool, nil
 }
 
 // MustNewClient connects and pings the db, then returns it. It panics if an error occurs
@@ -66,6 +92,14 @@ func MustNewClient(opts Opts) *Client {
 	return db
 }
 
+// HealthCheck pings the primary. It can be registered as a readiness probe.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	if err := c.Pool.Ping(ctx); err != nil {
+		return errors.Wrap(err, "pinging postgres")
+	}
+	return nil
+}
+
 // ExecuteTransaction executes a transaction and retries serialization failures.
 func (c *Client) ExecuteTransaction(ctx context.Context, isolationLevel pgx.TxIsoLevel, fn func(pgx.Tx) error) error {
 	return pgx.BeginTxFunc(ctx, c.Pool, pgx.TxOptions{IsoLevel: isolationLevel}, fn)
@@ -4,6 +4,8 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"io"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"time"
 
@@ -18,6 +20,14 @@ var logger = logging.NewLogger()
 // FileLoader loads a file and returns bytes.
 type FileLoader func(string) ([]byte, error)
 
+// NewFSFileLoader returns a FileLoader that loads files from the given file system, typically an embed.FS
+// so that migrations ship with the service binary.
+func NewFSFileLoader(fileSystem fs.FS) FileLoader {
+	return func(filepath string) ([]byte, error) {
+		return fs.ReadFile(fileSystem, path.Clean(filepath))
+	}
+}
+
 // Migration is the database representation of migration.
 type Migration struct {
 	Directory          string    `db:"directory"`
@@ -26,6 +36,9 @@ type Migration struct {
 	ExecutionTimestamp time.Time `db:"execution_timestamp"`
 	SQLQuery           string
 	ExpectedHash       string
+	// Optional SQL query reverting this migration.
+	DownFilename string
+	DownSQLQuery string
 }
 
 // Name returns a "{directory}:{filename}" string for clear/consistent logging.
@@ -36,8 +49,9 @@ func (m *Migration) Name() string {
 // File is used to parse migrations files.
 type File struct {
 	Migrations []struct {
-		Filename string `yaml:"filename"`
-		Hash     string `yaml:"hash"`
+		Filename     string `yaml:"filename"`
+		Hash         string `yaml:"hash"`
+		DownFilename string `yaml:"down_filename"`
 	}
 }
 
@@ -77,12 +91,22 @@ func GetMigrations(fileLoader FileLoader, migrationDirectory string) ([]*Migrati
 			return nil, errors.Wrapf(err, "could not open migration %s/%s", migrationDirectory, migration.Filename)
 		}
 		sqlQuery := string(migrationFileBytes)
+		var downSQLQuery string
+		if migration.DownFilename != "" {
+			downMigrationFileBytes, err := fileLoader(migrationDirectory + "/" + migration.DownFilename)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not open down migration %s/%s", migrationDirectory, migration.DownFilename)
+			}
+			downSQLQuery = string(downMigrationFileBytes)
+		}
 		migrations = append(migrations, &Migration{
 			Directory:    filepath.Base(migrationDirectory),
 			Filename:     migration.Filename,
 			SQLQuery:     sqlQuery,
 			Hash:         ComputeMigrationHash(sqlQuery),
 			ExpectedHash: migration.Hash,
+			DownFilename: migration.DownFilename,
+			DownSQLQuery: downSQLQuery,
 		})
 	}
 	return migrations, nil
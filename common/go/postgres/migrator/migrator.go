@@ -60,20 +60,119 @@ func (m *Migrator) InitializeDatabase(ctx context.Context, database, user, passw
 	return nil
 }
 
-// RunMigrations runs migrations.
+// RunMigrations runs migrations. Concurrent migrators wait for each other.
 func (m *Migrator) RunMigrations(ctx context.Context, fileLoader migrations.FileLoader, migrationsDirectories ...string) error {
 	log.Infof("Migrator started")
-	if err := m.createMigrationsTableIfNotExist(ctx); err != nil {
+	err := m.withAdvisoryLock(ctx, func() error {
+		// Created under the lock, as concurrent `CREATE TABLE IF NOT EXISTS` can fail on a pg_type unique violation.
+		if err := m.createMigrationsTableIfNotExist(ctx); err != nil {
+			return err
+		}
+		for _, migrationsDirectory := range migrationsDirectories {
+			log.Infof("Running [%s] migrations", filepath.Base(migrationsDirectory))
+			if err := m.runMigrations(ctx, fileLoader, migrationsDirectory); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	log.Infof("Migrator shutting down")
+	return nil
+}
+
+// PlanMigrations returns the migrations RunMigrations would apply, without applying them.
+func (m *Migrator) PlanMigrations(ctx context.Context, fileLoader migrations.FileLoader, migrationsDirectories ...string) ([]*migrations.Migration, error) {
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*migrations.Migration
 	for _, migrationsDirectory := range migrationsDirectories {
-		log.Infof("Running [%s] migrations", filepath.Base(migrationsDirectory))
-		if err := m.runMigrations(ctx, fileLoader, migrationsDirectory); err != nil {
+		directoryMigrations, err := migrations.GetMigrations(fileLoader, migrationsDirectory)
+		if err != nil {
+			return nil, err
+		}
+		directoryPending, err := planMigrations(directoryMigrations, applied)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, directoryPending...)
+	}
+	return pending, nil
+}
+
+// RollbackMigrations reverts the last `count` applied migrations of the given directory, using their down migrations.
+func (m *Migrator) RollbackMigrations(ctx context.Context, fileLoader migrations.FileLoader, migrationsDirectory string, count int) error {
+	directoryMigrations, err := migrations.GetMigrations(fileLoader, migrationsDirectory)
+	if err != nil {
+		return err
+	}
+	return m.withAdvisoryLock(ctx, func() error {
+		applied, err := m.getAppliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		rollbacks, err := planRollback(directoryMigrations, applied, count)
+		if err != nil {
 			return err
 		}
+		for _, migration := range rollbacks {
+			transactionFN := func(tx postgres.Tx) error {
+				if _, err := tx.Exec(ctx, migration.DownSQLQuery); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, deleteMigrationByHashQuery, migration.Directory, migration.Filename, migration.Hash)
+				return err
+			}
+			if err := m.client.ExecuteTransaction(ctx, postgres.Serializable, transactionFN); err != nil {
+				return errors.Wrapf(err, "could not revert migration [%s]", migration.Name())
+			}
+			log.Infof("Migration [%s] reverted", migration.Name())
+		}
+		return nil
+	})
+}
+
+// planMigrations returns the migrations of a directory that are not applied yet, in order.
+// Migrations whose content does not match the hash declared in migrations.yaml are rejected.
+func planMigrations(directoryMigrations []*migrations.Migration, applied map[migrationKey]struct{}) ([]*migrations.Migration, error) {
+	var pending []*migrations.Migration
+	for _, migration := range directoryMigrations {
+		if migration.ExpectedHash != "" && migration.ExpectedHash != migration.Hash {
+			return nil, errors.Errorf("migration [%s] checksum mismatch: expected %s, got %s", migration.Name(), migration.ExpectedHash, migration.Hash)
+		}
+		if _, ok := applied[newMigrationKey(migration)]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// planRollback returns the last `count` applied migrations of a directory, in the order they must be reverted.
+// It fails if any of them has no down migration, so that nothing is reverted.
+func planRollback(directoryMigrations []*migrations.Migration, applied map[migrationKey]struct{}, count int) ([]*migrations.Migration, error) {
+	var rollbacks []*migrations.Migration
+	for i := len(directoryMigrations) - 1; i >= 0 && len(rollbacks) < count; i-- {
+		migration := directoryMigrations[i]
+		if _, ok := applied[newMigrationKey(migration)]; !ok {
+			continue
+		}
+		if migration.DownSQLQuery == "" {
+			return nil, errors.Errorf("migration [%s] has no down migration", migration.Name())
+		}
+		rollbacks = append(rollbacks, migration)
+	}
+	return rollbacks, nil
+}
+
+// MustRollbackMigrations reverts migrations or panics.
+func (m *Migrator) MustRollbackMigrations(ctx context.Context, fileLoader migrations.FileLoader, migrationsDirectory string, count int) {
+	if err := m.RollbackMigrations(ctx, fileLoader, migrationsDirectory, count); err != nil {
+		log.Panicf("Error rolling back migrations: %v", err)
 	}
-	log.Infof("Migrator shutting down")
-	return nil
 }
 
 // MustRunMigrations runs migrations or panics.
@@ -83,6 +182,59 @@ func (m *Migrator) MustRunMigrations(ctx context.Context, fileLoader migrations.
 	}
 }
 
+// withAdvisoryLock calls fn while holding the migrations advisory lock.
+func (m *Migrator) withAdvisoryLock(ctx context.Context, fn func() error) error {
+	// Advisory locks are held by a session, so we hold on to a connection.
+	connection, err := m.client.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquiring connection")
+	}
+	defer connection.Release()
+	if _, err := connection.Exec(ctx, acquireAdvisoryLockQuery, migrationsAdvisoryLockKey); err != nil {
+		return errors.Wrap(err, "acquiring advisory lock")
+	}
+	defer func() {
+		if _, err := connection.Exec(context.Background(), releaseAdvisoryLockQuery, migrationsAdvisoryLockKey); err != nil {
+			log.Errorf("Could not release advisory lock: %v", err)
+		}
+	}()
+	return fn()
+}
+
+type migrationKey struct {
+	directory string
+	filename  string
+	hash      string
+}
+
+func newMigrationKey(migration *migrations.Migration) migrationKey {
+	return migrationKey{directory: migration.Directory, filename: migration.Filename, hash: migration.Hash}
+}
+
+func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[migrationKey]struct{}, error) {
+	applied := map[migrationKey]struct{}{}
+	var exists bool
+	if err := m.client.QueryRow(ctx, migrationTableExistsQuery).Scan(&exists); err != nil {
+		return nil, errors.Wrap(err, "checking migration table exists")
+	}
+	if !exists {
+		return applied, nil
+	}
+	rows, err := m.client.Query(ctx, selectMigrationsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting migrations")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key migrationKey
+		if err := rows.Scan(&key.directory, &key.filename, &key.hash); err != nil {
+			return nil, errors.Wrap(err, "scanning migration")
+		}
+		applied[key] = struct{}{}
+	}
+	return applied, errors.Wrap(rows.Err(), "iterating over migrations")
+}
+
 func (m *Migrator) createMigrationsTableIfNotExist(ctx context.Context) error {
 	if _, err := m.client.Exec(ctx, creationMigrationTableQuery); err != nil {
 		return errors.Wrap(err, "could not create migration table")
@@ -91,11 +243,20 @@ func (m *Migrator) createMigrationsTableIfNotExist(ctx context.Context) error {
 }
 
 func (m *Migrator) runMigrations(ctx context.Context, fileLoader migrations.FileLoader, migrationDirectory string) error {
-	migrations, err := migrations.GetMigrations(fileLoader, migrationDirectory)
+	directoryMigrations, err := migrations.GetMigrations(fileLoader, migrationDirectory)
+	if err != nil {
+		return err
+	}
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := planMigrations(directoryMigrations, applied)
 	if err != nil {
 		return err
 	}
-	for _, migration := range migrations {
+	log.Infof("%d/%d [%s] migrations already applied", len(directoryMigrations)-len(pending), len(directoryMigrations), filepath.Base(migrationDirectory))
+	for _, migration := range pending {
 		if err := m.runMigration(ctx, migration); err != nil {
 			log.Errorf("Could not run migration [%s]", migration.Name())
 			return err
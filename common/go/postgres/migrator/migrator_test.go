@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/go/postgres/migrator/migrations"
+)
+
+func newTestMigrations(filenames ...string) []*migrations.Migration {
+	var directoryMigrations []*migrations.Migration
+	for _, filename := range filenames {
+		sqlQuery := "-- " + filename
+		directoryMigrations = append(directoryMigrations, &migrations.Migration{
+			Directory:    "library",
+			Filename:     filename,
+			SQLQuery:     sqlQuery,
+			Hash:         migrations.ComputeMigrationHash(sqlQuery),
+			DownSQLQuery: "-- down " + filename,
+		})
+	}
+	return directoryMigrations
+}
+
+func newApplied(directoryMigrations ...*migrations.Migration) map[migrationKey]struct{} {
+	applied := map[migrationKey]struct{}{}
+	for _, migration := range directoryMigrations {
+		applied[newMigrationKey(migration)] = struct{}{}
+	}
+	return applied
+}
+
+func filenames(directoryMigrations []*migrations.Migration) []string {
+	var filenames []string
+	for _, migration := range directoryMigrations {
+		filenames = append(filenames, migration.Filename)
+	}
+	return filenames
+}
+
+func TestPlanMigrations(t *testing.T) {
+	directoryMigrations := newTestMigrations("001.sql", "002.sql", "003.sql", "004.sql")
+
+	pending, err := planMigrations(directoryMigrations, newApplied())
+	require.NoError(t, err)
+	require.Equal(t, []string{"001.sql", "002.sql", "003.sql", "004.sql"}, filenames(pending))
+
+	// Pending migrations keep their declaration order, even with gaps.
+	pending, err = planMigrations(directoryMigrations, newApplied(directoryMigrations[0], directoryMigrations[2]))
+	require.NoError(t, err)
+	require.Equal(t, []string{"002.sql", "004.sql"}, filenames(pending))
+
+	pending, err = planMigrations(directoryMigrations, newApplied(directoryMigrations...))
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// A migration modified since it was applied has a new hash, so it is pending again.
+	modified := newTestMigrations("001.sql")
+	modified[0].SQLQuery += "\n-- modified"
+	modified[0].Hash = migrations.ComputeMigrationHash(modified[0].SQLQuery)
+	pending, err = planMigrations(modified, newApplied(directoryMigrations[0]))
+	require.NoError(t, err)
+	require.Equal(t, []string{"001.sql"}, filenames(pending))
+}
+
+func TestPlanMigrationsChecksumMismatch(t *testing.T) {
+	directoryMigrations := newTestMigrations("001.sql", "002.sql")
+	directoryMigrations[0].ExpectedHash = directoryMigrations[0].Hash
+	_, err := planMigrations(directoryMigrations, newApplied())
+	require.NoError(t, err)
+
+	directoryMigrations[1].ExpectedHash = "0123456789abcdef"
+	_, err = planMigrations(directoryMigrations, newApplied())
+	require.ErrorContains(t, err, "migration [library:002.sql] checksum mismatch")
+}
+
+func TestPlanRollback(t *testing.T) {
+	directoryMigrations := newTestMigrations("001.sql", "002.sql", "003.sql", "004.sql")
+	applied := newApplied(directoryMigrations[0], directoryMigrations[1], directoryMigrations[2])
+
+	rollbacks, err := planRollback(directoryMigrations, applied, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"003.sql"}, filenames(rollbacks))
+
+	rollbacks, err = planRollback(directoryMigrations, applied, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"003.sql", "002.sql"}, filenames(rollbacks))
+
+	// Rolling back more migrations than applied reverts them all.
+	rollbacks, err = planRollback(directoryMigrations, applied, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"003.sql", "002.sql", "001.sql"}, filenames(rollbacks))
+
+	rollbacks, err = planRollback(directoryMigrations, applied, 0)
+	require.NoError(t, err)
+	require.Empty(t, rollbacks)
+
+	// Nothing is reverted if a migration in range has no down migration.
+	directoryMigrations[1].DownSQLQuery = ""
+	_, err = planRollback(directoryMigrations, applied, 1)
+	require.NoError(t, err)
+	_, err = planRollback(directoryMigrations, applied, 2)
+	require.ErrorContains(t, err, "migration [library:002.sql] has no down migration")
+}
@@ -9,6 +9,21 @@ CREATE TABLE IF NOT EXISTS migration(
   CONSTRAINT unique_migrations UNIQUE(directory, filename, hash)
 )
 `
+const migrationTableExistsQuery = `SELECT to_regclass('migration') IS NOT NULL`
+
+const selectMigrationsQuery = `SELECT directory, filename, hash FROM migration`
+
+const deleteMigrationByHashQuery = `
+DELETE FROM migration WHERE directory = $1 AND filename = $2 AND hash = $3
+`
+
+// Arbitrary key of the advisory lock held by migrators, so that concurrent migrators don't race.
+const migrationsAdvisoryLockKey = 4238571934
+
+const acquireAdvisoryLockQuery = `SELECT pg_advisory_lock($1)`
+
+const releaseAdvisoryLockQuery = `SELECT pg_advisory_unlock($1)`
+
 const insertMigrationByHashQuery = `
 INSERT INTO migration (directory, filename, hash) VALUES ($1, $2, $3) 
 ON CONFLICT(directory, filename, hash) DO NOTHING
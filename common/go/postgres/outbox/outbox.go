@@ -0,0 +1,192 @@
+// Package outbox implements the transactional outbox pattern: events are inserted in the same transaction as the
+// resources they describe, and a dispatcher publishes them with at-least-once delivery.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/logging"
+	"common/go/postgres"
+	"common/go/routine"
+)
+
+var log = logging.NewLogger()
+
+const (
+	defaultBatchSize         = 100
+	defaultBatchTimeout      = 30 * time.Second
+	defaultIntervalMs        = 1000
+	dispatcherRoutineName    = "outbox_dispatcher"
+	dispatcherBackOffSeconds = 5
+)
+
+// Event is a change event of a resource.
+type Event struct {
+	ID int64
+	// Name of the resource this event is about. Events of a resource are published in order.
+	Resource string
+	// Type of event, e.g. "created".
+	Type       string
+	Payload    []byte
+	CreateTime time.Time
+}
+
+// Insert inserts an event in the outbox, within the given transaction, so that it is published if and only if the
+// transaction commits. The event's ID and CreateTime are set.
+func Insert(ctx context.Context, tx postgres.Tx, event *Event) error {
+	if err := tx.QueryRow(ctx, insertEventQuery, event.Resource, event.Type, event.Payload).Scan(&event.ID, &event.CreateTime); err != nil {
+		return errors.Wrap(err, "inserting outbox event")
+	}
+	return nil
+}
+
+// Publisher publishes events. Events may be published more than once, so consumers should deduplicate on their ID.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// PublisherFunc is a function implementing the Publisher interface.
+type PublisherFunc func(ctx context.Context, event *Event) error
+
+// Publish implements the Publisher interface.
+func (f PublisherFunc) Publish(ctx context.Context, event *Event) error { return f(ctx, event) }
+
+// Dispatcher publishes outbox events and deletes them once published.
+type Dispatcher struct {
+	client       *postgres.Client
+	publisher    Publisher
+	batchSize    int
+	batchTimeout time.Duration
+	intervalMs   int
+	routine      *routine.Routine
+}
+
+// NewDispatcher instantiates and returns a new Dispatcher.
+func NewDispatcher(client *postgres.Client, publisher Publisher) *Dispatcher {
+	return &Dispatcher{
+		client:       client,
+		publisher:    publisher,
+		batchSize:    defaultBatchSize,
+		batchTimeout: defaultBatchTimeout,
+		intervalMs:   defaultIntervalMs,
+	}
+}
+
+// WithBatchSize sets the maximum number of events published per run.
+func (d *Dispatcher) WithBatchSize(batchSize int) *Dispatcher {
+	d.batchSize = batchSize
+	return d
+}
+
+// WithBatchTimeout bounds the time spent publishing a batch, during which the dispatcher holds a transaction open.
+// Events not published in time are left for the next run.
+func (d *Dispatcher) WithBatchTimeout(batchTimeout time.Duration) *Dispatcher {
+	d.batchTimeout = batchTimeout
+	return d
+}
+
+// WithIntervalMs sets the interval between runs.
+func (d *Dispatcher) WithIntervalMs(intervalMs int) *Dispatcher {
+	d.intervalMs = intervalMs
+	return d
+}
+
+// Start starts the dispatcher. Non-blocking call.
+func (d *Dispatcher) Start(ctx context.Context) *Dispatcher {
+	d.routine = routine.New(dispatcherRoutineName, d.Dispatch).
+		WithTickerMs(d.intervalMs).
+		WithConstantBackOff(dispatcherBackOffSeconds).
+		Start(ctx)
+	return d
+}
+
+// Close stops the dispatcher. Blocking call.
+func (d *Dispatcher) Close() {
+	if d.routine != nil {
+		d.routine.Close()
+	}
+}
+
+// Dispatch publishes a batch of events, in order. If an event fails to publish, subsequent events of its resource are
+// left in the outbox for the next run, so that resources never see events out of order.
+// Events are published within the transaction holding the dispatcher's advisory lock, so that published events are
+// deleted atomically. The batch timeout bounds how long that transaction stays open.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	var numFailedResources int
+	transactionFN := func(ctx context.Context, tx postgres.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, tryAcquireAdvisoryLockQuery, dispatcherAdvisoryLockKey).Scan(&locked); err != nil {
+			return errors.Wrap(err, "acquiring advisory lock")
+		}
+		if !locked {
+			// Another dispatcher is active.
+			return nil
+		}
+
+		events, err := selectEvents(ctx, tx, d.batchSize)
+		if err != nil {
+			return err
+		}
+		publishCtx, cancel := context.WithTimeout(ctx, d.batchTimeout)
+		defer cancel()
+		var publishedIDs []int64
+		publishedIDs, numFailedResources = publishEvents(publishCtx, d.publisher, events)
+		if len(publishedIDs) == 0 {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, deleteEventsQuery, publishedIDs); err != nil {
+			return errors.Wrap(err, "deleting published outbox events")
+		}
+		return nil
+	}
+	if err := d.client.RunInTx(ctx, transactionFN); err != nil {
+		return err
+	}
+	if numFailedResources > 0 {
+		return errors.Errorf("could not publish events of %d resources", numFailedResources)
+	}
+	return nil
+}
+
+// publishEvents publishes the given events in order, until the context is done. Returns the IDs of published events and
+// the number of resources that failed to publish.
+func publishEvents(ctx context.Context, publisher Publisher, events []*Event) ([]int64, int) {
+	failedResources := map[string]struct{}{}
+	publishedIDs := make([]int64, 0, len(events))
+	for i, event := range events {
+		if ctx.Err() != nil {
+			log.Warningf("Batch timeout exceeded, leaving %d outbox events for the next run", len(events)-i)
+			break
+		}
+		if _, ok := failedResources[event.Resource]; ok {
+			continue
+		}
+		if err := publisher.Publish(ctx, event); err != nil {
+			log.Warningf("Could not publish outbox event [%d] of [%s]: %v", event.ID, event.Resource, err)
+			failedResources[event.Resource] = struct{}{}
+			continue
+		}
+		publishedIDs = append(publishedIDs, event.ID)
+	}
+	return publishedIDs, len(failedResources)
+}
+
+func selectEvents(ctx context.Context, tx postgres.Tx, limit int) ([]*Event, error) {
+	rows, err := tx.Query(ctx, selectEventsQuery, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting outbox events")
+	}
+	defer rows.Close()
+	var events []*Event
+	for rows.Next() {
+		event := &Event{}
+		if err := rows.Scan(&event.ID, &event.Resource, &event.Type, &event.Payload, &event.CreateTime); err != nil {
+			return nil, errors.Wrap(err, "scanning outbox event")
+		}
+		events = append(events, event)
+	}
+	return events, errors.Wrap(rows.Err(), "iterating over outbox events")
+}
@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishEvents(t *testing.T) {
+	ctx := context.Background()
+	events := []*Event{
+		{ID: 1, Resource: "books/1"},
+		{ID: 2, Resource: "books/2"},
+		{ID: 3, Resource: "books/1"},
+		{ID: 4, Resource: "books/3"},
+		{ID: 5, Resource: "books/2"},
+	}
+
+	t.Run("PublishesInOrder", func(t *testing.T) {
+		var publishedIDs []int64
+		publisher := PublisherFunc(func(ctx context.Context, event *Event) error {
+			publishedIDs = append(publishedIDs, event.ID)
+			return nil
+		})
+		ids, numFailedResources := publishEvents(ctx, publisher, events)
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, publishedIDs)
+		require.Zero(t, numFailedResources)
+	})
+
+	t.Run("SkipsSubsequentEventsOfFailedResources", func(t *testing.T) {
+		var attemptedIDs []int64
+		publisher := PublisherFunc(func(ctx context.Context, event *Event) error {
+			attemptedIDs = append(attemptedIDs, event.ID)
+			if event.ID == 1 {
+				return errors.New("unavailable")
+			}
+			return nil
+		})
+		ids, numFailedResources := publishEvents(ctx, publisher, events)
+		// Event 3 must not be published before event 1.
+		require.Equal(t, []int64{2, 4, 5}, ids)
+		require.Equal(t, []int64{1, 2, 4, 5}, attemptedIDs)
+		require.Equal(t, 1, numFailedResources)
+	})
+
+	t.Run("CountsEachFailedResourceOnce", func(t *testing.T) {
+		publisher := PublisherFunc(func(ctx context.Context, event *Event) error {
+			if event.Resource == "books/3" {
+				return nil
+			}
+			return errors.New("unavailable")
+		})
+		ids, numFailedResources := publishEvents(ctx, publisher, events)
+		require.Equal(t, []int64{4}, ids)
+		require.Equal(t, 2, numFailedResources)
+	})
+
+	t.Run("StopsOnceTheContextIsDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		publisher := PublisherFunc(func(ctx context.Context, event *Event) error {
+			if event.ID == 2 {
+				cancel()
+			}
+			return nil
+		})
+		ids, numFailedResources := publishEvents(ctx, publisher, events)
+		require.Equal(t, []int64{1, 2}, ids)
+		require.Zero(t, numFailedResources)
+	})
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	ctx := context.Background()
+	createTime := time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC)
+	event := &Event{ID: 42, Resource: "books/1", Type: "created", Payload: []byte("payload"), CreateTime: createTime}
+
+	t.Run("PostsEvents", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.Equal(t, "42", r.Header.Get("Idempotency-Key"))
+			received := &webhookEvent{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+			require.Equal(t, &webhookEvent{ID: 42, Resource: "books/1", Type: "created", Payload: []byte("payload"), CreateTime: createTime}, received)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+		require.NoError(t, NewWebhookPublisher(server.URL).Publish(ctx, event))
+	})
+
+	t.Run("FailsOnErrorStatuses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		err := NewWebhookPublisher(server.URL).Publish(ctx, event)
+		require.ErrorContains(t, err, "503")
+		require.ErrorContains(t, err, "overloaded")
+	})
+}
@@ -0,0 +1,28 @@
+package outbox
+
+// CreateTableQuery creates the outbox table. Services using the outbox should add it to their migrations.
+const CreateTableQuery = `
+CREATE TABLE IF NOT EXISTS outbox_event(
+  id BIGSERIAL PRIMARY KEY,
+  resource TEXT NOT NULL,
+  type TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  create_time TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)
+`
+
+const insertEventQuery = `
+INSERT INTO outbox_event (resource, type, payload) VALUES ($1, $2, $3) RETURNING id, create_time
+`
+
+const selectEventsQuery = `
+SELECT id, resource, type, payload, create_time FROM outbox_event ORDER BY id LIMIT $1
+`
+
+const deleteEventsQuery = `DELETE FROM outbox_event WHERE id = ANY($1)`
+
+// Arbitrary key of the advisory lock held by the active dispatcher. Only one dispatcher publishes at a time, which
+// guarantees events of a resource are published in order.
+const dispatcherAdvisoryLockKey = 7359108264
+
+const tryAcquireAdvisoryLockQuery = `SELECT pg_try_advisory_xact_lock($1)`
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// webhookTimeout bounds each webhook request.
+const webhookTimeout = 10 * time.Second
+
+// WebhookPublisher publishes events by POSTing them as JSON to a URL.
+// The event ID is sent as the `Idempotency-Key` header so that receivers can deduplicate.
+type WebhookPublisher struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookPublisher instantiates and returns a new WebhookPublisher.
+func NewWebhookPublisher(url string) *WebhookPublisher {
+	return &WebhookPublisher{url: url, httpClient: &http.Client{Timeout: webhookTimeout}}
+}
+
+type webhookEvent struct {
+	ID         int64     `json:"id"`
+	Resource   string    `json:"resource"`
+	Type       string    `json:"type"`
+	Payload    []byte    `json:"payload"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// Publish implements the Publisher interface.
+func (p *WebhookPublisher) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(&webhookEvent{
+		ID:         event.ID,
+		Resource:   event.Resource,
+		Type:       event.Type,
+		Payload:    event.Payload,
+		CreateTime: event.CreateTime,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshaling event")
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", strconv.FormatInt(event.ID, 10))
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "posting event")
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return errors.Errorf("webhook returned %d: %s", response.StatusCode, responseBody)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+)
+
+const (
+	// replicaLagCheckInterval is how often we check the replication lag of replicas.
+	replicaLagCheckInterval = 5 * time.Second
+	// replicaLagQuery returns the replication lag of a replica in seconds. It is 0 on a primary, and on a replica that
+	// replayed everything it received: the time since the last replayed transaction keeps growing while the primary is
+	// idle, and does not measure lag then.
+	replicaLagQuery = `
+SELECT CASE
+  WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+  ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+)
+
+type pinPrimaryContextKey struct{}
+
+// PinPrimary returns a context whose reads are routed to the primary. Use this after a write, so that subsequent reads
+// of the same request see it.
+func PinPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinPrimaryContextKey{}, true)
+}
+
+func isPrimaryPinned(ctx context.Context) bool {
+	pinned, _ := ctx.Value(pinPrimaryContextKey{}).(bool)
+	return pinned
+}
+
+type replica struct {
+	host    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// ReadPool returns the pool read-only operations should use. Reads are spread across healthy replicas, and fall back to
+// the primary if there are none, or if the context is pinned to the primary.
+func (c *Client) ReadPool(ctx context.Context) *pgxpool.Pool {
+	if len(c.replicas) == 0 || isPrimaryPinned(ctx) {
+		return c.Pool
+	}
+	offset := c.replicaCounter.Add(1)
+	for i := range c.replicas {
+		replica := c.replicas[(int(offset)+i)%len(c.replicas)]
+		if replica.healthy.Load() {
+			return replica.pool
+		}
+	}
+	return c.Pool
+}
+
+// Close closes the primary and replica pools.
+func (c *Client) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	for _, replica := range c.replicas {
+		replica.pool.Close()
+	}
+	c.Pool.Close()
+}
+
+func (c *Client) connectReplicas() error {
+	for _, hostPort := range c.Opts.ReplicaHosts {
+		host, port := hostPort, c.Opts.Port
+		if h, p, err := net.SplitHostPort(hostPort); err == nil {
+			host = h
+			if port, err = strconv.Atoi(p); err != nil {
+				return errors.Wrapf(err, "parsing port of replica [%s]", hostPort)
+			}
+		}
+		pool, err := newPool(c.Opts, host, port)
+		if err != nil {
+			return errors.Wrapf(err, "connecting to replica [%s]", hostPort)
+		}
+		replica := &replica{host: hostPort, pool: pool}
+		replica.healthy.Store(true)
+		c.replicas = append(c.replicas, replica)
+	}
+	if len(c.replicas) == 0 || c.Opts.MaxReplicaLag <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.checkReplicasLag(ctx)
+	go func() {
+		ticker := time.NewTicker(replicaLagCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.checkReplicasLag(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// checkReplicasLag marks replicas that are unreachable or lagging behind the primary as unhealthy.
+func (c *Client) checkReplicasLag(ctx context.Context) {
+	for _, replica := range c.replicas {
+		ctx, cancel := context.WithTimeout(ctx, replicaLagCheckInterval)
+		var lagSeconds float64
+		err := replica.pool.QueryRow(ctx, replicaLagQuery).Scan(&lagSeconds)
+		cancel()
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		healthy := err == nil && lag <= c.Opts.MaxReplicaLag
+		if wasHealthy := replica.healthy.Swap(healthy); wasHealthy != healthy {
+			if healthy {
+				log.Infof("Routing reads to replica [%s] again", replica.host)
+			} else {
+				log.Warningf("Routing reads away from replica [%s]: lag [%s], error [%v]", replica.host, lag, err)
+			}
+		}
+	}
+}
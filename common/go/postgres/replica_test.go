@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// newUnreachablePool returns a pool that connects lazily to a port nothing listens on.
+func newUnreachablePool(t *testing.T) *pgxpool.Pool {
+	pool, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 user=postgres dbname=postgres sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func newTestReplicaClient(t *testing.T, replicaCount int) *Client {
+	client := &Client{Opts: Opts{MaxReplicaLag: time.Second}, Pool: newUnreachablePool(t)}
+	for i := 0; i < replicaCount; i++ {
+		replica := &replica{host: "replica", pool: newUnreachablePool(t)}
+		replica.healthy.Store(true)
+		client.replicas = append(client.replicas, replica)
+	}
+	return client
+}
+
+func TestReadPool(t *testing.T) {
+	ctx := context.Background()
+
+	client := newTestReplicaClient(t, 0)
+	require.Same(t, client.Pool, client.ReadPool(ctx))
+
+	// Reads are spread across replicas.
+	client = newTestReplicaClient(t, 2)
+	seen := map[*pgxpool.Pool]int{}
+	for i := 0; i < 4; i++ {
+		seen[client.ReadPool(ctx)]++
+	}
+	require.Equal(t, map[*pgxpool.Pool]int{client.replicas[0].pool: 2, client.replicas[1].pool: 2}, seen)
+
+	// Pinned reads go to the primary.
+	require.Same(t, client.Pool, client.ReadPool(PinPrimary(ctx)))
+
+	// Unhealthy replicas are skipped, and reads fall back to the primary once no replica is healthy.
+	client.replicas[0].healthy.Store(false)
+	for i := 0; i < 4; i++ {
+		require.Same(t, client.replicas[1].pool, client.ReadPool(ctx))
+	}
+	client.replicas[1].healthy.Store(false)
+	require.Same(t, client.Pool, client.ReadPool(ctx))
+}
+
+func TestCheckReplicasLag(t *testing.T) {
+	ctx := context.Background()
+	client := newTestReplicaClient(t, 2)
+	client.checkReplicasLag(ctx)
+	// Unreachable replicas are marked unhealthy.
+	require.False(t, client.replicas[0].healthy.Load())
+	require.False(t, client.replicas[1].healthy.Load())
+	require.Same(t, client.Pool, client.ReadPool(ctx))
+}
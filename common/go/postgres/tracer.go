@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const unlabelled = "unknown"
+
+var (
+	queryDurationHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "postgres_query_duration_seconds",
+			Help: "Duration of postgres queries",
+		},
+		[]string{"resource", "operation", "success"},
+	)
+	queryRowsHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "postgres_query_rows",
+			Help:    "Rows returned or affected by postgres queries",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		},
+		[]string{"resource", "operation"},
+	)
+)
+
+type queryLabelsContextKey struct{}
+
+type queryLabels struct {
+	resource  string
+	operation string
+}
+
+// WithQueryLabels returns a context whose queries are labelled with the given resource and operation,
+// e.g. ("books", "list"), in metrics and slow query logs.
+func WithQueryLabels(ctx context.Context, resource, operation string) context.Context {
+	return context.WithValue(ctx, queryLabelsContextKey{}, &queryLabels{resource: resource, operation: operation})
+}
+
+func getQueryLabels(ctx context.Context) *queryLabels {
+	if labels, ok := ctx.Value(queryLabelsContextKey{}).(*queryLabels); ok {
+		return labels
+	}
+	return &queryLabels{resource: unlabelled, operation: unlabelled}
+}
+
+type queryStartContextKey struct{}
+
+type queryStart struct {
+	time    time.Time
+	sql     string
+	numArgs int
+}
+
+// queryTracer records metrics for every query, and logs queries slower than a threshold.
+type queryTracer struct {
+	slowQueryThreshold time.Duration
+}
+
+// TraceQueryStart implements the pgx.QueryTracer interface.
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartContextKey{}, &queryStart{time: time.Now(), sql: data.SQL, numArgs: len(data.Args)})
+}
+
+// TraceQueryEnd implements the pgx.QueryTracer interface.
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartContextKey{}).(*queryStart)
+	if !ok {
+		return
+	}
+	duration := time.Since(start.time)
+	labels := getQueryLabels(ctx)
+	success := data.Err == nil
+	queryDurationHistogram.WithLabelValues(labels.resource, labels.operation, strconv.FormatBool(success)).Observe(duration.Seconds())
+	if success {
+		queryRowsHistogram.WithLabelValues(labels.resource, labels.operation).Observe(float64(data.CommandTag.RowsAffected()))
+	}
+
+	if t.slowQueryThreshold > 0 && duration > t.slowQueryThreshold {
+		// Parameters are never logged as they may hold sensitive data, which is why queries use placeholders.
+		log.Warningf(
+			"slow query [%s/%s] took [%s] with [%d] parameters: %s",
+			labels.resource, labels.operation, duration, start.numArgs, strings.Join(strings.Fields(start.sql), " "),
+		)
+	}
+}
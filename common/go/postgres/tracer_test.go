@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook records the messages of log entries.
+type recordingHook struct {
+	messages []string
+}
+
+func (h *recordingHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *recordingHook) Fire(entry *logrus.Entry) error {
+	h.messages = append(h.messages, entry.Message)
+	return nil
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func sampleSum(t *testing.T, observer prometheus.Observer) float64 {
+	metric := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleSum()
+}
+
+func traceQuery(tracer *queryTracer, ctx context.Context, sql string, args []any, commandTag string, err error) {
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag(commandTag), Err: err})
+}
+
+func TestQueryTracerMetrics(t *testing.T) {
+	tracer := &queryTracer{}
+	ctx := WithQueryLabels(context.Background(), "tracer_books", "list")
+	// Histograms are global, so we assert deltas, which hold across test runs and other tests recording queries.
+	succeeded := queryDurationHistogram.WithLabelValues("tracer_books", "list", "true")
+	failed := queryDurationHistogram.WithLabelValues("tracer_books", "list", "false")
+	rows := queryRowsHistogram.WithLabelValues("tracer_books", "list")
+	succeededBefore, failedBefore := sampleCount(t, succeeded), sampleCount(t, failed)
+	rowsCountBefore, rowsSumBefore := sampleCount(t, rows), sampleSum(t, rows)
+
+	traceQuery(tracer, ctx, "SELECT * FROM book", nil, "SELECT 3", nil)
+	traceQuery(tracer, ctx, "SELECT * FROM book", nil, "", errors.New("connection reset"))
+	require.Equal(t, succeededBefore+1, sampleCount(t, succeeded))
+	require.Equal(t, failedBefore+1, sampleCount(t, failed))
+	// Rows are only recorded for successful queries.
+	require.Equal(t, rowsCountBefore+1, sampleCount(t, rows))
+	require.Equal(t, rowsSumBefore+3, sampleSum(t, rows))
+
+	// Queries without labels are still recorded.
+	unlabelledSucceeded := queryDurationHistogram.WithLabelValues(unlabelled, unlabelled, "true")
+	before := sampleCount(t, unlabelledSucceeded)
+	traceQuery(tracer, context.Background(), "SELECT 1", nil, "SELECT 1", nil)
+	require.Equal(t, before+1, sampleCount(t, unlabelledSucceeded))
+
+	// Queries that were not started by the tracer are ignored.
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	require.Equal(t, succeededBefore+1, sampleCount(t, succeeded))
+}
+
+func TestQueryTracerSlowQueries(t *testing.T) {
+	hook := &recordingHook{}
+	hooks := log.ReplaceHooks(logrus.LevelHooks{})
+	t.Cleanup(func() { log.ReplaceHooks(hooks) })
+	log.AddHook(hook)
+	ctx := WithQueryLabels(context.Background(), "tracer_authors", "get")
+	sql := "SELECT *\n  FROM author\n  WHERE id = $1"
+
+	// Disabled without a threshold.
+	traceQuery(&queryTracer{}, ctx, sql, []any{"secret"}, "SELECT 1", nil)
+	require.Empty(t, hook.messages)
+
+	// Fast queries are not logged.
+	traceQuery(&queryTracer{slowQueryThreshold: time.Hour}, ctx, sql, []any{"secret"}, "SELECT 1", nil)
+	require.Empty(t, hook.messages)
+
+	tracer := &queryTracer{slowQueryThreshold: time.Nanosecond}
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"secret"}})
+	time.Sleep(time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+	require.Len(t, hook.messages, 1)
+	require.Contains(t, hook.messages[0], "slow query [tracer_authors/get]")
+	require.Contains(t, hook.messages[0], "with [1] parameters: SELECT * FROM author WHERE id = $1")
+	// Parameters are never logged.
+	require.NotContains(t, hook.messages[0], "secret")
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// rollbackTimeout bounds rollbacks, which run even if the context of the transaction is done.
+const rollbackTimeout = 5 * time.Second
+
+// TxFN is a function run in a transaction. The given context carries the transaction, so that nested calls to
+// RunInTx and AfterCommit use it.
+type TxFN func(ctx context.Context, tx Tx) error
+
+type txContextKey struct{}
+
+type txState struct {
+	tx               pgx.Tx
+	afterCommitHooks []func(context.Context)
+}
+
+// RunInTx runs fn in a transaction, committing it if fn returns nil and rolling it back otherwise.
+// If ctx already carries a transaction, fn runs in a savepoint of it instead, so that operations compose: a nested
+// failure only rolls back the savepoint, and the caller decides whether to fail the whole transaction.
+func (c *Client) RunInTx(ctx context.Context, fn TxFN) error {
+	if parent, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return runInTx(ctx, parent.tx.Begin, parent, fn)
+	}
+	return runInTx(ctx, c.Begin, nil, fn)
+}
+
+// runInTx runs fn in a transaction started by begin. If parent is set, the transaction is a savepoint of it.
+func runInTx(ctx context.Context, begin func(context.Context) (pgx.Tx, error), parent *txState, fn TxFN) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(tx)
+			panic(p)
+		}
+	}()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txContextKey{}, state), tx); err != nil {
+		rollback(tx)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		rollback(tx)
+		return errors.Wrap(err, "committing transaction")
+	}
+
+	// Hooks of a savepoint only run once the outermost transaction commits.
+	if parent != nil {
+		parent.afterCommitHooks = append(parent.afterCommitHooks, state.afterCommitHooks...)
+		return nil
+	}
+	for _, hook := range state.afterCommitHooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+// AfterCommit registers a hook called once the transaction carried by ctx commits, e.g. to publish events about the
+// resources it wrote. Hooks are dropped if the transaction rolls back. If ctx carries no transaction, the hook is
+// called immediately.
+func AfterCommit(ctx context.Context, hook func(context.Context)) {
+	state, ok := ctx.Value(txContextKey{}).(*txState)
+	if !ok {
+		hook(ctx)
+		return
+	}
+	state.afterCommitHooks = append(state.afterCommitHooks, hook)
+}
+
+// rollback rolls a transaction back with a fresh context, so that a transaction whose context is done still gets
+// rolled back instead of lingering until its connection is closed.
+func rollback(tx pgx.Tx) {
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		log.Errorf("Could not roll back transaction: %v", err)
+	}
+}
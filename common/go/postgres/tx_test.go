@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx records the statements a transaction and its savepoints would run.
+type fakeTx struct {
+	pgx.Tx
+	statements *[]string
+	name       string
+	closed     bool
+	commitErr  error
+}
+
+func (t *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	*t.statements = append(*t.statements, "SAVEPOINT "+t.name+"_1")
+	return &fakeTx{statements: t.statements, name: t.name + "_1"}, nil
+}
+
+func (t *fakeTx) Commit(context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	if t.commitErr != nil {
+		return t.commitErr
+	}
+	t.closed = true
+	*t.statements = append(*t.statements, "COMMIT "+t.name)
+	return nil
+}
+
+func (t *fakeTx) Rollback(context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+	*t.statements = append(*t.statements, "ROLLBACK "+t.name)
+	return nil
+}
+
+// fakeBegin returns a function beginning fake transactions named "tx".
+func fakeBegin(statements *[]string, commitErr error) func(context.Context) (pgx.Tx, error) {
+	return func(context.Context) (pgx.Tx, error) {
+		*statements = append(*statements, "BEGIN tx")
+		return &fakeTx{statements: statements, name: "tx", commitErr: commitErr}, nil
+	}
+}
+
+func TestRunInTx(t *testing.T) {
+	ctx := context.Background()
+	// Nested calls begin savepoints of the transaction carried by their context, not of the client's pool.
+	client := &Client{}
+
+	t.Run("CommitsAndRunsHooks", func(t *testing.T) {
+		var statements, hooks []string
+		err := runInTx(ctx, fakeBegin(&statements, nil), nil, func(ctx context.Context, tx Tx) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "published") })
+			require.Empty(t, hooks)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"BEGIN tx", "COMMIT tx"}, statements)
+		require.Equal(t, []string{"published"}, hooks)
+	})
+
+	t.Run("RollsBackAndDropsHooksOnError", func(t *testing.T) {
+		var statements, hooks []string
+		err := runInTx(ctx, fakeBegin(&statements, nil), nil, func(ctx context.Context, tx Tx) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "published") })
+			return errors.New("invalid book")
+		})
+		require.EqualError(t, err, "invalid book")
+		require.Equal(t, []string{"BEGIN tx", "ROLLBACK tx"}, statements)
+		require.Empty(t, hooks)
+	})
+
+	t.Run("RollsBackOnCommitError", func(t *testing.T) {
+		var statements, hooks []string
+		err := runInTx(ctx, fakeBegin(&statements, errors.New("serialization failure")), nil, func(ctx context.Context, tx Tx) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "published") })
+			return nil
+		})
+		require.ErrorContains(t, err, "committing transaction: serialization failure")
+		require.Equal(t, []string{"BEGIN tx", "ROLLBACK tx"}, statements)
+		require.Empty(t, hooks)
+	})
+
+	t.Run("RollsBackOnPanic", func(t *testing.T) {
+		var statements []string
+		require.PanicsWithValue(t, "boom", func() {
+			runInTx(ctx, fakeBegin(&statements, nil), nil, func(context.Context, Tx) error { panic("boom") })
+		})
+		require.Equal(t, []string{"BEGIN tx", "ROLLBACK tx"}, statements)
+	})
+
+	t.Run("NestedCallsUseSavepoints", func(t *testing.T) {
+		var statements, hooks []string
+		err := runInTx(ctx, fakeBegin(&statements, nil), nil, func(ctx context.Context, tx Tx) error {
+			err := client.RunInTx(ctx, func(ctx context.Context, tx Tx) error {
+				AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "committed savepoint") })
+				return nil
+			})
+			require.NoError(t, err)
+			// Hooks of a savepoint wait for the outermost transaction.
+			require.Empty(t, hooks)
+
+			err = client.RunInTx(ctx, func(ctx context.Context, tx Tx) error {
+				AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "rolled back savepoint") })
+				return errors.New("invalid book")
+			})
+			// The caller decides whether a failed savepoint fails the transaction.
+			require.EqualError(t, err, "invalid book")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"BEGIN tx", "SAVEPOINT tx_1", "COMMIT tx_1", "SAVEPOINT tx_1", "ROLLBACK tx_1", "COMMIT tx"}, statements)
+		require.Equal(t, []string{"committed savepoint"}, hooks)
+	})
+
+	t.Run("SavepointHooksAreDroppedIfTheTransactionRollsBack", func(t *testing.T) {
+		var statements, hooks []string
+		err := runInTx(ctx, fakeBegin(&statements, nil), nil, func(ctx context.Context, tx Tx) error {
+			err := client.RunInTx(ctx, func(ctx context.Context, tx Tx) error {
+				AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "published") })
+				return nil
+			})
+			require.NoError(t, err)
+			return errors.New("invalid book")
+		})
+		require.Error(t, err)
+		require.Empty(t, hooks)
+	})
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	called := false
+	AfterCommit(context.Background(), func(context.Context) { called = true })
+	require.True(t, called)
+}
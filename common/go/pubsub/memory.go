@@ -0,0 +1,115 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"common/go/uuid"
+)
+
+// groupBufferSize is the number of messages a group buffers before publishers block.
+const groupBufferSize = 1024
+
+// InMemory is a PubSub delivering messages within a process, for tests and single-replica services.
+// Messages are only delivered to groups that exist when they are published.
+type InMemory struct {
+	mutex sync.Mutex
+	// Topic -> group -> messages.
+	topics map[string]map[string]chan *Message
+	closed bool
+}
+
+var _ PubSub = (*InMemory)(nil)
+
+// NewInMemory instantiates and returns a new InMemory.
+func NewInMemory() *InMemory {
+	return &InMemory{topics: map[string]map[string]chan *Message{}}
+}
+
+// Publish implements the PubSub interface.
+func (p *InMemory) Publish(ctx context.Context, topic string, message proto.Message) (string, error) {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling message")
+	}
+	id := uuid.MustNew()
+	return id, p.publish(ctx, &Message{ID: id, Topic: topic, Data: data, PublishTime: time.Now()})
+}
+
+func (p *InMemory) publish(ctx context.Context, message *Message) error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return errors.New("pubsub is closed")
+	}
+	groups := make([]chan *Message, 0, len(p.topics[message.Topic]))
+	for _, group := range p.topics[message.Topic] {
+		groups = append(groups, group)
+	}
+	p.mutex.Unlock()
+
+	for _, group := range groups {
+		// Each group gets its own copy, as handlers set the attempt.
+		message := *message
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case group <- &message:
+		}
+	}
+	return nil
+}
+
+// Subscribe implements the PubSub interface.
+func (p *InMemory) Subscribe(ctx context.Context, topic string, opts SubscriptionOpts, handler Handler) error {
+	opts = opts.withDefaults()
+	group := opts.Group
+	if group == "" {
+		// Subscribers without a group get their own.
+		group = uuid.MustNew()
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return errors.New("pubsub is closed")
+	}
+	groups, ok := p.topics[topic]
+	if !ok {
+		groups = map[string]chan *Message{}
+		p.topics[topic] = groups
+	}
+	messages, ok := groups[group]
+	if !ok {
+		messages = make(chan *Message, groupBufferSize)
+		groups[group] = messages
+	}
+	p.mutex.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message := <-messages:
+			if handle(ctx, opts, handler, message) || opts.DeadLetterTopic == "" {
+				continue
+			}
+			deadLetter := &Message{ID: message.ID, Topic: opts.DeadLetterTopic, Data: message.Data, PublishTime: time.Now()}
+			if err := p.publish(ctx, deadLetter); err != nil {
+				log.Errorf("dead lettering message [%s] of topic [%s]: %v", message.ID, topic, err)
+			}
+		}
+	}
+}
+
+// Close implements the PubSub interface. Subscriptions return once their context is done.
+func (p *InMemory) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	return nil
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func subscribe(ctx context.Context, t *testing.T, p PubSub, topic string, opts SubscriptionOpts, handler Handler) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, p.Subscribe(ctx, topic, opts, handler))
+	}()
+	// Let the subscription register.
+	time.Sleep(10 * time.Millisecond)
+	return &wg
+}
+
+func TestInMemoryGroups(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewInMemory()
+
+	received := make(chan string, 10)
+	handler := func(group string) Handler {
+		return func(ctx context.Context, message *Message) error {
+			value := &wrapperspb.StringValue{}
+			require.NoError(t, message.Unmarshal(value))
+			received <- group + ":" + value.Value
+			return nil
+		}
+	}
+	wgs := []*sync.WaitGroup{
+		subscribe(ctx, t, p, "topic", SubscriptionOpts{Group: "a"}, handler("a")),
+		subscribe(ctx, t, p, "topic", SubscriptionOpts{Group: "a"}, handler("a")),
+		subscribe(ctx, t, p, "topic", SubscriptionOpts{Group: "b"}, handler("b")),
+	}
+
+	_, err := p.Publish(ctx, "topic", wrapperspb.String("hello"))
+	require.NoError(t, err)
+	// Each group gets the message exactly once.
+	require.ElementsMatch(t, []string{"a:hello", "b:hello"}, []string{<-received, <-received})
+	select {
+	case message := <-received:
+		require.Failf(t, "unexpected message", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	for _, wg := range wgs {
+		wg.Wait()
+	}
+}
+
+func TestInMemoryDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewInMemory()
+
+	var attempts []int
+	deadLetters := make(chan *Message, 1)
+	wgs := []*sync.WaitGroup{
+		subscribe(ctx, t, p, "topic", SubscriptionOpts{MaxAttempts: 3, Backoff: time.Millisecond, DeadLetterTopic: "dead"}, func(ctx context.Context, message *Message) error {
+			attempts = append(attempts, message.Attempt)
+			return errors.New("failure")
+		}),
+		subscribe(ctx, t, p, "dead", SubscriptionOpts{}, func(ctx context.Context, message *Message) error {
+			deadLetters <- message
+			return nil
+		}),
+	}
+
+	id, err := p.Publish(ctx, "topic", wrapperspb.String("hello"))
+	require.NoError(t, err)
+	deadLetter := <-deadLetters
+	require.Equal(t, id, deadLetter.ID)
+	require.Equal(t, []int{1, 2, 3}, attempts)
+
+	cancel()
+	for _, wg := range wgs {
+		wg.Wait()
+	}
+}
@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"common/go/uuid"
+)
+
+// JetStream is the subset of a NATS JetStream client the NATS PubSub uses. NewNATSJetStream adapts nats.go's
+// jetstream package to it.
+type JetStream interface {
+	// Publish publishes data to a subject. The broker discards messages whose ID it already received.
+	Publish(ctx context.Context, subject, id string, data []byte) error
+	// Consume delivers the messages of a consumer of the stream to the handler, one at a time, until the context is
+	// done.
+	Consume(ctx context.Context, stream string, config JetStreamConsumerConfig, handler func(JetStreamMessage)) error
+}
+
+// JetStreamConsumerConfig configures a JetStream consumer.
+type JetStreamConsumerConfig struct {
+	// Durable consumers are shared by every subscriber using their name. Consumers without a name are ephemeral.
+	Durable string
+	// Subject the consumer receives messages of.
+	Subject string
+}
+
+// JetStreamMessage is a message delivered by a JetStream consumer.
+type JetStreamMessage interface {
+	// ID is the ID the message was published with.
+	ID() string
+	Subject() string
+	Data() []byte
+	PublishTime() time.Time
+	// NumDelivered is the delivery attempt of this message, starting at 1.
+	NumDelivered() int
+	Ack() error
+	// NakWithDelay redelivers the message once the delay elapses.
+	NakWithDelay(delay time.Duration) error
+	// Term stops redelivering the message.
+	Term() error
+}
+
+// NATS is a PubSub backed by NATS JetStream, whose topics are subjects of a single stream. Unlike InMemory, messages
+// are persisted, and are redelivered by the broker, so retries survive restarts.
+type NATS struct {
+	js     JetStream
+	stream string
+}
+
+var _ PubSub = (*NATS)(nil)
+
+// NewNATS instantiates and returns a new NATS PubSub, publishing to and consuming from the given stream, which must
+// exist and capture the subjects of every topic, including dead letter topics.
+func NewNATS(js JetStream, stream string) *NATS {
+	return &NATS{js: js, stream: stream}
+}
+
+// Publish implements the PubSub interface.
+func (p *NATS) Publish(ctx context.Context, topic string, message proto.Message) (string, error) {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling message")
+	}
+	id := uuid.MustNew()
+	if err := p.js.Publish(ctx, topic, id, data); err != nil {
+		return "", errors.Wrapf(err, "publishing to %s", topic)
+	}
+	return id, nil
+}
+
+// Subscribe implements the PubSub interface. Subscribers of a group share a durable consumer.
+func (p *NATS) Subscribe(ctx context.Context, topic string, opts SubscriptionOpts, handler Handler) error {
+	opts = opts.withDefaults()
+	config := JetStreamConsumerConfig{Subject: topic}
+	if opts.Group != "" {
+		config.Durable = durableName(opts.Group, topic)
+	}
+	err := p.js.Consume(ctx, p.stream, config, func(jsMessage JetStreamMessage) {
+		p.handle(ctx, topic, opts, handler, jsMessage)
+	})
+	if err != nil && ctx.Err() == nil {
+		return errors.Wrapf(err, "consuming %s", topic)
+	}
+	return nil
+}
+
+// handle delivers a message to a handler once. Failed messages are redelivered by the broker with exponential backoff,
+// until they exhaust their attempts and are dead lettered.
+func (p *NATS) handle(ctx context.Context, topic string, opts SubscriptionOpts, handler Handler, jsMessage JetStreamMessage) {
+	message := &Message{
+		ID:          jsMessage.ID(),
+		Topic:       jsMessage.Subject(),
+		Data:        jsMessage.Data(),
+		PublishTime: jsMessage.PublishTime(),
+		Attempt:     jsMessage.NumDelivered(),
+	}
+	err := handler(ctx, message)
+	if err == nil {
+		if err := jsMessage.Ack(); err != nil {
+			log.Warningf("acking message [%s] of topic [%s]: %v", message.ID, topic, err)
+		}
+		return
+	}
+	log.Warningf("handling message [%s] of topic [%s], attempt %d/%d: %v", message.ID, topic, message.Attempt, opts.MaxAttempts, err)
+	if message.Attempt < opts.MaxAttempts {
+		if err := jsMessage.NakWithDelay(opts.backoff(message.Attempt)); err != nil {
+			log.Warningf("naking message [%s] of topic [%s]: %v", message.ID, topic, err)
+		}
+		return
+	}
+	if opts.DeadLetterTopic != "" {
+		// Dead letters get their own ID, as the broker would discard a message with the ID of the original.
+		if err := p.js.Publish(ctx, opts.DeadLetterTopic, message.ID+"-dead-letter", message.Data); err != nil {
+			log.Errorf("dead lettering message [%s] of topic [%s]: %v", message.ID, topic, err)
+			// Redeliver it, so that dead lettering is retried.
+			if err := jsMessage.NakWithDelay(opts.Backoff); err != nil {
+				log.Warningf("naking message [%s] of topic [%s]: %v", message.ID, topic, err)
+			}
+			return
+		}
+	}
+	if err := jsMessage.Term(); err != nil {
+		log.Warningf("terminating message [%s] of topic [%s]: %v", message.ID, topic, err)
+	}
+}
+
+// durableName returns the name of the durable consumer of a group, which must not contain '.', '*', '>' or spaces.
+func durableName(group, topic string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, group+"-"+topic)
+}
+
+// Close implements the PubSub interface. The JetStream client is owned by the caller, who closes it.
+func (p *NATS) Close() error {
+	return nil
+}
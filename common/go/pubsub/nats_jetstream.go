@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+)
+
+// natsJetStream adapts a nats.go JetStream client to the JetStream interface.
+type natsJetStream struct {
+	js jetstream.JetStream
+}
+
+var _ JetStream = (*natsJetStream)(nil)
+
+// NewNATSJetStream returns a JetStream backed by the given nats.go client, e.g. one returned by jetstream.New.
+func NewNATSJetStream(js jetstream.JetStream) JetStream {
+	return &natsJetStream{js: js}
+}
+
+// Publish implements the JetStream interface. The ID is sent as the message's Nats-Msg-Id, which the broker
+// deduplicates on.
+func (a *natsJetStream) Publish(ctx context.Context, subject, id string, data []byte) error {
+	_, err := a.js.Publish(ctx, subject, data, jetstream.WithMsgID(id))
+	return err
+}
+
+// Consume implements the JetStream interface. It creates or updates a consumer with an explicit ack policy and
+// unlimited deliveries, then consumes it until the context is done.
+func (a *natsJetStream) Consume(ctx context.Context, stream string, config JetStreamConsumerConfig, handler func(JetStreamMessage)) error {
+	consumer, err := a.js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       config.Durable,
+		FilterSubject: config.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "creating consumer of %s", config.Subject)
+	}
+	consumeContext, err := consumer.Consume(func(msg jetstream.Msg) {
+		metadata, err := msg.Metadata()
+		if err != nil {
+			log.Errorf("reading metadata of message of subject [%s]: %v", msg.Subject(), err)
+			if err := msg.Term(); err != nil {
+				log.Warningf("terminating message of subject [%s]: %v", msg.Subject(), err)
+			}
+			return
+		}
+		handler(&natsJetStreamMessage{Msg: msg, metadata: metadata})
+	}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		// The client keeps consuming, e.g. by reconnecting after missed heartbeats.
+		log.Warningf("consuming %s: %v", config.Subject, err)
+	}))
+	if err != nil {
+		return errors.Wrapf(err, "consuming %s", config.Subject)
+	}
+	<-ctx.Done()
+	consumeContext.Stop()
+	return ctx.Err()
+}
+
+// natsJetStreamMessage adapts a nats.go JetStream message to the JetStreamMessage interface.
+type natsJetStreamMessage struct {
+	jetstream.Msg
+	metadata *jetstream.MsgMetadata
+}
+
+var _ JetStreamMessage = (*natsJetStreamMessage)(nil)
+
+// ID implements the JetStreamMessage interface.
+func (m *natsJetStreamMessage) ID() string {
+	return m.Headers().Get(jetstream.MsgIDHeader)
+}
+
+// PublishTime implements the JetStreamMessage interface.
+func (m *natsJetStreamMessage) PublishTime() time.Time {
+	return m.metadata.Timestamp
+}
+
+// NumDelivered implements the JetStreamMessage interface.
+func (m *natsJetStreamMessage) NumDelivered() int {
+	return int(m.metadata.NumDelivered)
+}
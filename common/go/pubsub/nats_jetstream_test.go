@@ -0,0 +1,180 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeNATSClient is a nats.go JetStream client recording publications, with a single consumer.
+type fakeNATSClient struct {
+	jetstream.JetStream
+	subjects       []string
+	data           [][]byte
+	consumerStream string
+	consumerConfig jetstream.ConsumerConfig
+	consumer       *fakeNATSConsumer
+}
+
+func (c *fakeNATSClient) Publish(ctx context.Context, subject string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if len(opts) != 1 {
+		return nil, errors.Errorf("expected a single option, got %d", len(opts))
+	}
+	c.subjects = append(c.subjects, subject)
+	c.data = append(c.data, data)
+	return &jetstream.PubAck{}, nil
+}
+
+func (c *fakeNATSClient) CreateOrUpdateConsumer(ctx context.Context, stream string, config jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	c.consumerStream = stream
+	c.consumerConfig = config
+	return c.consumer, nil
+}
+
+// fakeNATSConsumer delivers its messages once, then closes consumed.
+type fakeNATSConsumer struct {
+	jetstream.Consumer
+	messages []*fakeNATSMessage
+	consumed chan struct{}
+	stopped  bool
+}
+
+func (c *fakeNATSConsumer) Consume(handler jetstream.MessageHandler, opts ...jetstream.PullConsumeOpt) (jetstream.ConsumeContext, error) {
+	go func() {
+		for _, message := range c.messages {
+			handler(message)
+		}
+		close(c.consumed)
+	}()
+	return c, nil
+}
+
+func (c *fakeNATSConsumer) Stop() {
+	c.stopped = true
+}
+
+type fakeNATSMessage struct {
+	jetstream.Msg
+	subject     string
+	headers     nats.Header
+	data        []byte
+	metadata    *jetstream.MsgMetadata
+	metadataErr error
+	acked       bool
+	nakDelay    time.Duration
+	termed      bool
+}
+
+func (m *fakeNATSMessage) Metadata() (*jetstream.MsgMetadata, error) {
+	return m.metadata, m.metadataErr
+}
+
+func (m *fakeNATSMessage) Subject() string {
+	return m.subject
+}
+
+func (m *fakeNATSMessage) Headers() nats.Header {
+	return m.headers
+}
+
+func (m *fakeNATSMessage) Data() []byte {
+	return m.data
+}
+
+func (m *fakeNATSMessage) Ack() error {
+	m.acked = true
+	return nil
+}
+
+func (m *fakeNATSMessage) Term() error {
+	m.termed = true
+	return nil
+}
+
+func (m *fakeNATSMessage) NakWithDelay(delay time.Duration) error {
+	m.nakDelay = delay
+	return nil
+}
+
+func newFakeNATSMessage(t *testing.T, id, value string, numDelivered uint64, publishTime time.Time) *fakeNATSMessage {
+	data, err := proto.Marshal(wrapperspb.String(value))
+	require.NoError(t, err)
+	return &fakeNATSMessage{
+		subject:  "orders",
+		headers:  nats.Header{jetstream.MsgIDHeader: []string{id}},
+		data:     data,
+		metadata: &jetstream.MsgMetadata{NumDelivered: numDelivered, Timestamp: publishTime},
+	}
+}
+
+func TestNATSJetStream(t *testing.T) {
+	t.Run("Publishes", func(t *testing.T) {
+		client := &fakeNATSClient{}
+		pubSub := NewNATS(NewNATSJetStream(client), "events")
+		_, err := pubSub.Publish(context.Background(), "orders", wrapperspb.String("a"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"orders"}, client.subjects)
+		value := &wrapperspb.StringValue{}
+		require.NoError(t, proto.Unmarshal(client.data[0], value))
+		require.Equal(t, "a", value.Value)
+	})
+
+	t.Run("Consumes", func(t *testing.T) {
+		publishTime := time.Unix(1700000000, 0)
+		succeeding := newFakeNATSMessage(t, "1", "ok", 1, publishTime)
+		failing := newFakeNATSMessage(t, "2", "fail", 2, publishTime)
+		malformed := &fakeNATSMessage{subject: "orders", metadataErr: errors.New("not a jetstream message")}
+		consumer := &fakeNATSConsumer{messages: []*fakeNATSMessage{succeeding, failing, malformed}, consumed: make(chan struct{})}
+		client := &fakeNATSClient{consumer: consumer}
+		pubSub := NewNATS(NewNATSJetStream(client), "events")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var messages []*Message
+		errs := make(chan error, 1)
+		go func() {
+			errs <- pubSub.Subscribe(ctx, "orders", SubscriptionOpts{Group: "billing"}, func(ctx context.Context, message *Message) error {
+				messages = append(messages, message)
+				value := &wrapperspb.StringValue{}
+				if err := message.Unmarshal(value); err != nil {
+					return err
+				}
+				if value.Value == "fail" {
+					return errors.New("failed")
+				}
+				return nil
+			})
+		}()
+		<-consumer.consumed
+		cancel()
+		require.NoError(t, <-errs)
+		require.True(t, consumer.stopped)
+
+		require.Equal(t, "events", client.consumerStream)
+		require.Equal(t, jetstream.ConsumerConfig{
+			Durable:       "billing-orders",
+			FilterSubject: "orders",
+			AckPolicy:     jetstream.AckExplicitPolicy,
+		}, client.consumerConfig)
+
+		// The malformed message never reaches the handler.
+		require.Len(t, messages, 2)
+		require.Equal(t, "1", messages[0].ID)
+		require.Equal(t, "orders", messages[0].Topic)
+		require.Equal(t, 1, messages[0].Attempt)
+		require.Equal(t, publishTime, messages[0].PublishTime)
+		require.Equal(t, "2", messages[1].ID)
+		require.Equal(t, 2, messages[1].Attempt)
+
+		require.True(t, succeeding.acked)
+		require.False(t, failing.acked)
+		require.Equal(t, 2*defaultBackoff, failing.nakDelay)
+		require.True(t, malformed.termed)
+	})
+}
@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"common/go/uuid"
+)
+
+// fakeJetStream is an in-memory JetStream with a single stream. Ephemeral consumers only receive messages published
+// after they are created.
+type fakeJetStream struct {
+	mutex sync.Mutex
+	ids   map[string]bool
+	// Durable name (or a unique name for ephemeral consumers) -> consumer.
+	consumers map[string]*fakeConsumer
+	// Delays of the naks of every message, keyed by ID.
+	naks map[string][]time.Duration
+}
+
+type fakeConsumer struct {
+	subject  string
+	messages chan *fakeJetStreamMessage
+}
+
+func newFakeJetStream() *fakeJetStream {
+	return &fakeJetStream{ids: map[string]bool{}, consumers: map[string]*fakeConsumer{}, naks: map[string][]time.Duration{}}
+}
+
+func (j *fakeJetStream) Publish(ctx context.Context, subject, id string, data []byte) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.ids[id] {
+		return nil
+	}
+	j.ids[id] = true
+	for _, consumer := range j.consumers {
+		if consumer.subject == subject {
+			consumer.messages <- &fakeJetStreamMessage{js: j, consumer: consumer, id: id, subject: subject, data: data, publishTime: time.Now(), numDelivered: 1}
+		}
+	}
+	return nil
+}
+
+func (j *fakeJetStream) Consume(ctx context.Context, stream string, config JetStreamConsumerConfig, handler func(JetStreamMessage)) error {
+	if stream != "events" {
+		return errors.Errorf("unknown stream %s", stream)
+	}
+	j.mutex.Lock()
+	name := config.Durable
+	if name == "" {
+		name = uuid.MustNew()
+	}
+	consumer, ok := j.consumers[name]
+	if !ok {
+		consumer = &fakeConsumer{subject: config.Subject, messages: make(chan *fakeJetStreamMessage, 100)}
+		j.consumers[name] = consumer
+	}
+	j.mutex.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message := <-consumer.messages:
+			handler(message)
+		}
+	}
+}
+
+func (j *fakeJetStream) hasConsumer(name string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	_, ok := j.consumers[name]
+	return ok
+}
+
+func (j *fakeJetStream) nakDelays(id string) []time.Duration {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.naks[id]
+}
+
+type fakeJetStreamMessage struct {
+	js           *fakeJetStream
+	consumer     *fakeConsumer
+	id           string
+	subject      string
+	data         []byte
+	publishTime  time.Time
+	numDelivered int
+}
+
+func (m *fakeJetStreamMessage) ID() string             { return m.id }
+func (m *fakeJetStreamMessage) Subject() string        { return m.subject }
+func (m *fakeJetStreamMessage) Data() []byte           { return m.data }
+func (m *fakeJetStreamMessage) PublishTime() time.Time { return m.publishTime }
+func (m *fakeJetStreamMessage) NumDelivered() int      { return m.numDelivered }
+func (m *fakeJetStreamMessage) Ack() error             { return nil }
+func (m *fakeJetStreamMessage) Term() error            { return nil }
+
+func (m *fakeJetStreamMessage) NakWithDelay(delay time.Duration) error {
+	m.js.mutex.Lock()
+	m.js.naks[m.id] = append(m.js.naks[m.id], delay)
+	m.js.mutex.Unlock()
+	redelivery := *m
+	redelivery.numDelivered++
+	time.AfterFunc(delay, func() { m.consumer.messages <- &redelivery })
+	return nil
+}
+
+func TestNATSGroups(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	js := newFakeJetStream()
+	p := NewNATS(js, "events")
+
+	received := make(chan string, 10)
+	handler := func(group string) Handler {
+		return func(ctx context.Context, message *Message) error {
+			value := &wrapperspb.StringValue{}
+			require.NoError(t, message.Unmarshal(value))
+			received <- group + ":" + value.Value
+			return nil
+		}
+	}
+	wgs := []*sync.WaitGroup{
+		subscribe(ctx, t, p, "library.books", SubscriptionOpts{Group: "a"}, handler("a")),
+		subscribe(ctx, t, p, "library.books", SubscriptionOpts{Group: "a"}, handler("a")),
+		subscribe(ctx, t, p, "library.books", SubscriptionOpts{}, handler("ephemeral")),
+	}
+	// Groups share a durable consumer, whose name is valid.
+	require.True(t, js.hasConsumer("a-library_books"))
+
+	_, err := p.Publish(ctx, "library.books", wrapperspb.String("hello"))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a:hello", "ephemeral:hello"}, []string{<-received, <-received})
+	select {
+	case message := <-received:
+		require.Failf(t, "unexpected message", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	for _, wg := range wgs {
+		wg.Wait()
+	}
+}
+
+func TestNATSDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	js := newFakeJetStream()
+	p := NewNATS(js, "events")
+
+	var attempts []int
+	deadLetters := make(chan *Message, 1)
+	opts := SubscriptionOpts{Group: "a", MaxAttempts: 3, Backoff: time.Millisecond, DeadLetterTopic: "dead"}
+	wgs := []*sync.WaitGroup{
+		subscribe(ctx, t, p, "topic", opts, func(ctx context.Context, message *Message) error {
+			attempts = append(attempts, message.Attempt)
+			return errors.New("failure")
+		}),
+		subscribe(ctx, t, p, "dead", SubscriptionOpts{}, func(ctx context.Context, message *Message) error {
+			deadLetters <- message
+			return nil
+		}),
+	}
+
+	id, err := p.Publish(ctx, "topic", wrapperspb.String("hello"))
+	require.NoError(t, err)
+	deadLetter := <-deadLetters
+	require.Equal(t, id+"-dead-letter", deadLetter.ID)
+	value := &wrapperspb.StringValue{}
+	require.NoError(t, deadLetter.Unmarshal(value))
+	require.Equal(t, "hello", value.Value)
+	require.Equal(t, []int{1, 2, 3}, attempts)
+	// The broker redelivers with exponential backoff.
+	require.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, js.nakDelays(id))
+
+	cancel()
+	for _, wg := range wgs {
+		wg.Wait()
+	}
+}
+
+func TestNATSConsumeErrors(t *testing.T) {
+	p := NewNATS(newFakeJetStream(), "unknown")
+	err := p.Subscribe(context.Background(), "topic", SubscriptionOpts{}, func(context.Context, *Message) error { return nil })
+	require.Error(t, err)
+}
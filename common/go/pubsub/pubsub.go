@@ -0,0 +1,109 @@
+// Package pubsub defines a publish/subscribe interface over proto messages, so that services standardize on one event
+// interface regardless of the underlying broker.
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 100 * time.Millisecond
+	// maxBackoff caps the doubling of backoffs, so that they don't overflow with many attempts.
+	maxBackoff = time.Hour
+)
+
+// Message is a message received from a topic.
+type Message struct {
+	ID          string
+	Topic       string
+	Data        []byte
+	PublishTime time.Time
+	// Delivery attempt of this message, starting at 1.
+	Attempt int
+}
+
+// Unmarshal unmarshals the data of this message into the given proto message.
+func (m *Message) Unmarshal(message proto.Message) error {
+	return errors.Wrapf(proto.Unmarshal(m.Data, message), "unmarshaling message [%s] of topic [%s]", m.ID, m.Topic)
+}
+
+// Handler handles a message. Returning an error causes the message to be redelivered with exponential backoff, until the
+// subscription's max attempts are exhausted, at which point it is sent to the dead letter topic, if any.
+type Handler func(ctx context.Context, message *Message) error
+
+// SubscriptionOpts configures a subscription.
+type SubscriptionOpts struct {
+	// Each message of a topic is delivered to a single subscriber of each group.
+	// Subscribers with no group each get every message.
+	Group string
+	// Maximum number of deliveries of a message. Defaults to 5.
+	MaxAttempts int
+	// Backoff before the second delivery of a message. It doubles on each subsequent delivery, up to an hour.
+	// Defaults to 100ms.
+	Backoff time.Duration
+	// If set, messages that exhaust their attempts are published to this topic.
+	DeadLetterTopic string
+}
+
+func (o SubscriptionOpts) withDefaults() SubscriptionOpts {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultMaxAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = defaultBackoff
+	}
+	return o
+}
+
+// backoff returns the backoff following the given delivery attempt.
+func (o SubscriptionOpts) backoff(attempt int) time.Duration {
+	backoff := o.Backoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	return backoff
+}
+
+// PubSub publishes and subscribes to topics.
+type PubSub interface {
+	// Publish publishes a message to a topic, and returns its ID.
+	Publish(ctx context.Context, topic string, message proto.Message) (string, error)
+	// Subscribe delivers messages of a topic to the given handler. Blocking call, returning once the context is done.
+	Subscribe(ctx context.Context, topic string, opts SubscriptionOpts, handler Handler) error
+	// Close closes this PubSub.
+	Close() error
+}
+
+// handle delivers a message to a handler until it succeeds or exhausts its attempts. It returns false if the message
+// should be dead lettered.
+func handle(ctx context.Context, opts SubscriptionOpts, handler Handler, message *Message) bool {
+	for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+		message.Attempt = attempt
+		err := handler(ctx, message)
+		if err == nil {
+			return true
+		}
+		log.Warningf("handling message [%s] of topic [%s], attempt %d/%d: %v", message.ID, message.Topic, attempt, opts.MaxAttempts, err)
+		if attempt == opts.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			// The message was not handled, but we are shutting down.
+			return true
+		case <-time.After(opts.backoff(attempt)):
+		}
+	}
+	return false
+}
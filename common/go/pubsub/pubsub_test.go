@@ -0,0 +1,17 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionOptsBackoff(t *testing.T) {
+	opts := SubscriptionOpts{}.withDefaults()
+	require.Equal(t, 100*time.Millisecond, opts.backoff(1))
+	require.Equal(t, 400*time.Millisecond, opts.backoff(3))
+	// Backoffs are capped, rather than overflowing.
+	require.Equal(t, time.Hour, opts.backoff(100))
+	require.Equal(t, 2*time.Hour, SubscriptionOpts{Backoff: 2 * time.Hour}.backoff(10))
+}
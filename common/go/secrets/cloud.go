@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/aws"
+	"common/go/gcp"
+)
+
+const gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com"
+
+// NewGCPSecretManagerProvider returns a provider resolving
+// "gcp-sm://projects/{project}/secrets/{secret}[/versions/{version}][#key]" references with the Secret Manager API of
+// the given endpoint. The latest version is resolved unless one is given, and a key resolves to that key of a JSON
+// secret.
+func NewGCPSecretManagerProvider(endpoint string, tokenSource *gcp.TokenSource) Provider {
+	return func(ctx context.Context, path string) (string, error) {
+		name, key, _ := strings.Cut(path, "#")
+		if !strings.Contains(name, "/versions/") {
+			name += "/versions/latest"
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/v1/"+name+":access", nil)
+		if err != nil {
+			return "", errors.Wrap(err, "creating request")
+		}
+		if err := tokenSource.Authorize(ctx, request); err != nil {
+			return "", err
+		}
+		response := &struct {
+			Payload struct {
+				Data []byte `json:"data"`
+			} `json:"payload"`
+		}{}
+		if err := doJSON(request, response); err != nil {
+			return "", errors.Wrapf(err, "accessing %s", name)
+		}
+		return jsonKey(string(response.Payload.Data), key)
+	}
+}
+
+// NewAWSSecretsManagerProvider returns a provider resolving "aws-sm://{secret id}[#key]" references with the Secrets
+// Manager API, where the secret id is a name or an ARN. The region is that of the ARN, or that of the environment.
+// A key resolves to that key of a JSON secret. If set, endpoint overrides the regional endpoint.
+func NewAWSSecretsManagerProvider(endpoint string, credentials func() (*aws.Credentials, error)) Provider {
+	return func(ctx context.Context, path string) (string, error) {
+		secretID, key, _ := strings.Cut(path, "#")
+		region, err := awsRegion(secretID)
+		if err != nil {
+			return "", err
+		}
+		awsCredentials, err := credentials()
+		if err != nil {
+			return "", errors.Wrap(err, "getting aws credentials")
+		}
+		url := endpoint
+		if url == "" {
+			url = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+		}
+		body, err := json.Marshal(map[string]string{"SecretId": secretID})
+		if err != nil {
+			return "", errors.Wrap(err, "marshaling request")
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/", bytes.NewReader(body))
+		if err != nil {
+			return "", errors.Wrap(err, "creating request")
+		}
+		request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		request.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+		aws.SignRequest(request, aws.HashPayload(body), awsCredentials, region, "secretsmanager", time.Now())
+		response := &struct {
+			SecretString string
+			SecretBinary []byte
+		}{}
+		if err := doJSON(request, response); err != nil {
+			return "", errors.Wrapf(err, "getting %s", secretID)
+		}
+		value := response.SecretString
+		if value == "" {
+			value = string(response.SecretBinary)
+		}
+		return jsonKey(value, key)
+	}
+}
+
+// awsRegion returns the region of a secret ARN, or that of the environment for secret names.
+func awsRegion(secretID string) (string, error) {
+	// arn:aws:secretsmanager:{region}:{account}:secret:{name}
+	if parts := strings.SplitN(secretID, ":", 5); len(parts) == 5 && parts[0] == "arn" {
+		return parts[3], nil
+	}
+	return aws.RegionFromEnv()
+}
+
+// jsonKey returns the given key of a JSON object secret, or the secret itself if no key is given.
+func jsonKey(value, key string) (string, error) {
+	if key == "" {
+		return value, nil
+	}
+	object := map[string]any{}
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return "", errors.Wrap(err, "unmarshaling secret")
+	}
+	keyValue, ok := object[key]
+	if !ok {
+		return "", errors.Errorf("secret has no key %s", key)
+	}
+	stringValue, ok := keyValue.(string)
+	if !ok {
+		return "", errors.Errorf("key %s of secret is not a string", key)
+	}
+	return stringValue, nil
+}
+
+func doJSON(request *http.Request, v any) error {
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return errors.Errorf("status %d: %s", response.StatusCode, responseBody)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "decoding response")
+	}
+	return nil
+}
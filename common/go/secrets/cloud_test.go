@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"common/go/aws"
+	"common/go/gcp"
+)
+
+func TestGCPSecretManagerProvider(t *testing.T) {
+	ctx := context.Background()
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 3600})
+	}))
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+
+	nameToValue := map[string]string{
+		"projects/project/secrets/api-key/versions/latest": "latest-key",
+		"projects/project/secrets/api-key/versions/1":      "first-key",
+		"projects/project/secrets/db/versions/latest":      `{"password": "db-password"}`,
+	}
+	secretManager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		value, ok := nameToValue[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data := base64.StdEncoding.EncodeToString([]byte(value))
+		json.NewEncoder(w).Encode(map[string]any{"payload": map[string]any{"data": data}})
+	}))
+	defer secretManager.Close()
+
+	resolver := NewResolver().WithProvider("gcp-sm", NewGCPSecretManagerProvider(secretManager.URL, gcp.NewDefaultTokenSource()))
+	for ref, expected := range map[string]string{
+		"gcp-sm://projects/project/secrets/api-key":            "latest-key",
+		"gcp-sm://projects/project/secrets/api-key/versions/1": "first-key",
+		"gcp-sm://projects/project/secrets/db#password":        "db-password",
+	} {
+		value, err := resolver.Resolve(ctx, ref)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	}
+	_, err := resolver.Resolve(ctx, "gcp-sm://projects/project/secrets/unknown")
+	require.Error(t, err)
+	_, err = resolver.Resolve(ctx, "gcp-sm://projects/project/secrets/db#unknown")
+	require.Error(t, err)
+}
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	var regions []string
+	secretIDToValue := map[string]string{
+		"api-key": "aws-key",
+		"arn:aws:secretsmanager:us-east-1:123456789012:secret:db": `{"password": "db-password"}`,
+	}
+	secretsManager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=id/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// Credential=id/{date}/{region}/secretsmanager/aws4_request
+		regions = append(regions, strings.Split(authorization, "/")[2])
+		request := struct{ SecretId string }{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		value, ok := secretIDToValue[request.SecretId]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": "ResourceNotFoundException"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"SecretString": value})
+	}))
+	defer secretsManager.Close()
+
+	resolver := NewResolver().WithProvider("aws-sm", NewAWSSecretsManagerProvider(secretsManager.URL, aws.CredentialsFromEnv))
+	value, err := resolver.Resolve(ctx, "aws-sm://api-key")
+	require.NoError(t, err)
+	require.Equal(t, "aws-key", value)
+	value, err = resolver.Resolve(ctx, "aws-sm://arn:aws:secretsmanager:us-east-1:123456789012:secret:db#password")
+	require.NoError(t, err)
+	require.Equal(t, "db-password", value)
+	// Secret names use the region of the environment, and ARNs their own.
+	require.Equal(t, []string{"eu-west-1", "us-east-1"}, regions)
+
+	_, err = resolver.Resolve(ctx, "aws-sm://unknown")
+	require.Error(t, err)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = NewResolver().Resolve(ctx, "aws-sm://api-key")
+	require.ErrorContains(t, err, "AWS_ACCESS_KEY_ID")
+}
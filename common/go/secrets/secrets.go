@@ -0,0 +1,176 @@
+// Package secrets resolves secret references, e.g. "env://API_KEY", "file:///run/secrets/api_key" or
+// "gcp-sm://projects/project/secrets/api-key", so that configuration holds references to secrets rather than the
+// secrets themselves.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/aws"
+	"common/go/gcp"
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+const schemeSeparator = "://"
+
+// Provider resolves the path of a secret reference, i.e. what follows "scheme://".
+type Provider func(ctx context.Context, path string) (string, error)
+
+// Resolver resolves secret references and caches their values.
+type Resolver struct {
+	mutex     sync.Mutex
+	providers map[string]Provider
+	cache     map[string]string
+}
+
+// Default is the resolver used by package level functions.
+var Default = NewResolver()
+
+// NewResolver instantiates and returns a new Resolver supporting the env, file, vault, gcp-sm and aws-sm schemes.
+// Cloud secret managers authenticate with the default credentials of their environment.
+func NewResolver() *Resolver {
+	return &Resolver{
+		providers: map[string]Provider{
+			"env":    resolveEnv,
+			"file":   resolveFile,
+			"vault":  resolveVault,
+			"gcp-sm": NewGCPSecretManagerProvider(gcpSecretManagerEndpoint, gcp.NewDefaultTokenSource(gcp.CloudPlatformScope)),
+			"aws-sm": NewAWSSecretsManagerProvider("", aws.CredentialsFromEnv),
+		},
+		cache: map[string]string{},
+	}
+}
+
+// WithProvider registers a provider for the given scheme.
+func (r *Resolver) WithProvider(scheme string, provider Provider) *Resolver {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.providers[scheme] = provider
+	return r
+}
+
+// IsRef returns true if the given value is a reference to a secret of a scheme this resolver supports.
+func (r *Resolver) IsRef(value string) bool {
+	scheme, _, ok := strings.Cut(value, schemeSeparator)
+	if !ok {
+		return false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_, ok = r.providers[scheme]
+	return ok
+}
+
+// Resolve returns the value of the given secret reference, from cache if it was already resolved.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	r.mutex.Lock()
+	value, ok := r.cache[ref]
+	r.mutex.Unlock()
+	if ok {
+		return value, nil
+	}
+	return r.resolve(ctx, ref)
+}
+
+func (r *Resolver) resolve(ctx context.Context, ref string) (string, error) {
+	scheme, path, ok := strings.Cut(ref, schemeSeparator)
+	if !ok {
+		return "", errors.Errorf("%s is not a secret reference", ref)
+	}
+	r.mutex.Lock()
+	provider, ok := r.providers[scheme]
+	r.mutex.Unlock()
+	if !ok {
+		return "", errors.Errorf("unsupported secret scheme %s", scheme)
+	}
+	value, err := provider(ctx, path)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving %s secret", scheme)
+	}
+	r.mutex.Lock()
+	r.cache[ref] = value
+	r.mutex.Unlock()
+	return value, nil
+}
+
+// Watch resolves the given secret reference every interval, bypassing the cache, and calls onRotate whenever its
+// value changes. Blocking call, returning once the context is done.
+func (r *Resolver) Watch(ctx context.Context, ref string, interval time.Duration, onRotate func(value string)) {
+	previous, _ := r.Resolve(ctx, ref)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		value, err := r.resolve(ctx, ref)
+		if err != nil {
+			log.Warningf("Could not resolve secret: %v", err)
+			continue
+		}
+		if value != previous {
+			previous = value
+			onRotate(value)
+		}
+	}
+}
+
+// IsRef calls IsRef on the Default resolver.
+func IsRef(value string) bool { return Default.IsRef(value) }
+
+// Resolve calls Resolve on the Default resolver.
+func Resolve(ctx context.Context, ref string) (string, error) { return Default.Resolve(ctx, ref) }
+
+// "env://NAME" resolves to the value of the NAME environment variable.
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// "file:///path" resolves to the content of the file, without its trailing newline.
+func resolveFile(_ context.Context, path string) (string, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(bytes), "\r\n"), nil
+}
+
+// "vault:///path#key" resolves to a key of a secret rendered to a file by the vault agent, as {"data": {"key": ...}}.
+func resolveVault(_ context.Context, path string) (string, error) {
+	path, key, ok := strings.Cut(path, "#")
+	if !ok {
+		return "", errors.Errorf("vault secret %s is missing a #key", path)
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var vaultSecret struct{ Data map[string]any }
+	if err := json.Unmarshal(bytes, &vaultSecret); err != nil {
+		return "", errors.Wrapf(err, "unmarshaling secret @%s", path)
+	}
+	value, ok := vaultSecret.Data[key]
+	if !ok {
+		return "", errors.Errorf("secret @%s has no key %s", path, key)
+	}
+	stringValue, ok := value.(string)
+	if !ok {
+		return "", errors.Errorf("key %s of secret @%s is not a string", key, path)
+	}
+	return stringValue, nil
+}
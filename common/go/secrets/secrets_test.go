@@ -0,0 +1,44 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	filePath := filepath.Join(directory, "secret")
+	require.NoError(t, os.WriteFile(filePath, []byte("file-secret\n"), 0600))
+	vaultPath := filepath.Join(directory, "vault.json")
+	require.NoError(t, os.WriteFile(vaultPath, []byte(`{"data": {"password": "vault-secret"}}`), 0600))
+	t.Setenv("SECRETS_TEST", "env-secret")
+
+	resolver := NewResolver()
+	for ref, expected := range map[string]string{
+		"env://SECRETS_TEST":                 "env-secret",
+		"file://" + filePath:                 "file-secret",
+		"vault://" + vaultPath + "#password": "vault-secret",
+	} {
+		require.True(t, resolver.IsRef(ref))
+		value, err := resolver.Resolve(ctx, ref)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	}
+
+	// Values are cached.
+	require.NoError(t, os.WriteFile(filePath, []byte("rotated"), 0600))
+	value, err := resolver.Resolve(ctx, "file://"+filePath)
+	require.NoError(t, err)
+	require.Equal(t, "file-secret", value)
+
+	require.False(t, resolver.IsRef("https://example.com"))
+	_, err = resolver.Resolve(ctx, "env://SECRETS_TEST_UNSET")
+	require.Error(t, err)
+	_, err = resolver.Resolve(ctx, "vault://"+vaultPath+"#unknown")
+	require.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"google.golang.org/grpc"
+
+	"common/go/certs"
+	"common/go/flags"
+	commongrpc "common/go/grpc"
+	"common/go/health"
+	"common/go/logging"
+	"common/go/postgres"
+	"common/go/prometheus"
+)
+
+var log = logging.NewLogger()
+
+// Opts holds the opts of every subsystem wired by `Run`. Services embed it into their own opts.
+type Opts struct {
+	flags.ConfigFileOpts
+	GRPC        commongrpc.Opts     `group:"gRPC" namespace:"grpc" env-namespace:"GRPC"`
+	Auth        commongrpc.AuthOpts `group:"Auth"`
+	GatewayPort int                 `long:"gateway-port" env:"GATEWAY_PORT" description:"Port to serve the gRPC gateway on, if the server has one" default:"8080"`
+	Certs       certs.Opts          `group:"Certs"`
+	Prometheus  prometheus.Opts     `group:"Prometheus" namespace:"prometheus" env-namespace:"PROMETHEUS"`
+	Health      health.Opts         `group:"Health" namespace:"health" env-namespace:"HEALTH"`
+	Postgres    postgres.Opts       `group:"Postgres"`
+}
+
+// Runtime holds the subsystems wired by `Run`. It is handed to the service's register function.
+type Runtime struct {
+	Opts   Opts
+	GRPC   *commongrpc.Server
+	Health *health.Registry
+	// Only set if the server runs with `WithPostgres`.
+	Postgres *postgres.Client
+
+	withPostgres       bool
+	registerHandlers   []commongrpc.RegisterHandler
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	shutdownHooks      []commongrpc.ShutdownHook
+	authPolicyFNs      []func(*commongrpc.Auth)
+}
+
+// Option configures a runtime.
+type Option func(*Runtime)
+
+// WithPostgres connects a postgres client and registers it as a readiness probe.
+func WithPostgres() Option {
+	return func(r *Runtime) { r.withPostgres = true }
+}
+
+// WithGateway serves a gRPC gateway transcoding REST/JSON requests to the server, using the given handlers.
+func WithGateway(registerHandlers ...commongrpc.RegisterHandler) Option {
+	return func(r *Runtime) { r.registerHandlers = append(r.registerHandlers, registerHandlers...) }
+}
+
+// WithLivenessProbe registers a liveness probe.
+func WithLivenessProbe(name string, check health.Check) Option {
+	return func(r *Runtime) { r.Health.RegisterLiveness(name, check) }
+}
+
+// WithReadinessProbe registers a readiness probe.
+func WithReadinessProbe(name string, check health.Check) Option {
+	return func(r *Runtime) { r.Health.RegisterReadiness(name, check) }
+}
+
+// WithUnaryInterceptors adds interceptors to the gRPC server.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(r *Runtime) { r.unaryInterceptors = append(r.unaryInterceptors, interceptors...) }
+}
+
+// WithStreamInterceptors adds interceptors to the gRPC server.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(r *Runtime) { r.streamInterceptors = append(r.streamInterceptors, interceptors...) }
+}
+
+// WithShutdownHooks adds hooks called when the gRPC server stops accepting RPCs.
+func WithShutdownHooks(hooks ...commongrpc.ShutdownHook) Option {
+	return func(r *Runtime) { r.shutdownHooks = append(r.shutdownHooks, hooks...) }
+}
+
+// WithAuthPolicy configures the authorization policy (public methods, method subjects, parent scoping) applied
+// when authentication is enabled through opts.
+func WithAuthPolicy(fn func(auth *commongrpc.Auth)) Option {
+	return func(r *Runtime) { r.authPolicyFNs = append(r.authPolicyFNs, fn) }
+}
+
+// Run wires metrics, health, postgres, the gRPC server and its gateway from the given opts, then blocks until a
+// shutdown signal is received and the server has gracefully stopped. The register function is called once the
+// gRPC server is instantiated, and should register the service's implementation on `runtime.GRPC.Raw`.
+// A typical main parses flags into opts embedding `server.Opts`, then hands them to `Run`.
+func Run(opts Opts, register func(*Runtime), options ...Option) {
+	runtime := newRuntime(opts, register, options...)
+	if runtime.Postgres != nil {
+		defer runtime.Postgres.Close()
+	}
+	if len(runtime.registerHandlers) == 0 {
+		runtime.GRPC.Serve()
+	} else {
+		gatewayOpts := commongrpc.GatewayOpts{GRPC: opts.GRPC, Port: opts.GatewayPort}
+		gateway := commongrpc.NewGateway(gatewayOpts, opts.Certs, opts.Prometheus, runtime.registerHandlers)
+		commongrpc.ServeWithGateway(runtime.GRPC, gateway)
+	}
+	log.Info("server exited")
+}
+
+// newRuntime applies the given options and wires metrics, health, postgres and the gRPC server, without serving it.
+func newRuntime(opts Opts, register func(*Runtime), options ...Option) *Runtime {
+	runtime := &Runtime{Opts: opts, Health: health.NewRegistry()}
+	for _, option := range options {
+		option(runtime)
+	}
+
+	prometheus.Serve(opts.Prometheus)
+	if runtime.withPostgres {
+		runtime.Postgres = postgres.MustNewClient(opts.Postgres)
+		runtime.Health.RegisterReadiness("postgres", runtime.Postgres.HealthCheck)
+	}
+	health.ServeRegistry(opts.Health, runtime.Health)
+
+	registerFN := func(*commongrpc.Server) { register(runtime) }
+	runtime.GRPC = commongrpc.NewServer(opts.GRPC, opts.Certs, opts.Prometheus, registerFN).
+		WithHealthRegistry(runtime.Health).
+		WithUnaryInterceptors(runtime.unaryInterceptors...).
+		WithStreamInterceptors(runtime.streamInterceptors...).
+		WithShutdownHooks(runtime.shutdownHooks...)
+	if auth := commongrpc.MustNewAuth(opts.Auth); auth != nil {
+		for _, authPolicyFN := range runtime.authPolicyFNs {
+			authPolicyFN(auth)
+		}
+		runtime.GRPC.WithAuthInterceptors(auth.UnaryServerInterceptor(), auth.StreamServerInterceptor())
+	}
+	return runtime
+}
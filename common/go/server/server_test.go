@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commongrpc "common/go/grpc"
+	"common/go/grpc/types"
+	commonhealth "common/go/health"
+	"common/go/prometheus"
+)
+
+const (
+	// libraryCheckMethod is a method that, unlike health checks, requires authentication.
+	libraryCheckMethod = "/library.Library/Check"
+	// libraryBakeMethod is a method whose requests are validated.
+	libraryBakeMethod = "/library.Library/Bake"
+)
+
+var libraryServiceDesc = grpc.ServiceDesc{
+	ServiceName: "library.Library",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Check", Handler: libraryCheckHandler},
+		{MethodName: "Bake", Handler: libraryBakeHandler},
+	},
+}
+
+func libraryCheckHandler(srv any, ctx context.Context, decode func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	request := &grpc_health_v1.HealthCheckRequest{}
+	if err := decode(request); err != nil {
+		return nil, err
+	}
+	handler := func(context.Context, any) (any, error) {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	}
+	if interceptor == nil {
+		return handler(ctx, request)
+	}
+	return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: libraryCheckMethod}, handler)
+}
+
+func libraryBakeHandler(srv any, ctx context.Context, decode func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	request := &types.HttpCookie{}
+	if err := decode(request); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, request any) (any, error) { return request, nil }
+	if interceptor == nil {
+		return handler(ctx, request)
+	}
+	return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: libraryBakeMethod}, handler)
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// serveTestRuntime wires and serves a runtime registering the library service, and returns a connection to it.
+func serveTestRuntime(t *testing.T, opts Opts, options ...Option) (*Runtime, *grpc.ClientConn) {
+	opts.GRPC = commongrpc.Opts{Port: freePort(t), DisableTLS: true}
+	opts.Prometheus = prometheus.Opts{Disable: true}
+	opts.Health = commonhealth.Opts{Disable: true}
+
+	registered := make(chan *Runtime, 1)
+	register := func(runtime *Runtime) {
+		runtime.GRPC.Raw.RegisterService(&libraryServiceDesc, struct{}{})
+		registered <- runtime
+	}
+	runtime := newRuntime(opts, register, options...)
+	go runtime.GRPC.Serve()
+	select {
+	case runtime = <-registered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("service was not registered")
+	}
+	t.Cleanup(runtime.GRPC.Raw.Stop)
+
+	connection, err := grpc.Dial(fmt.Sprintf("localhost:%d", opts.GRPC.Port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { connection.Close() })
+	return runtime, connection
+}
+
+func checkLibrary(ctx context.Context, connection *grpc.ClientConn) error {
+	return connection.Invoke(ctx, libraryCheckMethod, &grpc_health_v1.HealthCheckRequest{}, &grpc_health_v1.HealthCheckResponse{})
+}
+
+func TestRuntime(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ServesTheRegisteredService", func(t *testing.T) {
+		runtime, connection := serveTestRuntime(t, Opts{})
+		require.NotNil(t, runtime.GRPC)
+		require.Nil(t, runtime.Postgres)
+		require.NoError(t, checkLibrary(ctx, connection))
+	})
+
+	t.Run("ReportsReadinessOverGRPC", func(t *testing.T) {
+		var ready atomic.Bool
+		probe := func(context.Context) error {
+			if !ready.Load() {
+				return errors.New("warming up")
+			}
+			return nil
+		}
+		_, connection := serveTestRuntime(t, Opts{}, WithReadinessProbe("cache", probe))
+		client := grpc_health_v1.NewHealthClient(connection)
+
+		response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, response.Status)
+		ready.Store(true)
+		response, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+	})
+
+	t.Run("AuthenticatesBeforeServiceInterceptors", func(t *testing.T) {
+		var callers []string
+		interceptor := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if info.FullMethod == libraryCheckMethod {
+				caller, ok := commongrpc.CallerFromContext(ctx)
+				require.True(t, ok)
+				callers = append(callers, caller.Subject)
+			}
+			return handler(ctx, req)
+		}
+		opts := Opts{Auth: commongrpc.AuthOpts{APIKeys: map[string]string{"admin-key": "admin", "reader-key": "reader"}}}
+		authPolicy := func(auth *commongrpc.Auth) { auth.WithMethodSubjects(libraryCheckMethod, "admin") }
+		_, connection := serveTestRuntime(t, opts, WithUnaryInterceptors(interceptor), WithAuthPolicy(authPolicy))
+
+		require.Equal(t, codes.Unauthenticated, status.Code(checkLibrary(ctx, connection)))
+		readerCtx := metadata.AppendToOutgoingContext(ctx, "x-api-key", "reader-key")
+		require.Equal(t, codes.PermissionDenied, status.Code(checkLibrary(readerCtx, connection)))
+		require.Empty(t, callers)
+
+		adminCtx := metadata.AppendToOutgoingContext(ctx, "x-api-key", "admin-key")
+		require.NoError(t, checkLibrary(adminCtx, connection))
+		require.Equal(t, []string{"admin"}, callers)
+
+		// Anonymous callers are rejected before their requests are validated.
+		invalidCookie := &types.HttpCookie{}
+		err := connection.Invoke(ctx, libraryBakeMethod, invalidCookie, &types.HttpCookie{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		err = connection.Invoke(adminCtx, libraryBakeMethod, invalidCookie, &types.HttpCookie{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// Health checks stay public.
+		_, err = grpc_health_v1.NewHealthClient(connection).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"common/go/aip"
+	commongrpc "common/go/grpc"
+)
+
+type fakeParsedRequest struct {
+	aip.ParsedRequest
+	whereClause string
+	whereParams []any
+}
+
+func (f *fakeParsedRequest) GetSQLWhereClause() (string, []any) {
+	return f.whereClause, f.whereParams
+}
+
+func TestScope(t *testing.T) {
+	_, err := Scope(context.Background(), &fakeParsedRequest{}, "organization_id")
+	require.ErrorIs(t, err, ErrNoTenant)
+
+	ctx := ContextWithTenant(context.Background(), "acme")
+	scoped, err := Scope(ctx, &fakeParsedRequest{}, "organization_id")
+	require.NoError(t, err)
+	whereClause, whereParams := scoped.GetSQLWhereClause()
+	require.Equal(t, "WHERE organization_id = $1", whereClause)
+	require.Equal(t, []any{"acme"}, whereParams)
+
+	scoped, err = Scope(ctx, &fakeParsedRequest{whereClause: "WHERE (title = $1) OR (author = $2)", whereParams: []any{"a", "b"}}, "organization_id")
+	require.NoError(t, err)
+	whereClause, whereParams = scoped.GetSQLWhereClause()
+	require.Equal(t, "WHERE ((title = $1) OR (author = $2)) AND organization_id = $3", whereClause)
+	require.Equal(t, []any{"a", "b", "acme"}, whereParams)
+}
+
+type aggregateRequest struct {
+	proto.Message
+}
+
+func (r *aggregateRequest) GetFilter() string       { return "" }
+func (r *aggregateRequest) GetGroupBy() string      { return "" }
+func (r *aggregateRequest) GetAggregations() string { return "count()" }
+
+func TestParserScope(t *testing.T) {
+	parser := aip.NewParser().WithFilteringOptions().WithAggregationOptions(nil, nil).WithScope(ParserScope("organization_id"))
+	ctx := ContextWithTenant(context.Background(), "acme")
+	parsedRequest, err := parser.ParseAggregateRequestContext(ctx, &aggregateRequest{})
+	require.NoError(t, err)
+	whereClause, whereParams := parsedRequest.GetSQLWhereClause()
+	require.Equal(t, "WHERE organization_id = $1", whereClause)
+	require.Equal(t, []any{"acme"}, whereParams)
+
+	// Requests without a tenant, or parsed without their context, are rejected.
+	_, err = parser.ParseAggregateRequestContext(context.Background(), &aggregateRequest{})
+	require.ErrorIs(t, err, ErrNoTenant)
+	_, err = parser.ParseAggregateRequest(&aggregateRequest{})
+	require.Error(t, err)
+}
+
+const tenancyTestFileDescriptor = `
+name: "tenancy_test.proto"
+package: "test"
+syntax: "proto3"
+message_type { name: "Book" field { name: "name" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "name" } }
+message_type { name: "UpdateBookRequest" field { name: "book" number: 1 type: TYPE_MESSAGE type_name: ".test.Book" label: LABEL_OPTIONAL json_name: "book" } }
+`
+
+func newUpdateBookRequest(t *testing.T, name string) proto.Message {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(tenancyTestFileDescriptor), fileDescriptorProto))
+	fileDescriptor, err := protodesc.NewFile(fileDescriptorProto, nil)
+	require.NoError(t, err)
+	request := dynamicpb.NewMessage(fileDescriptor.Messages().ByName("UpdateBookRequest"))
+	require.NoError(t, protojson.Unmarshal([]byte(`{"book": {"name": "`+name+`"}}`), request))
+	return request
+}
+
+func TestParentScopeRejectsOtherTenants(t *testing.T) {
+	subjectToTenant := map[string]string{"alice": "1"}
+	tenantFN := func(caller *commongrpc.Caller) (string, error) { return subjectToTenant[caller.Subject], nil }
+	auth := commongrpc.MustNewAuth(commongrpc.AuthOpts{APIKeys: map[string]string{"key-alice": "alice"}}).
+		WithParentScope(ParentScope("organizations/%s", tenantFN))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key-alice"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Library/UpdateBook"}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	_, err := auth.UnaryServerInterceptor()(ctx, newUpdateBookRequest(t, "organizations/1/books/1"), info, handler)
+	require.NoError(t, err)
+	_, err = auth.UnaryServerInterceptor()(ctx, newUpdateBookRequest(t, "organizations/2/books/1"), info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"common/go/flags"
+	"common/go/grafana"
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+var opts struct {
+	Grafana        grafana.Opts
+	Text           string        `long:"text" description:"text of the annotation, e.g. 'deployed service v1.2.3'" required:"true"`
+	Tags           []string      `long:"tag" description:"tag to add to the annotation. Can be repeated"`
+	DashboardUID   string        `long:"dashboard-uid" description:"uid of the dashboard to annotate. Annotations are organization wide if unset"`
+	PanelID        int64         `long:"panel-id" description:"id of the panel to annotate, within the dashboard"`
+	Duration       time.Duration `long:"duration" description:"if set, annotates the region [now - duration, now] instead of a single point in time"`
+	TimeoutSeconds int64         `long:"timeout-seconds" description:"request timeout" default:"10"`
+}
+
+func main() {
+	flags.MustParse(&opts)
+	client := grafana.MustNewClient(opts.Grafana)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	annotation := &grafana.Annotation{
+		DashboardUID: opts.DashboardUID,
+		PanelID:      opts.PanelID,
+		Time:         now.UnixMilli(),
+		Tags:         opts.Tags,
+		Text:         opts.Text,
+	}
+	if opts.Duration > 0 {
+		annotation.Time = now.Add(-opts.Duration).UnixMilli()
+		annotation.TimeEnd = now.UnixMilli()
+	}
+	id, err := client.PostAnnotation(ctx, annotation)
+	if err != nil {
+		log.Panicf("posting annotation: %v", err)
+	}
+	log.Infof("posted annotation %d: %s %v", id, opts.Text, opts.Tags)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"common/go/flags"
+	"common/go/grafana"
+	"common/go/jsonnet"
+	"common/go/logging"
+)
+
+var log = logging.NewLogger()
+
+var opts struct {
+	Grafana        grafana.Opts
+	Jsonnet        jsonnet.EvaluateOpts
+	ConfigFilepath string `long:"config-filepath" description:"path to the .json or .jsonnet alerting config we wish to upload" required:"true"`
+	TimeoutSeconds int64  `long:"timeout-seconds" description:"import timeout" default:"30"`
+	DryRun         bool   `long:"dry-run" description:"print the changes that would be made and exit non-zero if grafana has drifted, without uploading"`
+}
+
+// alertingConfig is the alerting config uploaded by this tool.
+type alertingConfig struct {
+	// Datasources are created or updated by name, before rule groups are reconciled.
+	Datasources []*grafana.Datasource `json:"datasources"`
+	// Each rule group is reconciled: rules missing from a group are deleted from grafana.
+	RuleGroups []*grafana.RuleGroup `json:"ruleGroups"`
+}
+
+func main() {
+	flags.MustParse(&opts)
+	client := grafana.MustNewClient(opts.Grafana)
+	config, err := loadConfig(opts.ConfigFilepath)
+	if err != nil {
+		log.Panicf("loading config: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	drift := false
+	for _, datasource := range config.Datasources {
+		if opts.DryRun {
+			plan, err := client.PlanDatasource(ctx, datasource)
+			if err != nil {
+				log.Panicf("planning datasource: %v", err)
+			}
+			switch {
+			case !plan.Exists:
+				fmt.Printf("datasource [%s] does not exist and would be created\n", datasource.Name)
+			case plan.HasChanges():
+				fmt.Printf("datasource [%s] has drifted:\n%s\n", datasource.Name, plan.Diff)
+			default:
+				fmt.Printf("datasource [%s] is up to date\n", datasource.Name)
+			}
+			drift = drift || plan.HasChanges()
+			continue
+		}
+		if _, err := client.CreateOrUpdateDatasource(ctx, datasource); err != nil {
+			log.Panicf("provisioning datasource: %v", err)
+		}
+	}
+	for _, ruleGroup := range config.RuleGroups {
+		plan, err := client.PlanRuleGroup(ctx, ruleGroup)
+		if err != nil {
+			log.Panicf("planning rule group: %v", err)
+		}
+		drift = drift || plan.HasChanges()
+		if opts.DryRun {
+			printRuleGroupPlan(plan)
+			continue
+		}
+		if err := client.ApplyRuleGroup(ctx, plan); err != nil {
+			log.Panicf("applying rule group: %v", err)
+		}
+	}
+	if opts.DryRun && drift {
+		os.Exit(1)
+	}
+}
+
+func loadConfig(path string) (*alertingConfig, error) {
+	var bytes []byte
+	var err error
+	if filepath.Ext(path) == ".jsonnet" {
+		var content string
+		content, err = jsonnet.EvaluateFile(path, opts.Jsonnet)
+		bytes = []byte(content)
+	} else {
+		bytes, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	config := &alertingConfig{}
+	if err := json.Unmarshal(bytes, config); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling config")
+	}
+	return config, nil
+}
+
+func printRuleGroupPlan(plan *grafana.RuleGroupPlan) {
+	if !plan.HasChanges() {
+		fmt.Printf("rule group [%s] is up to date\n", plan.Group.Name())
+		return
+	}
+	fmt.Printf("rule group [%s] has drifted:\n", plan.Group.Name())
+	for _, rule := range plan.Create {
+		fmt.Printf("  + %s (%s)\n", rule.Title, rule.UID)
+	}
+	for uid, diff := range plan.Update {
+		fmt.Printf("  ~ %s\n%s\n", uid, diff)
+	}
+	for _, rule := range plan.Delete {
+		fmt.Printf("  - %s (%s)\n", rule.Title, rule.UID)
+	}
+	if plan.GroupChanged {
+		fmt.Printf("  ~ interval / rule order\n")
+	}
+}
@@ -3,80 +3,103 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/grafana-tools/sdk"
+	"github.com/pkg/errors"
 
 	"common/go/flags"
+	"common/go/grafana"
+	"common/go/jsonnet"
 	"common/go/logging"
 )
 
 var log = logging.NewLogger()
 
 var opts struct {
-	GrafanaAPIKey     string `long:"grafana-api-key" description:"Grafana API key" required:"true"`
-	GrafanaAPIURL     string `long:"grafana-api-url" description:"Grafana API url" required:"true"`
-	GrafanaFolder     string `long:"grafana-folder" description:"Folder to upload dashboard to"`
-	DashboardFilepath string `long:"dashboard-filepath" description:"path to the dashboard we wish to upload" required:"true"`
-	TimeoutSeconds    int64  `long:"timeout-seconds" description:"import timeout" default:"10"`
+	Grafana            grafana.Opts
+	Jsonnet            jsonnet.EvaluateOpts
+	GrafanaFolder      string `long:"grafana-folder" description:"Folder to upload dashboard to"`
+	DashboardFilepath  string `long:"dashboard-filepath" description:"path to the dashboard we wish to upload"`
+	DashboardDirectory string `long:"dashboard-directory" description:"path to a directory of dashboards we wish to sync. Subdirectories map to grafana folders"`
+	Prune              bool   `long:"prune" description:"with --dashboard-directory, delete dashboards of the synced folders that are not in the directory, and the folders left empty"`
+	Concurrency        int    `long:"concurrency" description:"with --dashboard-directory, number of dashboards uploaded in parallel" default:"4"`
+	TimeoutSeconds     int64  `long:"timeout-seconds" description:"import timeout" default:"10"`
+	DryRun             bool   `long:"dry-run" description:"print the diff against the existing dashboard and exit non-zero if it has drifted, without uploading"`
 }
 
 func main() {
 	flags.MustParse(&opts)
-	client, err := sdk.NewClient(opts.GrafanaAPIURL, opts.GrafanaAPIKey, sdk.DefaultHTTPClient)
-	if err != nil {
-		log.Panicf("instantiating grafana client: %v")
-	}
-	bytes, err := os.ReadFile(opts.DashboardFilepath)
-	if err != nil {
-		log.Panicf("reading file: %v", err)
-	}
-	board := &sdk.Board{}
-	if err := json.Unmarshal(bytes, board); err != nil {
-		log.Panicf("unmarshaling board: %v", err)
+	if (opts.DashboardFilepath == "") == (opts.DashboardDirectory == "") {
+		log.Panicf("exactly one of --dashboard-filepath or --dashboard-directory must be set")
 	}
+	client := grafana.MustNewClient(opts.Grafana)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.TimeoutSeconds)*time.Second)
 	defer cancel()
 
-	// Create folder if it doesn't exist.
-	folderID := sdk.DefaultFolderId
-	folderName := "General"
-	if opts.GrafanaFolder != "" {
-		folderName = opts.GrafanaFolder
-		folders, err := client.GetAllFolders(ctx)
+	var plans []*grafana.DashboardPlan
+	var pruned []sdk.FoundBoard
+	var prunedFolders []string
+	if opts.DashboardDirectory != "" {
+		syncOpts := grafana.SyncOpts{Concurrency: opts.Concurrency, Prune: opts.Prune, DryRun: opts.DryRun, Jsonnet: opts.Jsonnet}
+		result, err := client.SyncDirectory(ctx, opts.DashboardDirectory, syncOpts)
 		if err != nil {
-			log.Panicf("getting folders: %v", err)
+			log.Panicf("syncing directory: %v", err)
 		}
-		for _, folder := range folders {
-			if folder.Title == opts.GrafanaFolder {
-				folderID = folder.ID
-				break
-			}
+		plans, pruned, prunedFolders = result.Plans, result.Pruned, result.PrunedFolders
+	} else {
+		plan, err := uploadFile(ctx, client)
+		if err != nil {
+			log.Panic(err)
 		}
-		if folderID == sdk.DefaultFolderId {
-			// We must create the folder.
-			folder := sdk.Folder{Title: opts.GrafanaFolder}
-			var err error
-			folder, err = client.CreateFolder(ctx, folder)
-			if err != nil {
-				log.Panicf("creating folder: %v", err)
-			}
-			folderID = folder.ID
-			if folderID == sdk.DefaultFolderId {
-				log.Panic("folder created did not return an id")
-			}
-			log.Infof("created folder: %s", opts.GrafanaFolder)
+		plans = append(plans, plan)
+	}
+	if !opts.DryRun {
+		return
+	}
+
+	drift := len(pruned) > 0 || len(prunedFolders) > 0
+	for _, plan := range plans {
+		switch {
+		case !plan.Exists:
+			fmt.Printf("dashboard [%s] does not exist and would be created\n", plan.Name())
+		case plan.HasChanges():
+			fmt.Printf("dashboard [%s] has drifted:\n%s\n", plan.Name(), plan.Diff)
+		default:
+			fmt.Printf("dashboard [%s] is up to date\n", plan.Name())
 		}
+		drift = drift || plan.HasChanges()
 	}
+	for _, foundBoard := range pruned {
+		fmt.Printf("dashboard [%s/%s] is not in the directory and would be pruned\n", foundBoard.FolderTitle, foundBoard.Title)
+	}
+	for _, folder := range prunedFolders {
+		fmt.Printf("folder [%s] has no dashboards left and would be pruned\n", folder)
+	}
+	if drift {
+		os.Exit(1)
+	}
+}
 
-	params := sdk.SetDashboardParams{
-		FolderID:  folderID,
-		Overwrite: true,
+func uploadFile(ctx context.Context, client *grafana.Client) (*grafana.DashboardPlan, error) {
+	bytes, err := os.ReadFile(opts.DashboardFilepath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading file")
 	}
-	response, err := client.SetDashboard(ctx, *board, params)
+	board := &sdk.Board{}
+	if err := json.Unmarshal(bytes, board); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling board")
+	}
+	plan, err := client.PlanDashboard(ctx, board, opts.GrafanaFolder)
 	if err != nil {
-		log.Panicf("uploading dashboard to grafana: %v", err)
+		return nil, errors.Wrap(err, "planning dashboard")
+	}
+	if !opts.DryRun {
+		if _, err := client.ApplyDashboard(ctx, plan); err != nil {
+			return nil, errors.Wrap(err, "applying dashboard")
+		}
 	}
-	log.Infof("uploaded dashboard [%s/%s] @ %s%s", folderName, board.Title, opts.GrafanaAPIURL, *response.URL)
+	return plan, nil
 }